@@ -30,6 +30,7 @@ const (
 	syncPrefix    = "-sync"
 	asyncPrefix   = "-async"
 	preloadPrefix = "-preload"
+	modulePrefix  = "-module"
 )
 
 var (
@@ -55,11 +56,16 @@ func Generate(path string) string {
 	id, _, ext := filename(path)
 
 	switch ext {
-	case ".css", ".js":
+	case ".css", ".js", ".mjs":
+		prefixes := prefixesToTrim
+		if ext != ".css" {
+			prefixes = append([]string{modulePrefix}, prefixes...)
+		}
+
 		// Split on first dot to handle dot-suffixed filenames (e.g. "example-sync.braille")
 		parts := strings.SplitN(id, ".", 2)
 		base := parts[0]
-		for _, pr := range prefixesToTrim {
+		for _, pr := range prefixes {
 			if strings.HasSuffix(base, pr) {
 				base = strings.TrimSuffix(base, pr)
 				break
@@ -86,7 +92,12 @@ func assetType(fn, media, ext string) (assets.Type, error) {
 	case ".css":
 		return typeFromSyncSet(fn, media, assets.InlineCSS, assets.SyncCSS, assets.AsyncCSS, assets.PreloadCSS), nil
 	case ".js":
+		if strings.HasSuffix(strings.SplitN(fn, ".", 2)[0], modulePrefix) {
+			return assets.ModuleJS, nil
+		}
 		return typeFromSyncSet(fn, media, assets.InlineJS, assets.SyncJS, assets.AsyncJS, assets.PreloadJS), nil
+	case ".mjs":
+		return assets.ModuleJS, nil
 	case ".json":
 		return assets.JSON, nil
 	case ".html":
@@ -99,6 +110,12 @@ func assetType(fn, media, ext string) (assets.Type, error) {
 		return assets.WEBP, nil
 	case ".avif":
 		return assets.AVIF, nil
+	case ".svg":
+		return assets.SVG, nil
+	case ".woff2":
+		return assets.WOFF2, nil
+	case ".woff":
+		return assets.WOFF, nil
 	}
 	return assets.Unknown, fmt.Errorf("%w: for file %q with extension %q", ErrUnknownType, fn, ext)
 }
@@ -305,6 +305,24 @@ func TestAssetType(t *testing.T) {
 			ext:         ".avif",
 			want:        assets.AVIF,
 		},
+		{
+			description: "return svg for svg",
+			filename:    "example",
+			ext:         ".svg",
+			want:        assets.SVG,
+		},
+		{
+			description: "return woff2 for woff2",
+			filename:    "example",
+			ext:         ".woff2",
+			want:        assets.WOFF2,
+		},
+		{
+			description: "return woff for woff",
+			filename:    "example",
+			ext:         ".woff",
+			want:        assets.WOFF,
+		},
 	}
 
 	for _, tt := range tests {
@@ -365,6 +383,24 @@ func TestIdentifyType(t *testing.T) {
 			wantMedia:   "",
 			wantType:    assets.AsyncJS,
 		},
+		{
+			description: "return module js for js with module prefix",
+			path:        "example-module.js",
+			wantMedia:   "",
+			wantType:    assets.ModuleJS,
+		},
+		{
+			description: "return module js for mjs",
+			path:        "example.mjs",
+			wantMedia:   "",
+			wantType:    assets.ModuleJS,
+		},
+		{
+			description: "return inline css for css with module prefix",
+			path:        "example-module.css",
+			wantMedia:   "",
+			wantType:    assets.InlineCSS,
+		},
 	}
 
 	for _, tt := range tests {
@@ -446,6 +482,26 @@ func TestGenerate(t *testing.T) {
 			path:        "example-preload.js",
 			want:        "example",
 		},
+		{
+			description: "return filename without module prefix for js",
+			path:        "example-module.js",
+			want:        "example",
+		},
+		{
+			description: "return filename without module prefix for mjs",
+			path:        "example-module.mjs",
+			want:        "example",
+		},
+		{
+			description: "return filename for mjs",
+			path:        "example.mjs",
+			want:        "example",
+		},
+		{
+			description: "return filename with module prefix for css",
+			path:        "example-module.css",
+			want:        "example-module",
+		},
 		{
 			description: "return filename without last prefix only",
 			path:        "example-inline-sync-async.js",
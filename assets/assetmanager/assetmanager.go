@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/gauntface/go-html-asset-manager/v5/assets"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetid"
@@ -45,6 +46,9 @@ type Manager struct {
 	staticDir string
 	jsonDir   string
 
+	// mu guards the assets as they may be added while HTML files are
+	// manipulated concurrently
+	mu           sync.RWMutex
 	localAssets  []*LocalAsset
 	remoteAssets []*RemoteAsset
 }
@@ -55,7 +59,7 @@ func NewManager(htmlDir, staticDir, jsonDir string) (*Manager, error) {
 		return nil, err
 	}
 
-	staticAssets, err := findLocalAssets(staticDir, ".css", ".js", ".png", ".jpg", ".jpeg", ".webp", ".avif")
+	staticAssets, err := findLocalAssets(staticDir, ".css", ".js", ".mjs", ".png", ".jpg", ".jpeg", ".webp", ".avif", ".svg", ".woff2", ".woff")
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +88,9 @@ func (m *Manager) StaticDir() string {
 }
 
 func (m *Manager) All() []Asset {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	as := []Asset{}
 	for _, a := range m.localAssets {
 		as = append(as, a)
@@ -124,10 +131,16 @@ func (m *Manager) WithID(id string) map[assets.Type][]Asset {
 }
 
 func (m *Manager) AddRemote(a *RemoteAsset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.remoteAssets = append(m.remoteAssets, a)
 }
 
 func (m *Manager) AddLocal(a *LocalAsset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.localAssets = append(m.localAssets, a)
 }
 
@@ -168,6 +181,21 @@ func (m *Manager) String() string {
 			Type:      assets.AVIF,
 			CountOnly: true,
 		},
+		{
+			Title:     "SVG",
+			Type:      assets.SVG,
+			CountOnly: true,
+		},
+		{
+			Title:     "WOFF2",
+			Type:      assets.WOFF2,
+			CountOnly: true,
+		},
+		{
+			Title:     "WOFF",
+			Type:      assets.WOFF,
+			CountOnly: true,
+		},
 		{
 			Title: "Inline CSS",
 			Type:  assets.InlineCSS,
@@ -200,6 +228,10 @@ func (m *Manager) String() string {
 			Title: "Preload JS",
 			Type:  assets.PreloadJS,
 		},
+		{
+			Title: "Module JS",
+			Type:  assets.ModuleJS,
+		},
 	}
 
 	headings := []string{
@@ -309,6 +341,17 @@ func (l *LocalAsset) URL() (string, error) {
 	return path.Join("/", relPath), nil
 }
 
+// OriginalURL returns the URL of the asset before any changes to its path,
+// for example before it was revisioned.
+func (l *LocalAsset) OriginalURL() (string, error) {
+	relPath, err := filepath.Rel(l.relativeDir, l.originalPath)
+	if err != nil {
+		return "", fmt.Errorf("%w for directory %q and file %q; %v", errRelPath, l.relativeDir, l.originalPath, err)
+	}
+
+	return path.Join("/", relPath), nil
+}
+
 func (l *LocalAsset) Attributes() []html.Attribute {
 	return nil
 }
@@ -360,6 +403,12 @@ func (r *RemoteAsset) URL() (string, error) {
 	return r.url, nil
 }
 
+// UpdateURL changes the URL of the asset, for example when it refers to a
+// local file that has been revisioned.
+func (r *RemoteAsset) UpdateURL(u string) {
+	r.url = u
+}
+
 func (r *RemoteAsset) Attributes() []html.Attribute {
 	return r.attributes
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/gauntface/go-html-asset-manager/v5/assets"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetid"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/net/html"
 )
 
@@ -85,7 +86,7 @@ func TestNewManager(t *testing.T) {
 				if dir != wantDir {
 					t.Fatalf("Unexpected dir for files.Find; got %v, want %v", dir, wantDir)
 				}
-				wantExts := []string{".css", ".js", ".png", ".jpg", ".jpeg", ".webp", ".avif"}
+				wantExts := []string{".css", ".js", ".mjs", ".png", ".jpg", ".jpeg", ".webp", ".avif", ".svg", ".woff2", ".woff"}
 				if diff := cmp.Diff(exts, wantExts); diff != "" {
 					t.Fatalf("Unexpected exts for files.Find; diff %v", diff)
 				}
@@ -388,6 +389,7 @@ func TestNewManager(t *testing.T) {
 
 			opts := []cmp.Option{
 				cmp.AllowUnexported(Manager{}, LocalAsset{}),
+				cmpopts.IgnoreFields(Manager{}, "mu"),
 				cmp.Comparer(func(x, y func(string) ([]byte, error)) bool {
 					return reflect.ValueOf(x).Pointer() == reflect.ValueOf(y).Pointer()
 				}),
@@ -1014,6 +1016,46 @@ func TestLocalAsset_URL(t *testing.T) {
 	}
 }
 
+func TestLocalAsset_OriginalURL(t *testing.T) {
+	tests := []struct {
+		description string
+		asset       *LocalAsset
+		want        string
+		wantError   error
+	}{
+		{
+			description: "return error if relative path fails",
+			asset: &LocalAsset{
+				relativeDir:  "..",
+				originalPath: "/example/original.js",
+			},
+			wantError: errRelPath,
+		},
+		{
+			description: "return original file url after path update",
+			asset: &LocalAsset{
+				relativeDir:  "./example",
+				originalPath: "example/original.js",
+				path:         "example/original.1234.js",
+			},
+			want: "/original.js",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := tt.asset.OriginalURL()
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Unexpected result; Diff %v", diff)
+			}
+		})
+	}
+}
+
 func TestLocalAsset_IsLocal(t *testing.T) {
 	a := &LocalAsset{}
 	got := a.IsLocal()
@@ -1246,6 +1288,33 @@ func TestRemoteAsset_Contents(t *testing.T) {
 	}
 }
 
+func TestRemoteAsset_UpdateURL(t *testing.T) {
+	tests := []struct {
+		description string
+		asset       *RemoteAsset
+		url         string
+		want        string
+	}{
+		{
+			description: "return updated url",
+			asset: &RemoteAsset{
+				url: "/example/original.css",
+			},
+			url:  "/example/original.1234.css",
+			want: "/example/original.1234.css",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			tt.asset.UpdateURL(tt.url)
+			if diff := cmp.Diff(tt.asset.url, tt.want); diff != "" {
+				t.Errorf("Unexpected result; Diff %v", diff)
+			}
+		})
+	}
+}
+
 func TestRemoteAsset_URL(t *testing.T) {
 	tests := []struct {
 		description string
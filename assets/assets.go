@@ -28,10 +28,14 @@ const (
 	SyncJS          = "sync-js"
 	AsyncJS         = "async-js"
 	PreloadJS       = "preload-js"
+	ModuleJS        = "module-js"
 	JSON            = "json"
 	HTML            = "html"
 	PNG             = "png"
 	JPEG            = "jpeg"
 	WEBP            = "webp"
 	AVIF            = "avif"
+	SVG             = "svg"
+	WOFF2           = "woff2"
+	WOFF            = "woff"
 )
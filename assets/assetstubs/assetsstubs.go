@@ -65,6 +65,8 @@ type Asset struct {
 	URLReturn string
 	URLError  error
 
+	OriginalURLReturn string
+
 	AttributesReturn []html.Attribute
 
 	ContentsReturn string
@@ -95,6 +97,10 @@ func (a *Asset) URL() (string, error) {
 	return a.URLReturn, a.URLError
 }
 
+func (a *Asset) OriginalURL() (string, error) {
+	return a.OriginalURLReturn, a.URLError
+}
+
 func (a *Asset) Attributes() []html.Attribute {
 	return a.AttributesReturn
 }
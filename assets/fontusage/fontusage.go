@@ -0,0 +1,165 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package fontusage matches the @font-face rules in a page's CSS assets to
+// local font assets
+package fontusage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
+)
+
+var (
+	fontTypes = []assets.Type{
+		assets.WOFF2,
+		assets.WOFF,
+	}
+)
+
+// Font is a @font-face rule with the local font assets its src refers to, in
+// the order they are listed in the rule
+type Font struct {
+	Family       string
+	Weight       string
+	Style        string
+	UnicodeRange string
+	Assets       []assetmanager.Asset
+}
+
+// Fonts returns the fonts declared by the CSS assets of the given types that
+// match the keys of a page
+func Fonts(manager assetManager, keys []string, cssTypes ...assets.Type) ([]Font, error) {
+	fontsByURL := map[string]assetmanager.Asset{}
+	for _, t := range fontTypes {
+		for _, f := range manager.WithType(t) {
+			if !f.IsLocal() {
+				continue
+			}
+			u, err := f.URL()
+			if err != nil {
+				return nil, err
+			}
+			fontsByURL[u] = f
+		}
+	}
+	if len(fontsByURL) == 0 {
+		return nil, nil
+	}
+
+	// The same @font-face rule can be declared by several stylesheets, but
+	// different faces may share a font file, so dedupe on the descriptors
+	fonts := []Font{}
+	seen := map[string]bool{}
+	for _, t := range cssTypes {
+		for _, k := range keys {
+			cssAssets := manager.WithID(k)[t]
+			sortAssets(cssAssets)
+			for _, ca := range cssAssets {
+				if !ca.IsLocal() {
+					continue
+				}
+
+				cssURL, err := ca.URL()
+				if err != nil {
+					return nil, err
+				}
+				c, err := ca.Contents()
+				if err != nil {
+					return nil, err
+				}
+
+				for _, ff := range css.FontFaces(c) {
+					f := Font{
+						Family:       ff.Family,
+						Weight:       ff.Weight,
+						Style:        ff.Style,
+						UnicodeRange: ff.UnicodeRange,
+					}
+					urls := []string{}
+					added := map[string]bool{}
+					for _, src := range ff.Srcs {
+						u := stripQuery(css.ResolveURL(cssURL, src.URL))
+						a, ok := fontsByURL[u]
+						if !ok || added[u] {
+							continue
+						}
+						added[u] = true
+						urls = append(urls, u)
+						f.Assets = append(f.Assets, a)
+					}
+					if len(f.Assets) == 0 {
+						continue
+					}
+
+					key := faceKey(ff, urls)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					fonts = append(fonts, f)
+				}
+			}
+		}
+	}
+	return fonts, nil
+}
+
+// MimeType returns the mime type of a font asset
+func MimeType(t assets.Type) string {
+	switch t {
+	case assets.WOFF2:
+		return "font/woff2"
+	case assets.WOFF:
+		return "font/woff"
+	}
+	return ""
+}
+
+func faceKey(ff css.FontFace, urls []string) string {
+	return strings.Join([]string{
+		ff.Family,
+		ff.Weight,
+		ff.Style,
+		ff.Stretch,
+		ff.UnicodeRange,
+		strings.Join(urls, ","),
+	}, "|")
+}
+
+func stripQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i != -1 {
+		return u[:i]
+	}
+	return u
+}
+
+func sortAssets(as []assetmanager.Asset) {
+	sort.Slice(as, func(i, j int) bool {
+		ui, _ := as[i].URL()
+		uj, _ := as[j].URL()
+		return ui < uj
+	})
+}
+
+type assetManager interface {
+	WithID(id string) map[assets.Type][]assetmanager.Asset
+	WithType(t assets.Type) []assetmanager.Asset
+}
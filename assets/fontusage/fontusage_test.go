@@ -0,0 +1,199 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontusage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetstubs"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	errInjected = errors.New("injected error")
+
+	sansWOFF2 = &assetstubs.Asset{
+		TypeReturn:    assets.WOFF2,
+		URLReturn:     "/fonts/sans.woff2",
+		IsLocalReturn: true,
+	}
+	sansWOFF = &assetstubs.Asset{
+		TypeReturn:    assets.WOFF,
+		URLReturn:     "/fonts/sans.woff",
+		IsLocalReturn: true,
+	}
+	monoWOFF2 = &assetstubs.Asset{
+		TypeReturn:    assets.WOFF2,
+		URLReturn:     "/fonts/mono.woff2",
+		IsLocalReturn: true,
+	}
+	fontTypeReturn = map[assets.Type][]assetmanager.Asset{
+		assets.WOFF2: {sansWOFF2, monoWOFF2},
+		assets.WOFF:  {sansWOFF},
+	}
+)
+
+func TestFonts(t *testing.T) {
+	tests := []struct {
+		description string
+		manager     *assetstubs.Manager
+		keys        []string
+		cssTypes    []assets.Type
+		want        []Font
+		wantError   error
+	}{
+		{
+			description: "return nothing without font assets",
+			manager: &assetstubs.Manager{
+				WithIDReturn: map[string]map[assets.Type][]assetmanager.Asset{
+					"always": {
+						assets.InlineCSS: {
+							&assetstubs.Asset{
+								URLReturn:      "/css/always.css",
+								ContentsReturn: `@font-face{font-family:Sans;src:url(../fonts/sans.woff2)}`,
+								IsLocalReturn:  true,
+							},
+						},
+					},
+				},
+			},
+			keys:     []string{"always"},
+			cssTypes: []assets.Type{assets.InlineCSS},
+		},
+		{
+			description: "return error if css contents fail",
+			manager: &assetstubs.Manager{
+				WithTypeReturn: fontTypeReturn,
+				WithIDReturn: map[string]map[assets.Type][]assetmanager.Asset{
+					"always": {
+						assets.InlineCSS: {
+							&assetstubs.Asset{
+								URLReturn:     "/css/always.css",
+								ContentsError: errInjected,
+								IsLocalReturn: true,
+							},
+						},
+					},
+				},
+			},
+			keys:      []string{"always"},
+			cssTypes:  []assets.Type{assets.InlineCSS},
+			wantError: errInjected,
+		},
+		{
+			description: "return fonts for matching css types in order",
+			manager: &assetstubs.Manager{
+				WithTypeReturn: fontTypeReturn,
+				WithIDReturn: map[string]map[assets.Type][]assetmanager.Asset{
+					"always": {
+						assets.InlineCSS: {
+							&assetstubs.Asset{
+								URLReturn:      "/css/always.css",
+								ContentsReturn: `@font-face{font-family:Sans;src:url(../fonts/sans.woff2?v=1) format("woff2"),url(../fonts/sans.woff) format("woff")}`,
+								IsLocalReturn:  true,
+							},
+						},
+						assets.AsyncCSS: {
+							&assetstubs.Asset{
+								URLReturn:      "/css/always-async.css",
+								ContentsReturn: `@font-face{font-family:Mono;src:url(/fonts/mono.woff2)}`,
+								IsLocalReturn:  true,
+							},
+						},
+					},
+					"pre": {
+						assets.SyncCSS: {
+							&assetstubs.Asset{
+								URLReturn:      "/pre.css",
+								ContentsReturn: `@font-face{font-family:Mono;src:url(fonts/mono.woff2)}@font-face{font-family:Remote;src:url(https://example.com/remote.woff2)}`,
+								IsLocalReturn:  true,
+							},
+						},
+					},
+				},
+			},
+			keys:     []string{"always", "pre"},
+			cssTypes: []assets.Type{assets.InlineCSS, assets.SyncCSS},
+			want: []Font{
+				{
+					Family: "Sans",
+					Assets: []assetmanager.Asset{sansWOFF2, sansWOFF},
+				},
+				{
+					Family: "Mono",
+					Assets: []assetmanager.Asset{monoWOFF2},
+				},
+			},
+		},
+		{
+			description: "return faces that share a font file but not duplicate faces",
+			manager: &assetstubs.Manager{
+				WithTypeReturn: fontTypeReturn,
+				WithIDReturn: map[string]map[assets.Type][]assetmanager.Asset{
+					"always": {
+						assets.InlineCSS: {
+							&assetstubs.Asset{
+								URLReturn:      "/css/always.css",
+								ContentsReturn: `@font-face{font-family:Sans;font-weight:400;src:url(/fonts/sans.woff2)}@font-face{font-family:Sans;font-weight:700;src:url(/fonts/sans.woff2)}`,
+								IsLocalReturn:  true,
+							},
+						},
+					},
+					"pre": {
+						assets.InlineCSS: {
+							&assetstubs.Asset{
+								URLReturn:      "/css/pre.css",
+								ContentsReturn: `@font-face{font-family:Sans;font-weight:400;src:url(../fonts/sans.woff2)}`,
+								IsLocalReturn:  true,
+							},
+						},
+					},
+				},
+			},
+			keys:     []string{"always", "pre"},
+			cssTypes: []assets.Type{assets.InlineCSS},
+			want: []Font{
+				{
+					Family: "Sans",
+					Weight: "400",
+					Assets: []assetmanager.Asset{sansWOFF2},
+				},
+				{
+					Family: "Sans",
+					Weight: "700",
+					Assets: []assetmanager.Asset{sansWOFF2},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := Fonts(tt.manager, tt.keys, tt.cssTypes...)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
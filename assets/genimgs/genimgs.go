@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,20 +20,27 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/files"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/svg"
 	"golang.org/x/sync/semaphore"
 	"golang.org/x/sync/singleflight"
 )
 
 const (
 	maxS3ParallelRequests = 2
+
+	defaultSocialWidth  = 1200
+	defaultSocialHeight = 630
 )
 
 var (
 	errFileHash = errors.New("failed to get file hash")
 	errRelPath  = errors.New("unable to get relative path")
 
+	socialNameRegex = regexp.MustCompile(`^social-(\d+)x(\d+)$`)
+
 	imagingOpen = imaging.Open
 	filesHash   = files.Hash
+	osOpen      = os.Open
 
 	s3Sem   = semaphore.NewWeighted(maxS3ParallelRequests)
 	s3Group singleflight.Group
@@ -44,11 +55,82 @@ func Open(conf *config.Config, imgPath string) (image.Image, error) {
 	return imagingOpen(getPath(conf, imgPath))
 }
 
+// SVGSize returns the intrinsic width and height of an svg in the static dir.
+// SVGs are never rasterized so this is used instead of Open.
+func SVGSize(conf *config.Config, imgPath string) (int, int, error) {
+	f, err := osOpen(getPath(conf, imgPath))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	return svg.Size(f)
+}
+
+// IsSVG returns true if the image path refers to an svg
+func IsSVG(imgPath string) bool {
+	if u, err := url.Parse(imgPath); err == nil {
+		imgPath = u.Path
+	}
+	return strings.EqualFold(path.Ext(imgPath), ".svg")
+}
+
 type S3ClientInterface interface {
 	ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 }
 
+// SocialSize returns the width and height of generated social images
+func SocialSize(conf *config.Config) (int, int) {
+	w, h := defaultSocialWidth, defaultSocialHeight
+	if conf.Social != nil && conf.Social.Width > 0 {
+		w = conf.Social.Width
+	}
+	if conf.Social != nil && conf.Social.Height > 0 {
+		h = conf.Social.Height
+	}
+	return w, h
+}
+
+// SocialName returns the filename, without extension, of a generated social
+// image of the given size
+func SocialName(width, height int) string {
+	return fmt.Sprintf("social-%vx%v", width, height)
+}
+
+// LookupSizes returns the generated widths of an image
 func LookupSizes(s3Client S3ClientInterface, conf *config.Config, imgPath string) ([]GenImg, error) {
+	imgs, err := lookup(s3Client, conf, imgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := []GenImg{}
+	for _, i := range imgs {
+		if i.Height == 0 {
+			sizes = append(sizes, i)
+		}
+	}
+	return sizes, nil
+}
+
+// LookupSocial returns the generated social image of an image, or nil if
+// there isn't one for the configured size
+func LookupSocial(s3Client S3ClientInterface, conf *config.Config, imgPath string) (*GenImg, error) {
+	imgs, err := lookup(s3Client, conf, imgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := SocialSize(conf)
+	for _, i := range imgs {
+		if i.Type == "" && i.Size == int64(w) && i.Height == int64(h) {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+func lookup(s3Client S3ClientInterface, conf *config.Config, imgPath string) ([]GenImg, error) {
 	res, err, _ := s3Group.Do(imgPath, func() (interface{}, error) {
 		if val, ok := s3Cache.Load(imgPath); ok {
 			return val.([]GenImg), nil
@@ -107,6 +189,28 @@ func getImageSizes(s3Client S3ClientInterface, conf *config.Config, srcPath, has
 		ext := filepath.Ext(file)
 		filename := strings.TrimSuffix(file, ext)
 
+		var typ string
+		switch ext {
+		case ".webp":
+			typ = "image/webp"
+		case ".avif":
+			typ = "image/avif"
+		}
+
+		// Social images are cropped to an exact size so aren't limited by
+		// the max size
+		if m := socialNameRegex.FindStringSubmatch(filename); m != nil {
+			width, _ := strconv.ParseInt(m[1], 10, 64)
+			height, _ := strconv.ParseInt(m[2], 10, 64)
+			imgs = append(imgs, GenImg{
+				URL:    filepath.Join("/", generatedDirURL, file),
+				Type:   typ,
+				Size:   width,
+				Height: height,
+			})
+			continue
+		}
+
 		size, err := strconv.ParseInt(filename, 10, 64)
 		if err != nil {
 			continue
@@ -116,14 +220,6 @@ func getImageSizes(s3Client S3ClientInterface, conf *config.Config, srcPath, has
 			continue
 		}
 
-		var typ string
-		switch ext {
-		case ".webp":
-			typ = "image/webp"
-		case ".avif":
-			typ = "image/avif"
-		}
-
 		imgs = append(imgs, GenImg{
 			URL:  filepath.Join("/", generatedDirURL, file),
 			Type: typ,
@@ -188,4 +284,7 @@ type GenImg struct {
 	URL  string
 	Type string
 	Size int64
+	// The height of images cropped to an exact size, 0 for images that keep
+	// the aspect ratio of the original
+	Height int64
 }
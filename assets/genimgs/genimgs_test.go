@@ -2,8 +2,11 @@ package genimgs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -13,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/disintegration/imaging"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -23,6 +27,9 @@ type mockS3Client struct {
 	mu        sync.Mutex
 	active    int32
 	maxActive int32
+
+	// Filenames to return, a single 100.webp when empty
+	files []string
 }
 
 func (m *mockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
@@ -40,13 +47,17 @@ func (m *mockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjects
 		time.Sleep(m.delay)
 	}
 
-	key := fmt.Sprintf("%v/100.webp", *params.Prefix)
+	files := m.files
+	if len(files) == 0 {
+		files = []string{"100.webp"}
+	}
+	objs := []types.Object{}
+	for _, f := range files {
+		key := fmt.Sprintf("%v/%v", *params.Prefix, f)
+		objs = append(objs, types.Object{Key: &key})
+	}
 	return &s3.ListObjectsV2Output{
-		Contents: []types.Object{
-			{
-				Key: &key,
-			},
-		},
+		Contents:    objs,
 		IsTruncated: nil,
 	}, nil
 }
@@ -185,3 +196,138 @@ func TestLookupSizes_Semaphore(t *testing.T) {
 		t.Errorf("Expected max %v concurrent S3 calls, got %v", maxS3ParallelRequests, m.maxActive)
 	}
 }
+
+func TestLookupSocial(t *testing.T) {
+	oldFilesHash := filesHash
+	defer func() {
+		filesHash = oldFilesHash
+	}()
+	filesHash = func(path string) (string, error) {
+		return "mockhash", nil
+	}
+
+	tests := []struct {
+		description string
+		social      *config.SocialConfig
+		wantSizes   []GenImg
+		wantSocial  *GenImg
+	}{
+		{
+			description: "return the default social size",
+			wantSizes: []GenImg{
+				{URL: "/output/test.mockhash/400.jpg", Size: 400},
+			},
+			wantSocial: &GenImg{URL: "/output/test.mockhash/social-1200x630.jpg", Size: 1200, Height: 630},
+		},
+		{
+			description: "return the configured social size",
+			social: &config.SocialConfig{
+				Width:  800,
+				Height: 800,
+			},
+			wantSizes: []GenImg{
+				{URL: "/output/test.mockhash/400.jpg", Size: 400},
+			},
+			wantSocial: &GenImg{URL: "/output/test.mockhash/social-800x800.jpg", Size: 800, Height: 800},
+		},
+		{
+			description: "return nil when the social size wasn't generated",
+			social: &config.SocialConfig{
+				Width:  600,
+				Height: 600,
+			},
+			wantSizes: []GenImg{
+				{URL: "/output/test.mockhash/400.jpg", Size: 400},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			s3Cache = sync.Map{}
+			s3Group = singleflight.Group{}
+
+			conf := &config.Config{
+				Assets: &config.AssetsConfig{
+					StaticDir: "/static",
+				},
+				GenAssets: &config.GeneratedImagesConfig{
+					StaticDir:       "/static",
+					OutputDir:       "/static/output",
+					OutputBucket:    "bucket",
+					OutputBucketDir: "gen",
+					MaxWidth:        500,
+					MaxDensity:      1,
+				},
+				Social: tt.social,
+			}
+			m := &mockS3Client{
+				files: []string{"400.jpg", "social-1200x630.jpg", "social-800x800.jpg", "social-800x800.webp"},
+			}
+
+			sizes, err := LookupSizes(m, conf, "test.jpg")
+			if err != nil {
+				t.Fatalf("LookupSizes failed: %v", err)
+			}
+			if diff := cmp.Diff(sizes, tt.wantSizes); diff != "" {
+				t.Fatalf("Unexpected sizes; diff %v", diff)
+			}
+
+			social, err := LookupSocial(m, conf, "test.jpg")
+			if err != nil {
+				t.Fatalf("LookupSocial failed: %v", err)
+			}
+			if diff := cmp.Diff(social, tt.wantSocial); diff != "" {
+				t.Fatalf("Unexpected social image; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestSVGSize(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "icon.svg"), []byte(`<svg viewBox="0 0 32 16"></svg>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write svg: %v", err)
+	}
+
+	conf := &config.Config{
+		Assets: &config.AssetsConfig{
+			StaticDir: dir,
+		},
+	}
+
+	w, h, err := SVGSize(conf, "/icon.svg")
+	if err != nil {
+		t.Fatalf("SVGSize failed: %v", err)
+	}
+	if w != 32 || h != 16 {
+		t.Errorf("Unexpected size; got %vx%v, want 32x16", w, h)
+	}
+
+	_, _, err = SVGSize(conf, "/missing.svg")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Unexpected error for missing svg; got %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestIsSVG(t *testing.T) {
+	tests := []struct {
+		imgPath string
+		want    bool
+	}{
+		{imgPath: "/images/icon.svg", want: true},
+		{imgPath: "/images/icon.SVG", want: true},
+		{imgPath: "/images/icon.svg?v=1", want: true},
+		{imgPath: "/images/photo.png", want: false},
+		{imgPath: "/images/svg", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.imgPath, func(t *testing.T) {
+			if got := IsSVG(tt.imgPath); got != tt.want {
+				t.Errorf("IsSVG(%q) = %v, want %v", tt.imgPath, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package icons generates favicons, app icons and a web app manifest from a
+// single source image
+package icons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chai2010/webp"
+	"github.com/disintegration/imaging"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+)
+
+const (
+	// Favicon is the filename of the favicon with every favicon size
+	Favicon = "favicon.ico"
+	// AppleTouchIcon is the filename of the icon used by iOS
+	AppleTouchIcon = "apple-touch-icon.png"
+	// Manifest is the filename of the web app manifest
+	Manifest = "manifest.webmanifest"
+
+	appleTouchIconSize = 180
+
+	// The fraction of maskable icons kept clear on each side so the source
+	// stays in the safe zone when masked
+	maskablePadding = 0.1
+
+	// The smallest source that doesn't need to be scaled up
+	minSourceSize = 512
+
+	defaultStartURL        = "/"
+	defaultDisplay         = "standalone"
+	defaultBackgroundColor = "#ffffff"
+)
+
+var (
+	errNoIconsConfig = errors.New("no icons config")
+	errNoSource      = errors.New("no icons source image")
+	errNoOutputDir   = errors.New("no icons output dir")
+	errNoStaticDir   = errors.New("no assets static-dir")
+	errInvalidColor  = errors.New("invalid color")
+
+	// ErrOutsideStatic is returned when the icons output dir can't be served
+	// from the static-dir
+	ErrOutsideStatic = errors.New("icons output dir must be in the static-dir")
+
+	// FaviconSizes are the sizes in the favicon
+	FaviconSizes = []int{16, 32, 48}
+	appIconSizes = []int{192, 512}
+
+	imagingOpen     = imaging.Open
+	ioutilWriteFile = ioutil.WriteFile
+)
+
+// URL returns the URL of the icons output dir, without a trailing slash
+func URL(conf *config.Config) (string, error) {
+	if conf.Icons == nil {
+		return "", errNoIconsConfig
+	}
+	if conf.Icons.OutputDir == "" {
+		return "", errNoOutputDir
+	}
+	if conf.Assets == nil || conf.Assets.StaticDir == "" {
+		return "", errNoStaticDir
+	}
+
+	rel, err := filepath.Rel(conf.Assets.StaticDir, conf.Icons.OutputDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrOutsideStatic, conf.Icons.OutputDir)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return "/" + filepath.ToSlash(rel), nil
+}
+
+// Generate writes the favicon, apple-touch-icon, app icons and manifest to the
+// icons output dir and returns the paths written
+func Generate(conf *config.Config) ([]string, error) {
+	if conf.Icons == nil {
+		return nil, errNoIconsConfig
+	}
+	if conf.Icons.Source == "" {
+		return nil, errNoSource
+	}
+	dirURL, err := URL(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	bg, err := parseColor(backgroundColor(conf.Icons))
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := imagingOpen(conf.Icons.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open icons source %q: %w", conf.Icons.Source, err)
+	}
+	if b := src.Bounds(); b.Dx() < minSourceSize || b.Dy() < minSourceSize {
+		log.Printf("Warning: The icons source %q is %vx%v, icons will be scaled up from less than %vx%v", conf.Icons.Source, b.Dx(), b.Dy(), minSourceSize, minSourceSize)
+	}
+
+	err = os.MkdirAll(conf.Icons.OutputDir, 0777)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create icons output dir: %w", err)
+	}
+
+	written := []string{}
+	write := func(name string, b []byte) error {
+		p := filepath.Join(conf.Icons.OutputDir, name)
+		if err := ioutilWriteFile(p, b, 0644); err != nil {
+			return fmt.Errorf("failed to write %q: %w", p, err)
+		}
+		written = append(written, p)
+		return nil
+	}
+
+	favicons := []image.Image{}
+	for _, s := range FaviconSizes {
+		favicons = append(favicons, square(src, s, s, nil))
+	}
+	ico, err := encodeICO(favicons)
+	if err != nil {
+		return nil, err
+	}
+	if err := write(Favicon, ico); err != nil {
+		return nil, err
+	}
+
+	b, err := encodePNG(square(src, appleTouchIconSize, appleTouchIconSize, bg))
+	if err != nil {
+		return nil, err
+	}
+	if err := write(AppleTouchIcon, b); err != nil {
+		return nil, err
+	}
+
+	m := manifest{
+		Name:            conf.Icons.Name,
+		ShortName:       conf.Icons.ShortName,
+		StartURL:        defaultStartURL,
+		Display:         defaultDisplay,
+		ThemeColor:      conf.Icons.ThemeColor,
+		BackgroundColor: backgroundColor(conf.Icons),
+		Icons:           []manifestIcon{},
+	}
+	if conf.Icons.StartURL != "" {
+		m.StartURL = conf.Icons.StartURL
+	}
+	if conf.Icons.Display != "" {
+		m.Display = conf.Icons.Display
+	}
+
+	for _, s := range appIconSizes {
+		sizes := fmt.Sprintf("%vx%v", s, s)
+
+		name := fmt.Sprintf("icon-%v.png", s)
+		b, err := encodePNG(square(src, s, s, nil))
+		if err != nil {
+			return nil, err
+		}
+		if err := write(name, b); err != nil {
+			return nil, err
+		}
+		m.Icons = append(m.Icons, manifestIcon{Src: dirURL + "/" + name, Sizes: sizes, Type: "image/png", Purpose: "any"})
+
+		inner := int(float64(s) * (1 - 2*maskablePadding))
+		maskable := square(src, s, inner, bg)
+
+		// WebP is listed first so browsers that support it prefer it
+		name = fmt.Sprintf("maskable-%v.webp", s)
+		var buf bytes.Buffer
+		if err := webp.Encode(&buf, maskable, &webp.Options{Lossless: true}); err != nil {
+			return nil, fmt.Errorf("failed to encode %q: %w", name, err)
+		}
+		if err := write(name, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		m.Icons = append(m.Icons, manifestIcon{Src: dirURL + "/" + name, Sizes: sizes, Type: "image/webp", Purpose: "maskable"})
+
+		name = fmt.Sprintf("maskable-%v.png", s)
+		b, err = encodePNG(maskable)
+		if err != nil {
+			return nil, err
+		}
+		if err := write(name, b); err != nil {
+			return nil, err
+		}
+		m.Icons = append(m.Icons, manifestIcon{Src: dirURL + "/" + name, Sizes: sizes, Type: "image/png", Purpose: "maskable"})
+	}
+
+	b, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := write(Manifest, b); err != nil {
+		return nil, err
+	}
+
+	return written, nil
+}
+
+type manifest struct {
+	Name            string         `json:"name,omitempty"`
+	ShortName       string         `json:"short_name,omitempty"`
+	StartURL        string         `json:"start_url"`
+	Display         string         `json:"display"`
+	ThemeColor      string         `json:"theme_color,omitempty"`
+	BackgroundColor string         `json:"background_color"`
+	Icons           []manifestIcon `json:"icons"`
+}
+
+type manifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose"`
+}
+
+func backgroundColor(conf *config.IconsConfig) string {
+	if conf.BackgroundColor != "" {
+		return conf.BackgroundColor
+	}
+	return defaultBackgroundColor
+}
+
+// square returns the source fit within inner x inner, centered on a size x
+// size canvas of the background color, or a transparent one when nil
+func square(src image.Image, size, inner int, bg color.Color) *image.NRGBA {
+	if bg == nil {
+		bg = color.Transparent
+	}
+	canvas := imaging.New(size, size, bg)
+	fit := imaging.Fit(src, inner, inner, imaging.Lanczos)
+	b := fit.Bounds()
+	pos := image.Pt((size-b.Dx())/2, (size-b.Dy())/2)
+	return imaging.Overlay(canvas, fit, pos, 1)
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeICO returns an ICO file holding every image as a PNG
+func encodeICO(imgs []image.Image) ([]byte, error) {
+	const headerSize = 6
+	const entrySize = 16
+
+	pngs := [][]byte{}
+	for _, img := range imgs {
+		b, err := encodePNG(img)
+		if err != nil {
+			return nil, err
+		}
+		pngs = append(pngs, b)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, uint16(len(imgs))})
+
+	offset := headerSize + entrySize*len(imgs)
+	for i, img := range imgs {
+		b := img.Bounds()
+		// A width or height of 0 means 256 pixels
+		buf.Write([]byte{byte(b.Dx()), byte(b.Dy()), 0, 0})
+		binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(pngs[i])), uint32(offset)})
+		offset += len(pngs[i])
+	}
+	for _, p := range pngs {
+		buf.Write(p)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseColor parses a #rgb or #rrggbb color
+func parseColor(c string) (color.Color, error) {
+	hex := strings.TrimPrefix(c, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if !strings.HasPrefix(c, "#") || len(hex) != 6 {
+		return nil, fmt.Errorf("%w %q, expected #rgb or #rrggbb", errInvalidColor, c)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q, expected #rgb or #rrggbb", errInvalidColor, c)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
@@ -0,0 +1,289 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package icons
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		description string
+		conf        *config.Config
+		want        string
+		wantError   error
+	}{
+		{
+			description: "return error without icons config",
+			conf:        &config.Config{},
+			wantError:   errNoIconsConfig,
+		},
+		{
+			description: "return error without output dir",
+			conf:        &config.Config{Icons: &config.IconsConfig{}},
+			wantError:   errNoOutputDir,
+		},
+		{
+			description: "return error without static dir",
+			conf:        &config.Config{Icons: &config.IconsConfig{OutputDir: "/static/icons"}},
+			wantError:   errNoStaticDir,
+		},
+		{
+			description: "return error when output dir is outside the static dir",
+			conf: &config.Config{
+				Assets: &config.AssetsConfig{StaticDir: "/static"},
+				Icons:  &config.IconsConfig{OutputDir: "/other/icons"},
+			},
+			wantError: ErrOutsideStatic,
+		},
+		{
+			description: "return empty URL for the static dir",
+			conf: &config.Config{
+				Assets: &config.AssetsConfig{StaticDir: "/static"},
+				Icons:  &config.IconsConfig{OutputDir: "/static"},
+			},
+			want: "",
+		},
+		{
+			description: "return URL of the output dir",
+			conf: &config.Config{
+				Assets: &config.AssetsConfig{StaticDir: "/static"},
+				Icons:  &config.IconsConfig{OutputDir: "/static/images/icons"},
+			},
+			want: "/images/icons",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := URL(tt.conf)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Fatalf("Unexpected URL; got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	staticDir := t.TempDir()
+	srcPath := filepath.Join(t.TempDir(), "logo.png")
+	// A red square on a transparent background
+	src := imaging.New(600, 600, color.Transparent)
+	src = imaging.Overlay(src, imaging.New(400, 400, color.NRGBA{R: 255, A: 255}), image.Pt(100, 100), 1)
+	if err := imaging.Save(src, srcPath); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	conf := &config.Config{
+		Assets: &config.AssetsConfig{StaticDir: staticDir},
+		Icons: &config.IconsConfig{
+			Source:          srcPath,
+			OutputDir:       filepath.Join(staticDir, "icons"),
+			Name:            "Example Site",
+			ShortName:       "Example",
+			ThemeColor:      "#000",
+			BackgroundColor: "#00f",
+		},
+	}
+
+	written, err := Generate(conf)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	names := []string{}
+	for _, w := range written {
+		names = append(names, filepath.Base(w))
+	}
+	wantNames := []string{
+		"favicon.ico",
+		"apple-touch-icon.png",
+		"icon-192.png",
+		"maskable-192.webp",
+		"maskable-192.png",
+		"icon-512.png",
+		"maskable-512.webp",
+		"maskable-512.png",
+		"manifest.webmanifest",
+	}
+	if diff := cmp.Diff(names, wantNames); diff != "" {
+		t.Fatalf("Unexpected files; diff %v", diff)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(staticDir, "icons", Manifest))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	wantManifest := manifest{
+		Name:            "Example Site",
+		ShortName:       "Example",
+		StartURL:        "/",
+		Display:         "standalone",
+		ThemeColor:      "#000",
+		BackgroundColor: "#00f",
+		Icons: []manifestIcon{
+			{Src: "/icons/icon-192.png", Sizes: "192x192", Type: "image/png", Purpose: "any"},
+			{Src: "/icons/maskable-192.webp", Sizes: "192x192", Type: "image/webp", Purpose: "maskable"},
+			{Src: "/icons/maskable-192.png", Sizes: "192x192", Type: "image/png", Purpose: "maskable"},
+			{Src: "/icons/icon-512.png", Sizes: "512x512", Type: "image/png", Purpose: "any"},
+			{Src: "/icons/maskable-512.webp", Sizes: "512x512", Type: "image/webp", Purpose: "maskable"},
+			{Src: "/icons/maskable-512.png", Sizes: "512x512", Type: "image/png", Purpose: "maskable"},
+		},
+	}
+	if diff := cmp.Diff(m, wantManifest); diff != "" {
+		t.Fatalf("Unexpected manifest; diff %v", diff)
+	}
+
+	ico, err := ioutil.ReadFile(filepath.Join(staticDir, "icons", Favicon))
+	if err != nil {
+		t.Fatalf("Failed to read favicon: %v", err)
+	}
+	if got := binary.LittleEndian.Uint16(ico[4:6]); got != uint16(len(FaviconSizes)) {
+		t.Fatalf("Unexpected favicon image count; got %v, want %v", got, len(FaviconSizes))
+	}
+	for i, s := range FaviconSizes {
+		if got := int(ico[6+i*16]); got != s {
+			t.Fatalf("Unexpected favicon size; got %v, want %v", got, s)
+		}
+	}
+
+	maskable := mustOpenPNG(t, filepath.Join(staticDir, "icons", "maskable-512.png"))
+	if diff := cmp.Diff(color.NRGBAModel.Convert(maskable.At(0, 0)), color.NRGBA{B: 255, A: 255}); diff != "" {
+		t.Fatalf("Unexpected maskable background; diff %v", diff)
+	}
+	if diff := cmp.Diff(color.NRGBAModel.Convert(maskable.At(256, 256)), color.NRGBA{R: 255, A: 255}); diff != "" {
+		t.Fatalf("Unexpected maskable center; diff %v", diff)
+	}
+
+	icon := mustOpenPNG(t, filepath.Join(staticDir, "icons", "icon-512.png"))
+	if _, _, _, a := icon.At(0, 0).RGBA(); a != 0 {
+		t.Fatalf("Unexpected icon background alpha; got %v, want 0", a)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := []struct {
+		description string
+		conf        *config.Config
+		wantError   error
+	}{
+		{
+			description: "return error without icons config",
+			conf:        &config.Config{},
+			wantError:   errNoIconsConfig,
+		},
+		{
+			description: "return error without source",
+			conf:        &config.Config{Icons: &config.IconsConfig{}},
+			wantError:   errNoSource,
+		},
+		{
+			description: "return error for invalid background color",
+			conf: &config.Config{
+				Assets: &config.AssetsConfig{StaticDir: "/static"},
+				Icons: &config.IconsConfig{
+					Source:          "/logo.png",
+					OutputDir:       "/static/icons",
+					BackgroundColor: "white",
+				},
+			},
+			wantError: errInvalidColor,
+		},
+		{
+			description: "return error for missing source",
+			conf: &config.Config{
+				Assets: &config.AssetsConfig{StaticDir: "/static"},
+				Icons: &config.IconsConfig{
+					Source:    "/does-not-exist/logo.png",
+					OutputDir: "/static/icons",
+				},
+			},
+			wantError: os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			_, err := Generate(tt.conf)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      color.Color
+		wantError error
+	}{
+		{input: "#fff", want: color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+		{input: "#102030", want: color.NRGBA{R: 16, G: 32, B: 48, A: 255}},
+		{input: "102030", wantError: errInvalidColor},
+		{input: "#1020", wantError: errInvalidColor},
+		{input: "#zzzzzz", wantError: errInvalidColor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseColor(tt.input)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected color; diff %v", diff)
+			}
+		})
+	}
+}
+
+func mustOpenPNG(t *testing.T, p string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatalf("Failed to open %q: %v", p, err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Failed to decode %q: %v", p, err)
+	}
+	return img
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/icons"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/mitchellh/go-homedir"
+)
+
+var (
+	configPath = flag.String("config", "asset-manager.json", "The path of the Config file.")
+
+	errNoIconsConfig = errors.New("no icons config")
+
+	configGet     = config.Get
+	homedirExpand = homedir.Expand
+	iconsGenerate = icons.Generate
+)
+
+func main() {
+	flag.Parse()
+	if err := run(*configPath); err != nil {
+		fmt.Printf("☠️ Run was not successful: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(p string) error {
+	absConfigPath, err := homedirExpand(p)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for config flag: %w", err)
+	}
+	fmt.Printf("📁 Getting config file: %q\n", absConfigPath)
+
+	conf, err := configGet(absConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	if conf.Icons == nil {
+		return fmt.Errorf("%w in %q", errNoIconsConfig, absConfigPath)
+	}
+
+	fmt.Printf("🖼️ Generating icons from %q\n", conf.Icons.Source)
+	written, err := iconsGenerate(conf)
+	if err != nil {
+		return err
+	}
+	for _, w := range written {
+		fmt.Printf("    - %v\n", w)
+	}
+	fmt.Printf("✅ Done.\n")
+	return nil
+}
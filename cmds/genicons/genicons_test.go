@@ -0,0 +1,86 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/icons"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+)
+
+var errInjected = errors.New("injected error")
+
+func Test_run(t *testing.T) {
+	tests := []struct {
+		description   string
+		configGet     func(string) (*config.Config, error)
+		iconsGenerate func(*config.Config) ([]string, error)
+		wantError     error
+	}{
+		{
+			description: "return error if config can't be read",
+			configGet: func(string) (*config.Config, error) {
+				return nil, errInjected
+			},
+			wantError: errInjected,
+		},
+		{
+			description: "return error without icons config",
+			configGet: func(string) (*config.Config, error) {
+				return &config.Config{}, nil
+			},
+			wantError: errNoIconsConfig,
+		},
+		{
+			description: "return error if generating fails",
+			configGet: func(string) (*config.Config, error) {
+				return &config.Config{Icons: &config.IconsConfig{}}, nil
+			},
+			iconsGenerate: func(*config.Config) ([]string, error) {
+				return nil, errInjected
+			},
+			wantError: errInjected,
+		},
+		{
+			description: "generate icons",
+			configGet: func(string) (*config.Config, error) {
+				return &config.Config{Icons: &config.IconsConfig{}}, nil
+			},
+			iconsGenerate: func(*config.Config) ([]string, error) {
+				return []string{"/static/favicon.ico"}, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			configGet = tt.configGet
+			iconsGenerate = tt.iconsGenerate
+			t.Cleanup(func() {
+				configGet = config.Get
+				iconsGenerate = icons.Generate
+			})
+
+			err := run("asset-manager.json")
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
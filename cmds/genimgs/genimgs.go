@@ -42,9 +42,12 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/gauntface/go-html-asset-manager/v5/assets"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/genimgs"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/files"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/imgcrop"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/sets"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/video"
 	"github.com/mitchellh/go-homedir"
 	"github.com/schollz/progressbar/v3"
 	"golang.org/x/sync/semaphore"
@@ -52,12 +55,19 @@ import (
 
 const (
 	maxS3ParallelRequests = 2
+
+	// The default time in seconds of video poster frames
+	defaultPosterTime = 1
 )
 
 var (
 	configPath      = flag.String("config", "asset-manager.json", "The path of the Config file.")
 	cacheControlAge = flag.Int64("cache_control", 31104000, "The max age for caching images")
 	homedirExpand   = homedir.Expand
+
+	osStat            = os.Stat
+	videoProbe        = video.Probe
+	videoExtractFrame = video.ExtractFrame
 )
 
 func main() {
@@ -80,6 +90,12 @@ type client struct {
 	s3BucketDir string
 	maxWidth    int64
 
+	// Generate a social image of socialWidth x socialHeight for every image
+	social       bool
+	socialWidth  int
+	socialHeight int
+	focalPoints  map[string]*config.FocalPoint
+
 	staticManager    *assetmanager.Manager
 	generatedManager *assetmanager.Manager
 	s3               *s3.Client
@@ -109,6 +125,20 @@ func newClient(ctx context.Context) (*client, error) {
 		return nil, fmt.Errorf("failed to create output directory: %v", err)
 	}
 
+	// Posters are written next to the videos in the static dir that
+	// htmlassets looks for them in, before finding images so they are
+	// generated too when it is also the gen-assets static dir
+	if c.Video != nil {
+		if c.Assets == nil || c.Assets.StaticDir == "" {
+			fmt.Printf("🎞️ Skipping video posters without an assets static dir\n")
+		} else {
+			err = createPosters(c.Assets.StaticDir, c.Video)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	staticManager, err := assetmanager.NewManager("", c.GenAssets.StaticDir, "")
 	if err != nil {
 		return nil, err
@@ -130,12 +160,25 @@ func newClient(ctx context.Context) (*client, error) {
 	s3Client := s3.NewFromConfig(cfg)
 	s3Manager := s3manager.NewUploader(s3Client)
 
+	socialWidth, socialHeight := genimgs.SocialSize(c)
+	focalPoints := map[string]*config.FocalPoint{}
+	if c.Social != nil {
+		fmt.Printf("🖼️ Social images will be %vx%v\n", socialWidth, socialHeight)
+		if c.Social.FocalPoints != nil {
+			focalPoints = c.Social.FocalPoints
+		}
+	}
+
 	return &client{
 		staticdir:        c.GenAssets.StaticDir,
 		outputdir:        c.GenAssets.OutputDir,
 		s3Bucket:         c.GenAssets.OutputBucket,
 		s3BucketDir:      c.GenAssets.OutputBucketDir,
 		maxWidth:         maxWidth,
+		social:           c.Social != nil,
+		socialWidth:      socialWidth,
+		socialHeight:     socialHeight,
+		focalPoints:      focalPoints,
 		staticManager:    staticManager,
 		generatedManager: generatedManager,
 		s3:               s3Client,
@@ -191,6 +234,53 @@ func (c *client) run(ctx context.Context) error {
 	return nil
 }
 
+// createPosters extracts a poster frame next to every video in the static dir
+// that doesn't have one newer than the video
+func createPosters(staticDir string, conf *config.VideoConfig) error {
+	videos, err := files.Find(staticDir, video.Extensions...)
+	if err != nil {
+		return err
+	}
+
+	created := 0
+	for _, v := range videos {
+		poster := video.PosterPath(v)
+		vi, err := osStat(v)
+		if err != nil {
+			return err
+		}
+		if pi, err := osStat(poster); err == nil && !pi.ModTime().Before(vi.ModTime()) {
+			continue
+		}
+
+		info, err := videoProbe(v)
+		if err != nil {
+			return err
+		}
+		err = videoExtractFrame(v, poster, posterTime(conf.PosterTime, info.Duration))
+		if err != nil {
+			return err
+		}
+		created++
+	}
+
+	fmt.Printf("🎞️ Created %v video posters for %v videos\n", created, len(videos))
+	return nil
+}
+
+// posterTime returns the time of the poster frame, keeping it within the
+// video
+func posterTime(configured, duration float64) float64 {
+	at := configured
+	if at <= 0 {
+		at = defaultPosterTime
+	}
+	if duration > 0 && at >= duration {
+		at = duration / 2
+	}
+	return at
+}
+
 func (c *client) getS3GenImages(ctx context.Context) ([]awstypes.Object, error) {
 	if err := c.s3Sem.Acquire(ctx, 1); err != nil {
 		return nil, err
@@ -411,9 +501,37 @@ func (c *client) generateImageSet(imgPath string) ([]generateImage, error) {
 		)
 	}
 
+	if c.social {
+		genImgs = append(genImgs, c.socialImage(imgPath, outputDir))
+	}
+
 	return genImgs, nil
 }
 
+// socialImage returns the image cropped to the social image size, as a JPEG
+// unless the original is a PNG or JPEG as not every site reads other formats
+func (c *client) socialImage(imgPath, outputDir string) generateImage {
+	ext := strings.ToLower(filepath.Ext(imgPath))
+	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+		ext = ".jpg"
+	}
+
+	var focus *imgcrop.Focus
+	if rel, err := filepath.Rel(c.staticdir, imgPath); err == nil {
+		if fp, ok := c.focalPoints["/"+filepath.ToSlash(rel)]; ok && fp != nil {
+			focus = &imgcrop.Focus{X: fp.X, Y: fp.Y}
+		}
+	}
+
+	return generateImage{
+		originalPath: imgPath,
+		width:        c.socialWidth,
+		height:       c.socialHeight,
+		focus:        focus,
+		outputPath:   path.Join(outputDir, genimgs.SocialName(c.socialWidth, c.socialHeight)+ext),
+	}
+}
+
 func (c *client) generatedDir(imgPath string) (string, error) {
 	hash, err := files.Hash(imgPath)
 	if err != nil {
@@ -517,13 +635,22 @@ func (c *client) uploadImage(ctx context.Context, img generateImage) error {
 	return err
 }
 
+// resize returns the source image at the generated image's width, cropped
+// to its height when it has one
+func resize(srcImg image.Image, img generateImage) image.Image {
+	if img.height > 0 {
+		return imgcrop.Fill(srcImg, img.width, img.height, img.focus)
+	}
+	return imaging.Resize(srcImg, img.width, 0, imaging.Lanczos)
+}
+
 func createImagingImage(img generateImage) error {
 	srcImg, err := imaging.Open(img.originalPath)
 	if err != nil {
 		return err
 	}
 
-	dst := imaging.Resize(srcImg, img.width, 0, imaging.Lanczos)
+	dst := resize(srcImg, img)
 	err = imaging.Save(dst, img.outputPath)
 	if err != nil {
 		return err
@@ -537,7 +664,7 @@ func createWebpImage(img generateImage) error {
 		return err
 	}
 
-	dst := imaging.Resize(srcImg, img.width, 0, imaging.Lanczos)
+	dst := resize(srcImg, img)
 
 	f, err := os.Create(img.outputPath)
 	if err != nil {
@@ -564,6 +691,8 @@ func createAvifImage(img generateImage) error {
 	err = createImagingImage(generateImage{
 		originalPath: img.originalPath,
 		width:        img.width,
+		height:       img.height,
+		focus:        img.focus,
 		outputPath:   tmpPath,
 	})
 	if err != nil {
@@ -589,5 +718,8 @@ func createAvifImage(img generateImage) error {
 type generateImage struct {
 	originalPath string
 	width        int
-	outputPath   string
+	// The height to crop to, the aspect ratio is kept when 0
+	height     int
+	focus      *imgcrop.Focus
+	outputPath string
 }
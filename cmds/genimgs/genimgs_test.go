@@ -16,7 +16,23 @@
 
 package main
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/imgcrop"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/video"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
 
 func TestCacheControlHeader(t *testing.T) {
 	tests := []struct {
@@ -45,3 +61,174 @@ func TestCacheControlHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestSocialImage(t *testing.T) {
+	tests := []struct {
+		name      string
+		imgPath   string
+		outputDir string
+		want      generateImage
+	}{
+		{
+			name:      "keep jpeg images as jpeg",
+			imgPath:   "/static/images/photo.jpeg",
+			outputDir: "/static/gen/photo.abc",
+			want: generateImage{
+				originalPath: "/static/images/photo.jpeg",
+				width:        1200,
+				height:       630,
+				outputPath:   "/static/gen/photo.abc/social-1200x630.jpeg",
+			},
+		},
+		{
+			name:      "convert webp images to jpeg",
+			imgPath:   "/static/images/photo.webp",
+			outputDir: "/static/gen/photo.abc",
+			want: generateImage{
+				originalPath: "/static/images/photo.webp",
+				width:        1200,
+				height:       630,
+				outputPath:   "/static/gen/photo.abc/social-1200x630.jpg",
+			},
+		},
+		{
+			name:      "use the focal point of the image",
+			imgPath:   "/static/images/portrait.png",
+			outputDir: "/static/gen/portrait.abc",
+			want: generateImage{
+				originalPath: "/static/images/portrait.png",
+				width:        1200,
+				height:       630,
+				focus:        &imgcrop.Focus{X: 0.5, Y: 0.2},
+				outputPath:   "/static/gen/portrait.abc/social-1200x630.png",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{
+				staticdir:    "/static",
+				social:       true,
+				socialWidth:  1200,
+				socialHeight: 630,
+				focalPoints: map[string]*config.FocalPoint{
+					"/images/portrait.png": {X: 0.5, Y: 0.2},
+				},
+			}
+			got := c.socialImage(tt.imgPath, tt.outputDir)
+			if diff := cmp.Diff(got, tt.want, cmp.AllowUnexported(generateImage{})); diff != "" {
+				t.Fatalf("Unexpected social image; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestPosterTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured float64
+		duration   float64
+		want       float64
+	}{
+		{name: "default to 1 second", duration: 10, want: 1},
+		{name: "use the configured time", configured: 4.5, duration: 10, want: 4.5},
+		{name: "use half way for short videos", configured: 4, duration: 3, want: 1.5},
+		{name: "use the configured time for unknown durations", configured: 4, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := posterTime(tt.configured, tt.duration); got != tt.want {
+				t.Fatalf("posterTime(%v, %v) = %v, want %v", tt.configured, tt.duration, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePosters(t *testing.T) {
+	// Poster mod times relative to their video, nil for no poster
+	older, newer := -time.Hour, time.Hour
+	posters := map[string]*time.Duration{
+		"current.mp4":     &newer,
+		"stale.mp4":       &older,
+		"videos/new.webm": nil,
+	}
+
+	tests := []struct {
+		name       string
+		probeError error
+		want       []string
+		wantError  error
+	}{
+		{
+			name: "extract posters that are missing or older than the video",
+			want: []string{
+				"stale.mp4 -> stale.poster.jpg @ 5",
+				"videos/new.webm -> videos/new.poster.jpg @ 5",
+			},
+		},
+		{
+			name:       "return error if probing fails",
+			probeError: errInjected,
+			wantError:  errInjected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				videoProbe = video.Probe
+				videoExtractFrame = video.ExtractFrame
+			})
+
+			dir := t.TempDir()
+			videoTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			for v, offset := range posters {
+				vp := filepath.Join(dir, v)
+				mustWriteFile(t, vp, videoTime)
+				if offset != nil {
+					mustWriteFile(t, video.PosterPath(vp), videoTime.Add(*offset))
+				}
+			}
+
+			videoProbe = func(p string) (video.Info, error) {
+				return video.Info{Duration: 10}, tt.probeError
+			}
+			got := []string{}
+			videoExtractFrame = func(src, dst string, at float64) error {
+				rs, _ := filepath.Rel(dir, src)
+				rd, _ := filepath.Rel(dir, dst)
+				got = append(got, fmt.Sprintf("%v -> %v @ %v", filepath.ToSlash(rs), filepath.ToSlash(rd), at))
+				return nil
+			}
+
+			err := createPosters(dir, &config.VideoConfig{PosterTime: 5})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+
+			sort.Strings(got)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected posters; diff %v", diff)
+			}
+		})
+	}
+}
+
+func mustWriteFile(t *testing.T, p string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := ioutil.WriteFile(p, []byte(p), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Chtimes(p, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mod time: %v", err)
+	}
+}
@@ -0,0 +1,482 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmllinks"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmllint"
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/net/html"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "hamlint"
+	toolURI      = "https://github.com/gauntface/go-html-asset-manager"
+)
+
+var (
+	configPath = flag.String("config", "asset-manager.json", "The path of the Config file.")
+	format     = flag.String("format", formatText, "The output format, one of text, json or sarif.")
+	links      = flag.Bool("links", false, "Check that local links, images and fragments resolve.")
+
+	errIssuesFound   = errors.New("errors found")
+	errInvalidFormat = errors.New("invalid format")
+
+	configGet              = config.Get
+	homedirExpand          = homedir.Expand
+	assetmanagerNewManager = assetmanager.NewManager
+	osGetwd                = os.Getwd
+	osStat                 = os.Stat
+
+	// Rules for broken links found by the links flag
+	linkRules = []htmllint.Rule{
+		{
+			ID:          "broken-link",
+			Level:       htmllint.LevelError,
+			Description: "Local links must point at an existing file or a directory with an index.html",
+		},
+		{
+			ID:          "broken-fragment",
+			Level:       htmllint.LevelError,
+			Description: "Fragment links must point at an existing id on the page",
+		},
+		{
+			ID:          "missing-generated-image",
+			Level:       htmllint.LevelError,
+			Description: "Generated images must exist in the gen-assets output directory",
+		},
+	}
+)
+
+func main() {
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not initialize client: %v\n", err)
+		os.Exit(1)
+	}
+	if err := c.run(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Run was not successful: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type client struct {
+	format string
+	links  bool
+	// Files are reported relative to this directory
+	wd string
+
+	config  *config.Config
+	manager assetmanagerManager
+}
+
+// page is a parsed HTML file
+type page struct {
+	path      string
+	url       string
+	doc       *html.Node
+	positions map[*html.Node]htmllint.Position
+}
+
+// result is an issue found in a file
+type result struct {
+	File string `json:"file"`
+	htmllint.Issue
+}
+
+func newClient() (*client, error) {
+	flag.Parse()
+
+	absConfigPath, err := homedirExpand(*configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for config flag: %w", err)
+	}
+
+	c, err := configGet(absConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	staticDir, jsonDir := "", ""
+	if c.Assets != nil {
+		staticDir, jsonDir = c.Assets.StaticDir, c.Assets.JSONDir
+	}
+	manager, err := assetmanagerNewManager(c.HTMLDir, staticDir, jsonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := osGetwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	return &client{
+		format:  *format,
+		links:   *links,
+		wd:      wd,
+		config:  c,
+		manager: manager,
+	}, nil
+}
+
+// run lints every HTML file and writes the results. An error is returned if
+// any issue is an error so the exit code can fail a build, warnings alone
+// don't fail.
+func (c *client) run(w io.Writer) error {
+	pages, err := c.pages()
+	if err != nil {
+		return err
+	}
+
+	results := lint(pages)
+	if c.links {
+		results = append(results, c.checkLinks(pages)...)
+	}
+	// Group the results by file
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].File < results[j].File
+	})
+
+	for i := range results {
+		results[i].File = c.relPath(results[i].File)
+	}
+	if err := write(w, c.format, results); err != nil {
+		return err
+	}
+
+	errCount := 0
+	for _, r := range results {
+		if r.Level == htmllint.LevelError {
+			errCount++
+		}
+	}
+	if errCount > 0 {
+		return fmt.Errorf("%w: %v errors", errIssuesFound, errCount)
+	}
+	return nil
+}
+
+func (c *client) pages() ([]page, error) {
+	las := []*assetmanager.LocalAsset{}
+	for _, a := range c.manager.WithType(assets.HTML) {
+		la, ok := a.(*assetmanager.LocalAsset)
+		if !ok {
+			continue
+		}
+		las = append(las, la)
+	}
+	sort.Slice(las, func(i, j int) bool {
+		return las[i].Path() < las[j].Path()
+	})
+
+	htmlDir := ""
+	if c.config != nil {
+		htmlDir = c.config.HTMLDir
+	}
+
+	pages := []page{}
+	for _, la := range las {
+		contents, err := la.Contents()
+		if err != nil {
+			return nil, err
+		}
+		doc, err := html.Parse(strings.NewReader(contents))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", la.Path(), err)
+		}
+
+		rel, err := filepath.Rel(htmlDir, la.Path())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative path for %q: %w", la.Path(), err)
+		}
+		pages = append(pages, page{
+			path:      la.Path(),
+			url:       "/" + filepath.ToSlash(rel),
+			doc:       doc,
+			positions: htmllint.Positions(contents, doc),
+		})
+	}
+	return pages, nil
+}
+
+func lint(pages []page) []result {
+	results := []result{}
+	for _, p := range pages {
+		for _, i := range htmllint.Lint(p.doc, p.positions) {
+			results = append(results, result{
+				File:  p.path,
+				Issue: i,
+			})
+		}
+	}
+	return results
+}
+
+// checkLinks returns a result for every local link that doesn't resolve to
+// a file in the HTML, static or generated image directories
+func (c *client) checkLinks(pages []page) []result {
+	byURL := map[string]page{}
+	lps := []htmllinks.Page{}
+	for _, p := range pages {
+		byURL[p.url] = p
+		lps = append(lps, htmllinks.Page{URL: p.url, Doc: p.doc})
+	}
+
+	generatedPrefix := c.generatedURLPrefix()
+	results := []result{}
+	for _, b := range htmllinks.Check(lps, c.config.BaseURL, c.exists) {
+		p := byURL[b.Page]
+		pos := p.positions[b.Node]
+		i := htmllint.Issue{
+			Rule:    "broken-link",
+			Level:   htmllint.LevelError,
+			Message: fmt.Sprintf("%q doesn't resolve to a file", b.URL),
+			Line:    pos.Line,
+			Column:  pos.Column,
+		}
+		switch {
+		case b.Reason == htmllinks.MissingFragment:
+			i.Rule = "broken-fragment"
+			i.Message = fmt.Sprintf("%q points at an id that doesn't exist", b.URL)
+		case generatedPrefix != "" && strings.HasPrefix(b.Path, generatedPrefix):
+			i.Rule = "missing-generated-image"
+			i.Message = fmt.Sprintf("%q is not in the generated images, run genimgs", b.URL)
+		}
+		results = append(results, result{
+			File:  p.path,
+			Issue: i,
+		})
+	}
+	return results
+}
+
+// exists returns true if a URL path is a file in any of the directories
+// files are served from
+func (c *client) exists(urlPath string) bool {
+	dirs := []string{c.config.HTMLDir}
+	if c.config.Assets != nil {
+		dirs = append(dirs, c.config.Assets.StaticDir)
+	}
+	if c.config.GenAssets != nil {
+		dirs = append(dirs, c.config.GenAssets.StaticDir)
+	}
+
+	for _, d := range dirs {
+		if d == "" {
+			continue
+		}
+		fi, err := osStat(filepath.Join(d, filepath.FromSlash(urlPath)))
+		if err == nil && !fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedURLPrefix returns the URL path generated images are served from
+func (c *client) generatedURLPrefix() string {
+	g := c.config.GenAssets
+	if g == nil || g.StaticDir == "" || g.OutputDir == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(g.StaticDir, g.OutputDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return "/" + filepath.ToSlash(rel) + "/"
+}
+
+func (c *client) relPath(p string) string {
+	if c.wd == "" {
+		return filepath.ToSlash(p)
+	}
+	r, err := filepath.Rel(c.wd, p)
+	if err != nil || strings.HasPrefix(r, "..") {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(r)
+}
+
+func write(w io.Writer, f string, results []result) error {
+	switch f {
+	case formatText:
+		return writeText(w, results)
+	case formatJSON:
+		return writeJSON(w, results)
+	case formatSARIF:
+		return writeJSON(w, sarifLog(results))
+	}
+	return fmt.Errorf("%w %q; expected one of %q, %q or %q", errInvalidFormat, f, formatText, formatJSON, formatSARIF)
+}
+
+func writeText(w io.Writer, results []result) error {
+	files := 0
+	for i, r := range results {
+		if i == 0 || results[i-1].File != r.File {
+			files++
+			if _, err := fmt.Fprintf(w, "%v\n", r.File); err != nil {
+				return err
+			}
+		}
+		position := ""
+		if r.Line > 0 {
+			position = fmt.Sprintf("%v:%v ", r.Line, r.Column)
+		}
+		if _, err := fmt.Fprintf(w, "    %v%v: %v (%v)\n", position, r.Level, r.Message, r.Rule); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%v issues found in %v files\n", len(results), files)
+	return err
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+type sarifLogFile struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func sarifLog(results []result) sarifLogFile {
+	rules := []sarifRule{}
+	for _, r := range append(append([]htmllint.Rule{}, htmllint.Rules...), linkRules...) {
+		rules = append(rules, sarifRule{
+			ID:               r.ID,
+			ShortDescription: sarifMessage{Text: r.Description},
+			DefaultConfig:    sarifConfig{Level: r.Level},
+		})
+	}
+
+	sr := []sarifResult{}
+	for _, r := range results {
+		pl := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: r.File},
+		}
+		if r.Line > 0 {
+			pl.Region = &sarifRegion{StartLine: r.Line, StartColumn: r.Column}
+		}
+		sr = append(sr, sarifResult{
+			RuleID:    r.Rule,
+			Level:     r.Level,
+			Message:   sarifMessage{Text: r.Message},
+			Locations: []sarifLocation{{PhysicalLocation: pl}},
+		})
+	}
+
+	return sarifLogFile{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           toolName,
+						InformationURI: toolURI,
+						Rules:          rules,
+					},
+				},
+				Results: sr,
+			},
+		},
+	}
+}
+
+type assetmanagerManager interface {
+	WithType(t assets.Type) []assetmanager.Asset
+}
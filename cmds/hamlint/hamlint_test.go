@@ -0,0 +1,229 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmllint"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_run(t *testing.T) {
+	tests := []struct {
+		description string
+		files       map[string]string
+		links       bool
+		format      string
+		want        string
+		wantError   error
+	}{
+		{
+			description: "return nothing for pages without issues",
+			files: map[string]string{
+				"index.html": `<html lang="en"><body><img src="/a.png" alt="A" width="1" height="1"></body></html>`,
+			},
+			format: formatText,
+			want:   "0 issues found in 0 files\n",
+		},
+		{
+			description: "return nothing for warnings",
+			files: map[string]string{
+				"index.html": `<html lang="en"><body><img src="/a.png" alt="A"></body></html>`,
+			},
+			format: formatText,
+			want:   "html/index.html\n    1:23 warning: <img src=\"/a.png\"> has no width or height (img-size)\n1 issues found in 1 files\n",
+		},
+		{
+			description: "return error for errors sorted by file",
+			files: map[string]string{
+				"b.html": `<html><body></body></html>`,
+				"a.html": `<html lang="en"><body><iframe src="/a"></iframe></body></html>`,
+			},
+			format:    formatText,
+			want:      "html/a.html\n    1:23 error: <iframe src=\"/a\"> has no title (iframe-title)\nhtml/b.html\n    1:1 error: <html> has no lang attribute (html-lang)\n2 issues found in 2 files\n",
+			wantError: errIssuesFound,
+		},
+		{
+			description: "return error for broken links grouped by file",
+			files: map[string]string{
+				"html/index.html":                 `<html lang="en"><body><a href="/blog/#b">Blog</a><a href="/about/">About</a><a href="/css/main.css"></a><a href="/generated/a.123/100.webp"></a><a href="https://example.com/missing/"></a></body></html>`,
+				"html/blog/index.html":            `<html lang="en"><body><h1 id="a">A</h1><a href="/">Home</a><a href="#a">A</a></body></html>`,
+				"static/css/main.css":             ``,
+				"static/generated/a.456/100.webp": ``,
+			},
+			links:     true,
+			format:    formatText,
+			want:      "html/index.html\n    1:23 error: \"/blog/#b\" points at an id that doesn't exist (broken-fragment)\n    1:50 error: \"/about/\" doesn't resolve to a file (broken-link)\n    1:105 error: \"/generated/a.123/100.webp\" is not in the generated images, run genimgs (missing-generated-image)\n    1:145 error: \"https://example.com/missing/\" doesn't resolve to a file (broken-link)\n4 issues found in 1 files\n",
+			wantError: errIssuesFound,
+		},
+		{
+			description: "return error for invalid format",
+			files: map[string]string{
+				"index.html": `<html lang="en"></html>`,
+			},
+			format:    "xml",
+			wantError: errInvalidFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			htmlDir := filepath.Join(tmpDir, "html")
+			staticDir := filepath.Join(tmpDir, "static")
+			for name, contents := range tt.files {
+				if !strings.Contains(name, "/") {
+					name = filepath.Join("html", name)
+				}
+				writeFile(t, filepath.Join(tmpDir, name), contents)
+			}
+			manager, err := assetmanager.NewManager(htmlDir, "", "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			c := &client{
+				format: tt.format,
+				links:  tt.links,
+				wd:     tmpDir,
+				config: &config.Config{
+					HTMLDir: htmlDir,
+					BaseURL: "https://example.com",
+					Assets: &config.AssetsConfig{
+						StaticDir: staticDir,
+					},
+					GenAssets: &config.GeneratedImagesConfig{
+						StaticDir: staticDir,
+						OutputDir: filepath.Join(staticDir, "generated"),
+					},
+				},
+				manager: manager,
+			}
+			var buf bytes.Buffer
+			err = c.run(&buf)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if diff := cmp.Diff(buf.String(), tt.want); diff != "" {
+				t.Fatalf("Unexpected output; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_write(t *testing.T) {
+	results := []result{
+		{
+			File: "index.html",
+			Issue: htmllint.Issue{
+				Rule:    "img-alt",
+				Level:   htmllint.LevelError,
+				Message: "no alt",
+				Line:    3,
+				Column:  5,
+			},
+		},
+		{
+			File: "about.html",
+			Issue: htmllint.Issue{
+				Rule:    "html-lang",
+				Level:   htmllint.LevelError,
+				Message: "no lang",
+			},
+		},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := write(&buf, formatJSON, results); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var got []map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to parse output: %v", err)
+		}
+		want := []map[string]interface{}{
+			{"file": "index.html", "rule": "img-alt", "level": "error", "message": "no alt", "line": 3.0, "column": 5.0},
+			{"file": "about.html", "rule": "html-lang", "level": "error", "message": "no lang"},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Fatalf("Unexpected output; diff %v", diff)
+		}
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := write(&buf, formatSARIF, results); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var got sarifLogFile
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to parse output: %v", err)
+		}
+		if got.Version != sarifVersion || len(got.Runs) != 1 {
+			t.Fatalf("Unexpected sarif log: %+v", got)
+		}
+		if len(got.Runs[0].Tool.Driver.Rules) != len(htmllint.Rules)+len(linkRules) {
+			t.Fatalf("Unexpected rules; got %v, want %v", len(got.Runs[0].Tool.Driver.Rules), len(htmllint.Rules)+len(linkRules))
+		}
+		want := []sarifResult{
+			{
+				RuleID:  "img-alt",
+				Level:   "error",
+				Message: sarifMessage{Text: "no alt"},
+				Locations: []sarifLocation{
+					{PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: "index.html"},
+						Region:           &sarifRegion{StartLine: 3, StartColumn: 5},
+					}},
+				},
+			},
+			{
+				RuleID:  "html-lang",
+				Level:   "error",
+				Message: sarifMessage{Text: "no lang"},
+				Locations: []sarifLocation{
+					{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "about.html"}}},
+				},
+			},
+		}
+		if diff := cmp.Diff(got.Runs[0].Results, want); diff != "" {
+			t.Fatalf("Unexpected results; diff %v", diff)
+		}
+	})
+}
+
+func writeFile(t *testing.T, p, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
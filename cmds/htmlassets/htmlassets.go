@@ -25,10 +25,13 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -36,22 +39,41 @@ import (
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/asyncsrc"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/autoinline"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/esmodules"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/fontpreload"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/iconlinks"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/iframedefaultsize"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/imgsize"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/imgtopicture"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/injectassets"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/lazyload"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/lcpimage"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/localvideo"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/opengraphimg"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/ratiowrapper"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/resourcehints"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/revisionurls"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/stripassets"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations/svginline"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/vimeoclean"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations/youtubeclean"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors/assetmanifest"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors/precache"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors/sitemap"
 	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors/fontassets"
 	"github.com/gauntface/go-html-asset-manager/v5/preprocessors/hamassets"
 	"github.com/gauntface/go-html-asset-manager/v5/preprocessors/jsonassets"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors/minifyassets"
 	"github.com/gauntface/go-html-asset-manager/v5/preprocessors/revisionassets"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors/svgoptimize"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/budget"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlencoding"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlminify"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/remoteimgs"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/vimeoapi"
 	"github.com/mitchellh/go-homedir"
 	"github.com/schollz/progressbar/v3"
@@ -64,12 +86,14 @@ var (
 	vimeoToken = flag.String("vimeo", "", "Personal access token for Vimeo API")
 	debug      = flag.String("debug", "", "Provide a HTML file name to log debug info as required")
 
-	errRunFailed  = errors.New("failed to run successfully")
-	errManipulate = errors.New("failed to manipulate HTML")
+	errRunFailed      = errors.New("failed to run successfully")
+	errManipulate     = errors.New("failed to manipulate HTML")
+	errBudgetExceeded = errors.New("budgets exceeded")
 
 	configGet              = config.Get
 	homedirExpand          = homedir.Expand
 	assetmanagerNewManager = assetmanager.NewManager
+	osStat                 = os.Stat
 )
 
 func main() {
@@ -87,14 +111,28 @@ func main() {
 type client struct {
 	htmlParse       func(r io.Reader) (*html.Node, error)
 	htmlRender      func(w io.Writer, n *html.Node) error
+	htmlMinify      func(w io.Writer, n *html.Node) error
 	ioutilWriteFile func(filename string, data []byte, perm os.FileMode) error
 
-	config        *config.Config
-	manager       assetmanagerManager
-	vimeo         *vimeoapi.Client
-	preprocessors []preprocessors.Preprocessor
-	manipulators  []manipulations.Manipulator
-	s3            *s3.Client
+	config         *config.Config
+	manager        assetmanagerManager
+	vimeo          *vimeoapi.Client
+	remoteImages   *remoteimgs.Client
+	preprocessors  []preprocessors.Preprocessor
+	manipulators   []manipulations.Manipulator
+	postprocessors []postprocessors.Postprocessor
+	s3             *s3.Client
+
+	// htmlModTimes holds the modification times of the HTML files from
+	// before they are rewritten
+	htmlModTimes map[string]time.Time
+
+	// htmlBytesSaved is updated atomically as HTML files are minified
+	// concurrently
+	htmlBytesSaved int64
+
+	budgetMu         sync.Mutex
+	budgetViolations []budget.Violation
 }
 
 func newClient() (*client, error) {
@@ -131,6 +169,14 @@ func newClient() (*client, error) {
 		vimeo = vimeoapi.New(*vimeoToken)
 	}
 
+	var remoteImages *remoteimgs.Client
+	if c.RemoteImages != nil {
+		remoteImages, err = remoteimgs.New(c.RemoteImages.CacheFile, c.RemoteImages.Offline)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remote image client: %w", err)
+		}
+	}
+
 	cfg, err := awsconfig.LoadDefaultConfig(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load AWS SDK config, %v", err)
@@ -138,39 +184,83 @@ func newClient() (*client, error) {
 
 	s3Client := s3.NewFromConfig(cfg)
 
+	var htmlMinify func(w io.Writer, n *html.Node) error
+	if c.Minify != nil && c.Minify.HTML {
+		htmlMinify = htmlminify.Render
+	}
+
 	return &client{
 		htmlRender:      html.Render,
+		htmlMinify:      htmlMinify,
 		htmlParse:       html.Parse,
 		ioutilWriteFile: ioutil.WriteFile,
 
-		config:  c,
-		manager: manager,
-		vimeo:   vimeo,
-		s3:      s3Client,
+		config:       c,
+		manager:      manager,
+		vimeo:        vimeo,
+		remoteImages: remoteImages,
+		s3:           s3Client,
 		preprocessors: []preprocessors.Preprocessor{
 			hamassets.Preprocessor,
 			jsonassets.Preprocessor,
+			svgoptimize.Preprocessor,
+			fontassets.Preprocessor,
+			minifyassets.Preprocessor,
 			revisionassets.Preprocessor,
 		},
 		manipulators: []manipulations.Manipulator{
 			opengraphimg.Manipulator,
+			iconlinks.Manipulator,
 			youtubeclean.Manipulator,
 			vimeoclean.Manipulator,
 			iframedefaultsize.Manipulator,
+			localvideo.Manipulator,
 			imgsize.Manipulator,
+			svginline.Manipulator,
 			imgtopicture.Manipulator,
 			ratiowrapper.Manipulator,
+			lcpimage.Manipulator,
 			lazyload.Manipulator,
 			asyncsrc.Manipulator,
 			stripassets.Manipulator,
+			fontpreload.Manipulator,
 			injectassets.Manipulator,
+			autoinline.Manipulator,
+			esmodules.Manipulator,
+			resourcehints.Manipulator,
+			revisionurls.Manipulator,
+		},
+		postprocessors: []postprocessors.Postprocessor{
+			assetmanifest.Postprocessor,
+			precache.Postprocessor,
+			sitemap.Postprocessor,
 		},
 	}, nil
 }
 
 func (c *client) run() error {
+	err := c.runSteps()
+
+	// Persist newly fetched remote image sizes even if a step failed, so
+	// they don't have to be fetched again on the next run
+	if c.remoteImages != nil {
+		if saveErr := c.remoteImages.Save(); saveErr != nil {
+			if err != nil {
+				fmt.Printf("🚨 Failed to save remote image sizes: %v\n", saveErr)
+				return err
+			}
+			return saveErr
+		}
+	}
+	return err
+}
+
+func (c *client) runSteps() error {
 	prettyPrintAssets(c.manager)
 
+	// Record when pages last changed before they are rewritten
+	c.htmlModTimes = htmlModTimes(c.manager)
+
 	// Step 1: Run preprocessprs
 	errs := c.preprocesses(c.manager, c.preprocessors)
 	if len(errs) > 0 {
@@ -182,6 +272,22 @@ func (c *client) run() error {
 	if len(errs) > 0 {
 		return logReturn(errRunFailed, errs)
 	}
+	if c.htmlMinify != nil {
+		fmt.Printf("🗜  HTML minification saved %v bytes\n", atomic.LoadInt64(&c.htmlBytesSaved))
+	}
+
+	// Step 3: Run postprocessors now every file is in its final state
+	errs = c.postprocesses(c.manager, c.postprocessors)
+	if len(errs) > 0 {
+		return logReturn(errRunFailed, errs)
+	}
+
+	// Step 4: Fail once everything is written if a page is over budget
+	if len(c.budgetViolations) > 0 {
+		fmt.Printf("🚨 Pages exceeded their budgets\n")
+		fmt.Println(budget.Table(c.budgetViolations))
+		return fmt.Errorf("%w: %v budgets exceeded", errBudgetExceeded, len(c.budgetViolations))
+	}
 
 	return nil
 }
@@ -191,6 +297,7 @@ func (c *client) preprocesses(manager assetmanagerManager, preprocesses []prepro
 
 	runtime := preprocessors.Runtime{
 		Assets: manager,
+		Config: c.config,
 	}
 	for i, p := range preprocesses {
 		err := p(runtime)
@@ -202,6 +309,43 @@ func (c *client) preprocesses(manager assetmanagerManager, preprocesses []prepro
 	return errs
 }
 
+func (c *client) postprocesses(manager assetmanagerManager, postprocesses []postprocessors.Postprocessor) []error {
+	errs := []error{}
+
+	runtime := postprocessors.Runtime{
+		Debug:    *debug != "",
+		Assets:   manager,
+		Config:   c.config,
+		ModTimes: c.htmlModTimes,
+	}
+	for i, p := range postprocesses {
+		err := p(runtime)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("postprocessor %v failed: %w", i, err))
+		}
+	}
+
+	return errs
+}
+
+// htmlModTimes returns the modification times of the local HTML files keyed
+// by path. Files that can't be read are left for the manipulations to report.
+func htmlModTimes(manager assetmanagerManager) map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	for _, a := range manager.WithType(assets.HTML) {
+		if !a.IsLocal() {
+			continue
+		}
+		p := a.(*assetmanager.LocalAsset).Path()
+		info, err := osStat(p)
+		if err != nil {
+			continue
+		}
+		modTimes[p] = info.ModTime()
+	}
+	return modTimes
+}
+
 func (c *client) manipulations(manager assetmanagerManager, manipulators []manipulations.Manipulator) []error {
 	htmlAssets := manager.WithType(assets.HTML)
 
@@ -263,14 +407,23 @@ func (c *client) manipulateHTMLFile(asset assetmanagerLocalAsset, manager assetm
 		return fmt.Errorf("failed to parse file %q: %w", asset, err)
 	}
 
+	pageURL, err := asset.URL()
+	if err != nil {
+		return err
+	}
+
 	debug := *debug != "" && asset.Debug(*debug)
 	r := manipulations.Runtime{
 		Debug:    debug,
 		Assets:   manager,
+		PageURL:  pageURL,
 		Vimeo:    c.vimeo,
 		S3:       c.s3,
 		HasVimeo: c.vimeo != nil,
 		Config:   c.config,
+
+		HasRemoteImages: c.remoteImages != nil,
+		RemoteImages:    c.remoteImages,
 	}
 	for _, m := range manips {
 		if err := m(r, doc); err != nil {
@@ -278,27 +431,77 @@ func (c *client) manipulateHTMLFile(asset assetmanagerLocalAsset, manager assetm
 		}
 	}
 
-	err = c.writeChanges(asset.Path(), doc)
+	size, err := c.writeChanges(asset.Path(), doc)
 	if err != nil {
 		return fmt.Errorf("failed to write changes: %w", err)
 	}
 
+	return c.checkBudgets(asset.Path(), doc, size)
+}
+
+// checkBudgets records the budgets a manipulated page exceeds
+func (c *client) checkBudgets(htmlFile string, doc *html.Node, size int64) error {
+	if c.config == nil || len(c.config.Budgets) == 0 {
+		return nil
+	}
+
+	page, err := filepath.Rel(c.config.HTMLDir, htmlFile)
+	if err != nil {
+		return fmt.Errorf("failed to get relative path for %q: %w", htmlFile, err)
+	}
+	page = filepath.ToSlash(page)
+
+	b, err := budget.ForPage(c.config.Budgets, page)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return nil
+	}
+
+	staticDirs := []string{}
+	if c.config.Assets != nil {
+		staticDirs = append(staticDirs, c.config.Assets.StaticDir)
+	}
+	if c.config.GenAssets != nil {
+		staticDirs = append(staticDirs, c.config.GenAssets.StaticDir)
+	}
+	violations := budget.Check(page, budget.Measure(doc, size, staticDirs...), b)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	c.budgetMu.Lock()
+	defer c.budgetMu.Unlock()
+	c.budgetViolations = append(c.budgetViolations, violations...)
 	return nil
 }
 
-func (c *client) writeChanges(htmlFile string, doc *html.Node) error {
+// writeChanges renders the document to the HTML file and returns the number
+// of bytes written
+func (c *client) writeChanges(htmlFile string, doc *html.Node) (int64, error) {
 	htmlencoding.EncodeNodes(doc)
 	var buf bytes.Buffer
 	err := c.htmlRender(&buf, doc)
 	if err != nil {
-		return fmt.Errorf("failed to render html node to string: %w", err)
+		return 0, fmt.Errorf("failed to render html node to string: %w", err)
 	}
 
-	err = c.ioutilWriteFile(htmlFile, buf.Bytes(), 0644)
+	b := buf.Bytes()
+	if c.htmlMinify != nil {
+		var minified bytes.Buffer
+		if err := c.htmlMinify(&minified, doc); err != nil {
+			return 0, fmt.Errorf("failed to minify html: %w", err)
+		}
+		atomic.AddInt64(&c.htmlBytesSaved, int64(buf.Len()-minified.Len()))
+		b = minified.Bytes()
+	}
+
+	err = c.ioutilWriteFile(htmlFile, b, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to write changes to %q: %w", htmlFile, err)
+		return 0, fmt.Errorf("failed to write changes to %q: %w", htmlFile, err)
 	}
-	return nil
+	return int64(len(b)), nil
 }
 
 func prettyPrintAssets(assets assetmanagerManager) {
@@ -331,4 +534,5 @@ type assetmanagerLocalAsset interface {
 	Contents() (string, error)
 	Debug(string) bool
 	Path() string
+	URL() (string, error)
 }
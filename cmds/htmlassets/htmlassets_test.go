@@ -17,22 +17,31 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"image"
+	"image/png"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gauntface/go-html-asset-manager/v5/assets"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetstubs"
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
 	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/budget"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/remoteimgs"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/mitchellh/go-homedir"
@@ -51,6 +60,7 @@ func TestMain(m *testing.M) {
 	origHomeDirExpand := homedirExpand
 	origConfigGet := configGet
 	origVimeo := vimeoToken
+	origOSStat := osStat
 
 	reset = func() {
 		debug = origDebug
@@ -59,6 +69,7 @@ func TestMain(m *testing.M) {
 		homedirExpand = origHomeDirExpand
 		configGet = origConfigGet
 		vimeoToken = origVimeo
+		osStat = origOSStat
 	}
 
 	os.Exit(m.Run())
@@ -313,6 +324,46 @@ func Test_preprocesses(t *testing.T) {
 	}
 }
 
+func Test_postprocesses(t *testing.T) {
+	tests := []struct {
+		description    string
+		manager        *assetstubs.Manager
+		postprocessors []postprocessors.Postprocessor
+		wantErrors     []error
+	}{
+		{
+			description: "return errors if postprocessing fails",
+			postprocessors: []postprocessors.Postprocessor{
+				func(runtime postprocessors.Runtime) error {
+					return errInjected
+				},
+			},
+			wantErrors: []error{
+				errInjected,
+			},
+		},
+		{
+			description: "return no errors on success",
+			manager:     &assetstubs.Manager{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &client{}
+			errs := c.postprocesses(tt.manager, tt.postprocessors)
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("Unexpected errors; got %v, want %v", errs, tt.wantErrors)
+			}
+			for i, e := range errs {
+				if !errors.Is(e, tt.wantErrors[i]) {
+					t.Fatalf("Unexpected error at %v; got %v, want %v", i, e, tt.wantErrors[i])
+				}
+			}
+		})
+	}
+}
+
 func Test_manipulations(t *testing.T) {
 	tests := []struct {
 		description   string
@@ -362,7 +413,10 @@ func Test_writeChanges(t *testing.T) {
 		htmlFile    string
 		node        *html.Node
 		render      func(w io.Writer, n *html.Node) error
+		minify      func(w io.Writer, n *html.Node) error
 		writeFile   func(filename string, data []byte, perm os.FileMode) error
+		wantData    string
+		wantSaved   int64
 		wantError   error
 	}{
 		{
@@ -382,38 +436,160 @@ func Test_writeChanges(t *testing.T) {
 			},
 			wantError: errInjected,
 		},
+		{
+			description: "return error if minify fails",
+			render: func(w io.Writer, n *html.Node) error {
+				return nil
+			},
+			minify: func(w io.Writer, n *html.Node) error {
+				return errInjected
+			},
+			wantError: errInjected,
+		},
 		{
 			description: "return nothing on success",
 			render: func(w io.Writer, n *html.Node) error {
+				_, err := w.Write([]byte("<p>Hello</p>"))
+				return err
+			},
+			writeFile: func(filename string, data []byte, perm os.FileMode) error {
 				return nil
 			},
+			wantData: "<p>Hello</p>",
+		},
+		{
+			description: "write minified HTML and record bytes saved",
+			render: func(w io.Writer, n *html.Node) error {
+				_, err := w.Write([]byte("<p>Hello</p>"))
+				return err
+			},
+			minify: func(w io.Writer, n *html.Node) error {
+				_, err := w.Write([]byte("<p>Hello"))
+				return err
+			},
 			writeFile: func(filename string, data []byte, perm os.FileMode) error {
 				return nil
 			},
+			wantData:  "<p>Hello",
+			wantSaved: 4,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.description, func(t *testing.T) {
+			var gotData string
 			c := &client{
-				htmlRender:      tt.render,
-				ioutilWriteFile: tt.writeFile,
+				htmlRender: tt.render,
+				htmlMinify: tt.minify,
+				ioutilWriteFile: func(filename string, data []byte, perm os.FileMode) error {
+					gotData = string(data)
+					return tt.writeFile(filename, data, perm)
+				},
 			}
-			err := c.writeChanges(tt.htmlFile, tt.node)
+			size, err := c.writeChanges(tt.htmlFile, tt.node)
 			if !errors.Is(err, tt.wantError) {
 				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
 			}
+			if err != nil {
+				return
+			}
+			if gotData != tt.wantData {
+				t.Fatalf("Unexpected data written; got %q, want %q", gotData, tt.wantData)
+			}
+			if size != int64(len(tt.wantData)) {
+				t.Fatalf("Unexpected size returned; got %v, want %v", size, len(tt.wantData))
+			}
+			if c.htmlBytesSaved != tt.wantSaved {
+				t.Fatalf("Unexpected bytes saved; got %v, want %v", c.htmlBytesSaved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func Test_checkBudgets(t *testing.T) {
+	tests := []struct {
+		description string
+		config      *config.Config
+		htmlFile    string
+		node        *html.Node
+		size        int64
+		want        []budget.Violation
+		wantError   error
+	}{
+		{
+			description: "do nothing without budgets",
+			config:      &config.Config{HTMLDir: "/html"},
+			htmlFile:    "/html/index.html",
+			node:        MustGetNode(t, `<style>.a{}</style>`),
+			size:        100,
+		},
+		{
+			description: "do nothing if no budget matches the page",
+			config: &config.Config{
+				HTMLDir: "/html",
+				Budgets: []*config.BudgetConfig{
+					{Path: "blog/*", HTMLBytes: 10},
+				},
+			},
+			htmlFile: "/html/index.html",
+			node:     MustGetNode(t, `<p>Hello</p>`),
+			size:     100,
+		},
+		{
+			description: "return error for an invalid path pattern",
+			config: &config.Config{
+				HTMLDir: "/html",
+				Budgets: []*config.BudgetConfig{
+					{Path: "[", HTMLBytes: 10},
+				},
+			},
+			htmlFile:  "/html/index.html",
+			node:      MustGetNode(t, `<p>Hello</p>`),
+			wantError: budget.ErrInvalidPattern,
+		},
+		{
+			description: "record exceeded budgets by relative page path",
+			config: &config.Config{
+				HTMLDir: "/html",
+				Budgets: []*config.BudgetConfig{
+					{HTMLBytes: 10, InlineCSSBytes: 100},
+					{Path: "blog/*", InlineCSSBytes: 2},
+				},
+			},
+			htmlFile: "/html/blog/index.html",
+			node:     MustGetNode(t, `<style>.a{}</style>`),
+			size:     5,
+			want: []budget.Violation{
+				{Page: "blog/index.html", Metric: budget.InlineCSSBytes, Value: 4, Limit: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &client{
+				config: tt.config,
+			}
+			err := c.checkBudgets(tt.htmlFile, tt.node, tt.size)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if diff := cmp.Diff(c.budgetViolations, tt.want); diff != "" {
+				t.Fatalf("Unexpected violations; diff %v", diff)
+			}
 		})
 	}
 }
 
 func Test_run(t *testing.T) {
 	tests := []struct {
-		description   string
-		manager       *assetstubs.Manager
-		preprocessors []preprocessors.Preprocessor
-		manipulations []manipulations.Manipulator
-		wantError     error
+		description    string
+		manager        *assetstubs.Manager
+		preprocessors  []preprocessors.Preprocessor
+		manipulations  []manipulations.Manipulator
+		postprocessors []postprocessors.Postprocessor
+		violations     []budget.Violation
+		wantError      error
 	}{
 		{
 			description: "return error if preprocessors fail",
@@ -442,6 +618,24 @@ func Test_run(t *testing.T) {
 			},
 			wantError: errRunFailed,
 		},
+		{
+			description: "return error if postprocessors fail",
+			manager:     &assetstubs.Manager{},
+			postprocessors: []postprocessors.Postprocessor{
+				func(runtime postprocessors.Runtime) error {
+					return errInjected
+				},
+			},
+			wantError: errRunFailed,
+		},
+		{
+			description: "return error if budgets are exceeded",
+			manager:     &assetstubs.Manager{},
+			violations: []budget.Violation{
+				{Page: "index.html", Metric: budget.HTMLBytes, Value: 20, Limit: 10},
+			},
+			wantError: errBudgetExceeded,
+		},
 		{
 			description: "return nothing on success",
 			manager:     &assetstubs.Manager{},
@@ -451,9 +645,11 @@ func Test_run(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.description, func(t *testing.T) {
 			c := &client{
-				manager:       tt.manager,
-				preprocessors: tt.preprocessors,
-				manipulators:  tt.manipulations,
+				manager:          tt.manager,
+				preprocessors:    tt.preprocessors,
+				manipulators:     tt.manipulations,
+				postprocessors:   tt.postprocessors,
+				budgetViolations: tt.violations,
 			}
 			err := c.run()
 			if !errors.Is(err, tt.wantError) {
@@ -463,6 +659,99 @@ func Test_run(t *testing.T) {
 	}
 }
 
+func Test_run_savesRemoteImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20))); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "sizes.json")
+	remoteImages, err := remoteimgs.New(cacheFile, false)
+	if err != nil {
+		t.Fatalf("Failed to create remote images client: %v", err)
+	}
+
+	c := &client{
+		manager: &assetstubs.Manager{},
+		postprocessors: []postprocessors.Postprocessor{
+			func(runtime postprocessors.Runtime) error {
+				if _, _, err := remoteImages.Size(server.URL + "/photo.png"); err != nil {
+					return err
+				}
+				return errInjected
+			},
+		},
+		remoteImages: remoteImages,
+	}
+	if err := c.run(); !errors.Is(err, errRunFailed) {
+		t.Fatalf("Unexpected error; got %v, want %v", err, errRunFailed)
+	}
+
+	// Sizes fetched before the failure should be saved
+	offline, err := remoteimgs.New(cacheFile, true)
+	if err != nil {
+		t.Fatalf("Failed to create offline remote images client: %v", err)
+	}
+	w, h, err := offline.Size(server.URL + "/photo.png")
+	if err != nil {
+		t.Fatalf("Expected remote image size to be saved: %v", err)
+	}
+	if w != 30 || h != 20 {
+		t.Errorf("Unexpected saved size; got %vx%v, want 30x20", w, h)
+	}
+}
+
+func Test_htmlModTimes(t *testing.T) {
+	defer reset()
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	osStat = func(name string) (os.FileInfo, error) {
+		if name != "index.html" {
+			return nil, errInjected
+		}
+		return fileInfo{modTime: mtime}, nil
+	}
+
+	manager := &assetstubs.Manager{
+		WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+			assets.HTML: {
+				assetstubs.MustNewLocalAsset(t, "testdata/noassets/", "index.html"),
+				assetstubs.MustNewLocalAsset(t, "testdata/noassets/", "missing.html"),
+			},
+		},
+	}
+	want := map[string]time.Time{"index.html": mtime}
+	if diff := cmp.Diff(htmlModTimes(manager), want); diff != "" {
+		t.Fatalf("Unexpected modification times; diff %v", diff)
+	}
+
+	// The times are handed to the postprocessors
+	var got map[string]time.Time
+	c := &client{htmlModTimes: want}
+	c.postprocesses(manager, []postprocessors.Postprocessor{
+		func(runtime postprocessors.Runtime) error {
+			got = runtime.ModTimes
+			return nil
+		},
+	})
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Unexpected postprocessor modification times; diff %v", diff)
+	}
+}
+
+type fileInfo struct {
+	os.FileInfo
+	modTime time.Time
+}
+
+func (f fileInfo) ModTime() time.Time {
+	return f.modTime
+}
+
 func Test_integration_noassets(t *testing.T) {
 	defer reset()
 
@@ -0,0 +1,321 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package autoinline
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/minify"
+	"golang.org/x/net/html"
+)
+
+var (
+	ioutilReadFile = ioutil.ReadFile
+
+	// Attributes that only make sense when a file is requested
+	requestAttributes = map[string]bool{
+		"href":           true,
+		"rel":            true,
+		"src":            true,
+		"integrity":      true,
+		"crossorigin":    true,
+		"referrerpolicy": true,
+		"fetchpriority":  true,
+	}
+)
+
+// Manipulator replaces the tags of small local sync CSS and JS files with
+// inline style and script tags and the src of small local images with data
+// URIs. Tags are handled in document order until the page budget is spent.
+// It must run after injectassets so the injected tags are in the page.
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if !shouldRun(runtime.Config) {
+		return nil
+	}
+	conf := runtime.Config.AutoInline
+
+	syncAssets, err := syncAssetsByURL(runtime.Assets)
+	if err != nil {
+		return err
+	}
+
+	b := &budget{
+		unlimited: conf.PageMaxBytes <= 0,
+		remaining: conf.PageMaxBytes,
+	}
+
+	inlined := []string{}
+	for _, n := range elements(doc) {
+		var u string
+		var err error
+		switch n.Data {
+		case "link":
+			u, err = inlineCSS(runtime, n, syncAssets, b)
+		case "script":
+			u, err = inlineJS(runtime, n, syncAssets, b)
+		case "img":
+			u, err = inlineImage(runtime, n, b)
+		}
+		if err != nil {
+			return err
+		}
+		if u != "" {
+			inlined = append(inlined, u)
+		}
+	}
+
+	removePreloads(doc, inlined)
+	return nil
+}
+
+func shouldRun(conf *config.Config) bool {
+	if conf == nil || conf.AutoInline == nil {
+		return false
+	}
+	return conf.AutoInline.AssetMaxBytes > 0 || conf.AutoInline.ImageMaxBytes > 0
+}
+
+type budget struct {
+	unlimited bool
+	remaining int64
+}
+
+// spend returns true if there is room for the bytes in the budget
+func (b *budget) spend(size int) bool {
+	if b.unlimited {
+		return true
+	}
+	if int64(size) > b.remaining {
+		return false
+	}
+	b.remaining -= int64(size)
+	return true
+}
+
+func syncAssetsByURL(manager manipulations.AssetManager) (map[string]assetmanager.Asset, error) {
+	byURL := map[string]assetmanager.Asset{}
+	for _, t := range []assets.Type{assets.SyncCSS, assets.SyncJS} {
+		for _, a := range manager.WithType(t) {
+			if !a.IsLocal() {
+				continue
+			}
+			u, err := a.URL()
+			if err != nil {
+				return nil, err
+			}
+			byURL[u] = a
+		}
+	}
+	return byURL, nil
+}
+
+// elements returns the link, script and img elements in document order
+func elements(doc *html.Node) []*html.Node {
+	nodes := []*html.Node{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "link" || n.Data == "script" || n.Data == "img") {
+			nodes = append(nodes, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return nodes
+}
+
+func inlineCSS(runtime manipulations.Runtime, n *html.Node, syncAssets map[string]assetmanager.Asset, b *budget) (string, error) {
+	attrs := htmlparsing.Attributes(n)
+	if attrs["rel"].Val != "stylesheet" {
+		return "", nil
+	}
+	// Async stylesheets switch media once loaded
+	if _, ok := attrs["onload"]; ok {
+		return "", nil
+	}
+	u := attrs["href"].Val
+	a, ok := syncAssets[u]
+	if !ok || a.Type() != assets.SyncCSS {
+		return "", nil
+	}
+
+	c, err := a.Contents()
+	if err != nil {
+		return "", err
+	}
+	c = minify.StripSourceMapComments(c)
+	if !fits(runtime, u, c, runtime.Config.AutoInline.AssetMaxBytes) || strings.Contains(strings.ToLower(c), "</style") {
+		return "", nil
+	}
+
+	// Relative references must keep pointing at the same files once the
+	// stylesheet is part of the page
+	c = css.ReplaceReferences(c, func(ref string) string {
+		if strings.HasPrefix(ref, "#") {
+			return ref
+		}
+		return css.ResolveURL(u, ref)
+	})
+	if !b.spend(len(c)) {
+		return "", nil
+	}
+
+	style := htmlparsing.InlineCSSTag(c)
+	style.Attr = withoutRequestAttributes(n.Attr)
+	htmlparsing.SwapNodes(n, style)
+	return u, nil
+}
+
+func inlineJS(runtime manipulations.Runtime, n *html.Node, syncAssets map[string]assetmanager.Asset, b *budget) (string, error) {
+	attrs := htmlparsing.Attributes(n)
+	for _, k := range []string{"async", "defer", "nomodule"} {
+		if _, ok := attrs[k]; ok {
+			return "", nil
+		}
+	}
+	if attrs["type"].Val == "module" {
+		return "", nil
+	}
+	u := attrs["src"].Val
+	a, ok := syncAssets[u]
+	if !ok || a.Type() != assets.SyncJS {
+		return "", nil
+	}
+
+	c, err := a.Contents()
+	if err != nil {
+		return "", err
+	}
+	c = minify.StripSourceMapComments(c)
+	// Script contents can't hold anything that would end the element early
+	lower := strings.ToLower(c)
+	if !fits(runtime, u, c, runtime.Config.AutoInline.AssetMaxBytes) || strings.Contains(lower, "</script") || strings.Contains(lower, "<!--") {
+		return "", nil
+	}
+	if !b.spend(len(c)) {
+		return "", nil
+	}
+
+	script := htmlparsing.InlineJSTag(c)
+	script.Attr = withoutRequestAttributes(n.Attr)
+	htmlparsing.SwapNodes(n, script)
+	return u, nil
+}
+
+func inlineImage(runtime manipulations.Runtime, n *html.Node, b *budget) (string, error) {
+	if runtime.Config.AutoInline.ImageMaxBytes <= 0 {
+		return "", nil
+	}
+	if runtime.Config.Assets == nil || runtime.Config.Assets.StaticDir == "" {
+		return "", nil
+	}
+
+	// Responsive images pick their own file so the src may never be used
+	if n.Parent != nil && n.Parent.Data == "picture" {
+		return "", nil
+	}
+	attrs := htmlparsing.Attributes(n)
+	if _, ok := attrs["srcset"]; ok {
+		return "", nil
+	}
+
+	src := attrs["src"].Val
+	if !strings.HasPrefix(src, "/") || strings.HasPrefix(src, "//") {
+		return "", nil
+	}
+	pu, err := url.Parse(src)
+	if err != nil {
+		return "", nil
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(pu.Path)))
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", nil
+	}
+
+	c, err := ioutilReadFile(filepath.Join(runtime.Config.Assets.StaticDir, pu.Path))
+	if err != nil {
+		fmt.Printf("Failed to read image %q\n", src)
+		return "", nil
+	}
+	if !fits(runtime, src, string(c), runtime.Config.AutoInline.ImageMaxBytes) {
+		return "", nil
+	}
+
+	dataURI := fmt.Sprintf("data:%v;base64,%v", strings.Split(mimeType, ";")[0], base64.StdEncoding.EncodeToString(c))
+	if !b.spend(len(dataURI)) {
+		return "", nil
+	}
+
+	for i, a := range n.Attr {
+		if a.Key == "src" {
+			n.Attr[i].Val = dataURI
+		}
+	}
+	return src, nil
+}
+
+func fits(runtime manipulations.Runtime, u, contents string, max int64) bool {
+	if max <= 0 || int64(len(contents)) > max {
+		if runtime.Debug {
+			fmt.Printf("Not inlining %q with size %v bytes\n", u, len(contents))
+		}
+		return false
+	}
+	return true
+}
+
+func withoutRequestAttributes(attrs []html.Attribute) []html.Attribute {
+	kept := []html.Attribute{}
+	for _, a := range attrs {
+		if requestAttributes[a.Key] {
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
+
+// removePreloads removes preloads of files that are now part of the page
+func removePreloads(doc *html.Node, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	inlined := map[string]bool{}
+	for _, u := range urls {
+		inlined[u] = true
+	}
+	for _, l := range htmlparsing.FindNodesByTag("link", doc) {
+		attrs := htmlparsing.Attributes(l)
+		if attrs["rel"].Val == "preload" && inlined[attrs["href"].Val] {
+			l.Parent.RemoveChild(l)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package autoinline
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetstubs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+var reset func()
+
+func TestMain(m *testing.M) {
+	origReadFile := ioutilReadFile
+
+	reset = func() {
+		ioutilReadFile = origReadFile
+	}
+
+	os.Exit(m.Run())
+}
+
+func Test_Manipulator(t *testing.T) {
+	conf := func(assetMax, imageMax, pageMax int64) *config.Config {
+		return &config.Config{
+			Assets: &config.AssetsConfig{
+				StaticDir: "/static",
+			},
+			AutoInline: &config.AutoInlineConfig{
+				AssetMaxBytes: assetMax,
+				ImageMaxBytes: imageMax,
+				PageMaxBytes:  pageMax,
+			},
+		}
+	}
+
+	manager := &assetstubs.Manager{
+		WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+			assets.SyncCSS: {
+				&assetstubs.Asset{
+					TypeReturn:     assets.SyncCSS,
+					URLReturn:      "/css/small.css",
+					ContentsReturn: ".a{background:url(img/a.png)}\n/*# sourceMappingURL=small.css.map */",
+					IsLocalReturn:  true,
+				},
+				&assetstubs.Asset{
+					TypeReturn:     assets.SyncCSS,
+					URLReturn:      "/css/large.css",
+					ContentsReturn: strings.Repeat("a", 100),
+					IsLocalReturn:  true,
+				},
+				&assetstubs.Asset{
+					TypeReturn:     assets.SyncCSS,
+					URLReturn:      "/css/end.css",
+					ContentsReturn: `.a{}</style>`,
+					IsLocalReturn:  true,
+				},
+				&assetstubs.Asset{
+					TypeReturn:     assets.SyncCSS,
+					URLReturn:      "https://example.com/remote.css",
+					ContentsReturn: `.a{}`,
+				},
+			},
+			assets.SyncJS: {
+				&assetstubs.Asset{
+					TypeReturn:     assets.SyncJS,
+					URLReturn:      "/js/small.js",
+					ContentsReturn: "a();\n//# sourceMappingURL=small.js.map",
+					IsLocalReturn:  true,
+				},
+				&assetstubs.Asset{
+					TypeReturn:     assets.SyncJS,
+					URLReturn:      "/js/end.js",
+					ContentsReturn: `a("</script>");`,
+					IsLocalReturn:  true,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		readFile    func(filename string) ([]byte, error)
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without auto-inline config",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: &config.Config{},
+			},
+			doc:  MustGetNode(t, `<head><link href="/css/small.css" rel="stylesheet"/></head>`),
+			want: `<html><head><link href="/css/small.css" rel="stylesheet"/></head><body></body></html>`,
+		},
+		{
+			description: "inline small sync css in place, resolve relative references and strip source map comments",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: conf(50, 0, 0),
+			},
+			doc:  MustGetNode(t, `<head><link href="/css/large.css" rel="stylesheet"/><link href="/css/small.css" rel="stylesheet" media="print"/></head>`),
+			want: `<html><head><link href="/css/large.css" rel="stylesheet"/><style media="print">.a{background:url(/css/img/a.png)}</style></head><body></body></html>`,
+		},
+		{
+			description: "do nothing for async, remote or unsafe css",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: conf(50, 0, 0),
+			},
+			doc:  MustGetNode(t, `<head><link href="/css/small.css" rel="stylesheet" media="print" onload="this.media='all'"/><link href="https://example.com/remote.css" rel="stylesheet"/><link href="/css/end.css" rel="stylesheet"/></head>`),
+			want: `<html><head><link href="/css/small.css" rel="stylesheet" media="print" onload="this.media=&#39;all&#39;"/><link href="https://example.com/remote.css" rel="stylesheet"/><link href="/css/end.css" rel="stylesheet"/></head><body></body></html>`,
+		},
+		{
+			description: "inline small sync js without source map comments and remove its preload",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: conf(50, 0, 0),
+			},
+			doc:  MustGetNode(t, `<head><link rel="preload" as="script" href="/js/small.js"/></head><body><script src="/js/small.js" id="a"></script><script src="/js/small.js" defer=""></script><script src="/js/end.js"></script></body>`),
+			want: `<html><head></head><body><script id="a">a();</script><script src="/js/small.js" defer=""></script><script src="/js/end.js"></script></body></html>`,
+		},
+		{
+			description: "return error if contents fail",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{
+					WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+						assets.SyncJS: {
+							&assetstubs.Asset{
+								TypeReturn:    assets.SyncJS,
+								URLReturn:     "/js/small.js",
+								ContentsError: errInjected,
+								IsLocalReturn: true,
+							},
+						},
+					},
+				},
+				Config: conf(50, 0, 0),
+			},
+			doc:       MustGetNode(t, `<body><script src="/js/small.js"></script></body>`),
+			want:      `<html><head></head><body><script src="/js/small.js"></script></body></html>`,
+			wantError: errInjected,
+		},
+		{
+			description: "inline small images as data uris",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: conf(0, 10, 0),
+			},
+			doc: MustGetNode(t, `<img src="/img/a.png?v=1" alt="A"/><img src="/img/b.png" srcset="/img/b.png 1x"/><picture><img src="/img/c.png"/></picture><img src="https://example.com/d.png"/><img src="/doc.pdf"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				if filename != "/static/img/a.png" {
+					t.Errorf("unexpected read of %v", filename)
+				}
+				return []byte("png"), nil
+			},
+			want: `<html><head></head><body><img src="data:image/png;base64,cG5n" alt="A"/><img src="/img/b.png" srcset="/img/b.png 1x"/><picture><img src="/img/c.png"/></picture><img src="https://example.com/d.png"/><img src="/doc.pdf"/></body></html>`,
+		},
+		{
+			description: "do nothing for images above the threshold or that can't be read",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: conf(0, 2, 0),
+			},
+			doc: MustGetNode(t, `<img src="/img/a.png"/><img src="/img/missing.png"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				if filename == "/static/img/missing.png" {
+					return nil, errInjected
+				}
+				return []byte("png"), nil
+			},
+			want: `<html><head></head><body><img src="/img/a.png"/><img src="/img/missing.png"/></body></html>`,
+		},
+		{
+			description: "stop inlining once the page budget is spent",
+			runtime: manipulations.Runtime{
+				Assets: manager,
+				Config: conf(50, 10, 30),
+			},
+			doc: MustGetNode(t, `<body><script src="/js/small.js"></script><img src="/img/a.png"/><script src="/js/small.js"></script></body>`),
+			readFile: func(filename string) ([]byte, error) {
+				return []byte("png"), nil
+			},
+			want: `<html><head></head><body><script>a();</script><img src="data:image/png;base64,cG5n"/><script src="/js/small.js"></script></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			defer reset()
+			if tt.readFile != nil {
+				ioutilReadFile = tt.readFile
+			}
+
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
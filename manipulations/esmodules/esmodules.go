@@ -0,0 +1,269 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package esmodules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/jsmodules"
+	"golang.org/x/net/html"
+)
+
+var (
+	errImportMapParse = errors.New("unable to parse import map")
+)
+
+// Manipulator adds modulepreload links for the static imports of the module
+// scripts on a page and an import map for revisioned modules. It must run
+// after injectassets so injected module scripts are found.
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	headNode := htmlparsing.FindNodeByTag("head", doc)
+	if headNode == nil {
+		return nil
+	}
+
+	scripts := moduleScripts(doc)
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	modules, err := localModules(runtime.Assets)
+	if err != nil {
+		return err
+	}
+	if len(modules) == 0 {
+		return nil
+	}
+
+	// Only modules the page can load need to be in its import map
+	reachable, err := reachableModules(scripts, modules, jsmodules.Imports)
+	if err != nil {
+		return err
+	}
+	if err := addImportMap(headNode, reachable); err != nil {
+		return err
+	}
+
+	preloads, err := reachableModules(scripts, modules, jsmodules.StaticImports)
+	if err != nil {
+		return err
+	}
+
+	existing := existingModulePreloads(headNode)
+	for _, s := range scripts {
+		existing[s] = true
+	}
+	for _, m := range preloads {
+		u := m.url
+		if existing[u] {
+			continue
+		}
+		if runtime.Debug {
+			fmt.Printf("Adding modulepreload for %q\n", u)
+		}
+		headNode.AppendChild(htmlparsing.ModulePreloadTag(u))
+		existing[u] = true
+	}
+
+	return nil
+}
+
+// reachableModules returns the scripts' modules followed by every module
+// they import with the given func, directly or indirectly.
+func reachableModules(scripts []string, modules []*module, importsFn func(contents string) []string) ([]*module, error) {
+	byURL := map[string]*module{}
+	byOriginalURL := map[string]*module{}
+	for _, m := range modules {
+		byURL[m.url] = m
+		byOriginalURL[m.originalURL] = m
+	}
+
+	visited := map[string]bool{}
+	reachable := []*module{}
+	for _, s := range scripts {
+		m, ok := byURL[s]
+		if !ok || visited[m.url] {
+			continue
+		}
+		visited[m.url] = true
+		reachable = append(reachable, m)
+	}
+
+	for i := 0; i < len(reachable); i++ {
+		m := reachable[i]
+		c, err := m.asset.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range importsFn(c) {
+			// Imports are rewritten to revisioned files by revisionassets,
+			// except in reference cycles where the import map is relied on
+			u, ok := jsmodules.Resolve(m.url, s)
+			if !ok {
+				continue
+			}
+			dep, ok := byURL[u]
+			if !ok {
+				dep, ok = byOriginalURL[u]
+			}
+			if !ok || visited[dep.url] {
+				continue
+			}
+			visited[dep.url] = true
+			reachable = append(reachable, dep)
+		}
+	}
+	return reachable, nil
+}
+
+func addImportMap(headNode *html.Node, modules []*module) error {
+	imports := map[string]string{}
+	for _, m := range modules {
+		if m.url != m.originalURL {
+			imports[m.originalURL] = m.url
+		}
+	}
+	if len(imports) == 0 {
+		return nil
+	}
+
+	for _, s := range htmlparsing.FindNodesByTag("script", headNode) {
+		if htmlparsing.Attributes(s)["type"].Val != "importmap" {
+			continue
+		}
+		return mergeImportMap(s, imports)
+	}
+
+	b, err := json.Marshal(importMap{Imports: imports})
+	if err != nil {
+		return err
+	}
+
+	// The import map has to be parsed before any module is fetched
+	headNode.InsertBefore(htmlparsing.ImportMapTag(string(b)), htmlparsing.FirstResourceNode(headNode))
+	return nil
+}
+
+func mergeImportMap(n *html.Node, imports map[string]string) error {
+	contents := ""
+	if n.FirstChild != nil {
+		contents = n.FirstChild.Data
+	}
+
+	existing := map[string]interface{}{}
+	if strings.TrimSpace(contents) != "" {
+		if err := json.Unmarshal([]byte(contents), &existing); err != nil {
+			return fmt.Errorf("%w; %v", errImportMapParse, err)
+		}
+	}
+
+	existingImports, ok := existing["imports"].(map[string]interface{})
+	if !ok {
+		existingImports = map[string]interface{}{}
+	}
+	for k, v := range imports {
+		if _, ok := existingImports[k]; !ok {
+			existingImports[k] = v
+		}
+	}
+	existing["imports"] = existingImports
+
+	b, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+
+	if n.FirstChild == nil {
+		n.AppendChild(&html.Node{Type: html.TextNode})
+	}
+	n.FirstChild.Data = string(b)
+	return nil
+}
+
+func moduleScripts(doc *html.Node) []string {
+	srcs := []string{}
+	for _, s := range htmlparsing.FindNodesByTag("script", doc) {
+		attrs := htmlparsing.Attributes(s)
+		if attrs["type"].Val != "module" || attrs["src"].Val == "" {
+			continue
+		}
+		srcs = append(srcs, attrs["src"].Val)
+	}
+	return srcs
+}
+
+func localModules(manager manipulations.AssetManager) ([]*module, error) {
+	modules := []*module{}
+	for _, a := range manager.WithType(assets.ModuleJS) {
+		if !a.IsLocal() {
+			continue
+		}
+		oa, ok := a.(originalURLAsset)
+		if !ok {
+			continue
+		}
+
+		u, err := a.URL()
+		if err != nil {
+			return nil, err
+		}
+		ou, err := oa.OriginalURL()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, &module{
+			asset:       a,
+			url:         u,
+			originalURL: ou,
+		})
+	}
+	return modules, nil
+}
+
+func existingModulePreloads(headNode *html.Node) map[string]bool {
+	existing := map[string]bool{}
+	for _, l := range htmlparsing.FindNodesByTag("link", headNode) {
+		attrs := htmlparsing.Attributes(l)
+		if attrs["rel"].Val != "modulepreload" {
+			continue
+		}
+		existing[attrs["href"].Val] = true
+	}
+	return existing
+}
+
+type module struct {
+	asset       assetmanager.Asset
+	url         string
+	originalURL string
+}
+
+type importMap struct {
+	Imports map[string]string `json:"imports"`
+}
+
+type originalURLAsset interface {
+	OriginalURL() (string, error)
+}
@@ -0,0 +1,219 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package esmodules
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetstubs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+func Test_Manipulator(t *testing.T) {
+	revisioned := &assetstubs.Manager{
+		WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+			assets.ModuleJS: {
+				&assetstubs.Asset{
+					URLReturn:         "/js/main.1234.js",
+					OriginalURLReturn: "/js/main.js",
+					ContentsReturn:    `import {a} from "./a.js"; import "lodash"; const b = import("./b.js");`,
+					IsLocalReturn:     true,
+				},
+				&assetstubs.Asset{
+					URLReturn:         "/js/a.5678.js",
+					OriginalURLReturn: "/js/a.js",
+					ContentsReturn:    `export * from "../lib/c.js"; import "./main.js";`,
+					IsLocalReturn:     true,
+				},
+				&assetstubs.Asset{
+					URLReturn:         "/js/b.9012.js",
+					OriginalURLReturn: "/js/b.js",
+					IsLocalReturn:     true,
+				},
+				&assetstubs.Asset{
+					URLReturn:         "/lib/c.js",
+					OriginalURLReturn: "/lib/c.js",
+					IsLocalReturn:     true,
+				},
+				// Not imported by the page's scripts
+				&assetstubs.Asset{
+					URLReturn:         "/js/other.3456.js",
+					OriginalURLReturn: "/js/other.js",
+					IsLocalReturn:     true,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without module scripts",
+			runtime: manipulations.Runtime{
+				Assets: revisioned,
+			},
+			doc:  MustGetNode(t, `<body><script src="/js/main.1234.js"></script></body>`),
+			want: `<html><head></head><body><script src="/js/main.1234.js"></script></body></html>`,
+		},
+		{
+			description: "return error if module contents fail",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{
+					WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+						assets.ModuleJS: {
+							&assetstubs.Asset{
+								URLReturn:         "/js/main.js",
+								OriginalURLReturn: "/js/main.js",
+								ContentsError:     errInjected,
+								IsLocalReturn:     true,
+							},
+						},
+					},
+				},
+			},
+			doc:       MustGetNode(t, `<body><script type="module" src="/js/main.js"></script></body>`),
+			want:      `<html><head></head><body><script type="module" src="/js/main.js"></script></body></html>`,
+			wantError: errInjected,
+		},
+		{
+			description: "add modulepreload without import map for unrevisioned modules",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{
+					WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+						assets.ModuleJS: {
+							&assetstubs.Asset{
+								URLReturn:         "/js/main.js",
+								OriginalURLReturn: "/js/main.js",
+								ContentsReturn:    `import "./a.js"`,
+								IsLocalReturn:     true,
+							},
+							&assetstubs.Asset{
+								URLReturn:         "/js/a.js",
+								OriginalURLReturn: "/js/a.js",
+								IsLocalReturn:     true,
+							},
+						},
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<body><script type="module" src="/js/main.js"></script></body>`),
+			want: `<html><head><link rel="modulepreload" href="/js/a.js"/></head><body><script type="module" src="/js/main.js"></script></body></html>`,
+		},
+		{
+			description: "add modulepreload for imports rewritten to revisioned files",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{
+					WithTypeReturn: map[assets.Type][]assetmanager.Asset{
+						assets.ModuleJS: {
+							&assetstubs.Asset{
+								URLReturn:         "/js/main.1234.js",
+								OriginalURLReturn: "/js/main.js",
+								ContentsReturn:    `import "./a.5678.js"`,
+								IsLocalReturn:     true,
+							},
+							&assetstubs.Asset{
+								URLReturn:         "/js/a.5678.js",
+								OriginalURLReturn: "/js/a.js",
+								IsLocalReturn:     true,
+							},
+						},
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<body><script type="module" src="/js/main.1234.js"></script></body>`),
+			want: `<html><head><script type="importmap">{"imports":{"/js/a.js":"/js/a.5678.js","/js/main.js":"/js/main.1234.js"}}</script><link rel="modulepreload" href="/js/a.5678.js"/></head><body><script type="module" src="/js/main.1234.js"></script></body></html>`,
+		},
+		{
+			description: "add import map and modulepreload for static imports",
+			runtime: manipulations.Runtime{
+				Assets: revisioned,
+			},
+			doc:  MustGetNode(t, `<head><title>Example</title><link rel="stylesheet" href="/main.css"/></head><body><script type="module" src="/js/main.1234.js"></script></body>`),
+			want: `<html><head><title>Example</title><script type="importmap">{"imports":{"/js/a.js":"/js/a.5678.js","/js/b.js":"/js/b.9012.js","/js/main.js":"/js/main.1234.js"}}</script><link rel="stylesheet" href="/main.css"/><link rel="modulepreload" href="/js/a.5678.js"/><link rel="modulepreload" href="/lib/c.js"/></head><body><script type="module" src="/js/main.1234.js"></script></body></html>`,
+		},
+		{
+			description: "merge with an existing import map and skip existing preloads",
+			runtime: manipulations.Runtime{
+				Assets: revisioned,
+			},
+			doc:  MustGetNode(t, `<head><script type="importmap">{"imports":{"lodash":"/lib/lodash.js","/js/b.js":"/js/b.js"},"scopes":{}}</script><link rel="modulepreload" href="/lib/c.js"/></head><body><script type="module" src="/js/main.1234.js"></script></body>`),
+			want: `<html><head><script type="importmap">{"imports":{"/js/a.js":"/js/a.5678.js","/js/b.js":"/js/b.js","/js/main.js":"/js/main.1234.js","lodash":"/lib/lodash.js"},"scopes":{}}</script><link rel="modulepreload" href="/lib/c.js"/><link rel="modulepreload" href="/js/a.5678.js"/></head><body><script type="module" src="/js/main.1234.js"></script></body></html>`,
+		},
+		{
+			description: "return error for an invalid import map",
+			runtime: manipulations.Runtime{
+				Assets: revisioned,
+			},
+			doc:       MustGetNode(t, `<head><script type="importmap">{</script></head><body><script type="module" src="/js/main.1234.js"></script></body>`),
+			want:      `<html><head><script type="importmap">{</script></head><body><script type="module" src="/js/main.1234.js"></script></body></html>`,
+			wantError: errImportMapParse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
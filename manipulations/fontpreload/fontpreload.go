@@ -0,0 +1,155 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontpreload
+
+import (
+	"fmt"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/fontusage"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultMaxPreload = 2
+)
+
+var (
+	// Only fonts from render blocking CSS are needed above the fold
+	aboveFoldCSS = []assets.Type{
+		assets.InlineCSS,
+		assets.SyncCSS,
+	}
+
+	// Any of the page's CSS can set the font of its elements
+	pageCSS = []assets.Type{
+		assets.InlineCSS,
+		assets.SyncCSS,
+		assets.AsyncCSS,
+	}
+)
+
+// Manipulator preloads the local fonts declared by a page's inline and sync
+// CSS that the text of the page is rendered with. It must run before
+// injectassets so the page keys match the injected assets.
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if runtime.Config == nil || runtime.Config.Fonts == nil {
+		return nil
+	}
+
+	headNode := htmlparsing.FindNodeByTag("head", doc)
+	if headNode == nil {
+		return nil
+	}
+
+	keys := htmlparsing.GetKeys(doc)
+	fonts, err := fontusage.Fonts(runtime.Assets, keys.Sorted(), aboveFoldCSS...)
+	if err != nil {
+		return err
+	}
+
+	if len(fonts) == 0 {
+		return nil
+	}
+
+	rules, err := pageFontRules(runtime, doc, keys.Sorted())
+	if err != nil {
+		return err
+	}
+	used := map[int]bool{}
+	for _, use := range pageFontUses(doc, rules) {
+		for _, i := range usedFaces(fonts, use) {
+			used[i] = true
+		}
+	}
+
+	existing := existingPreloads(headNode)
+	max := runtime.Config.Fonts.MaxPreload
+	if max <= 0 {
+		max = defaultMaxPreload
+	}
+	count := 0
+	for i, f := range fonts {
+		if count >= max {
+			break
+		}
+		if !used[i] {
+			continue
+		}
+
+		// Srcs are listed in order of preference so only preload the first
+		a := f.Assets[0]
+		u, err := a.URL()
+		if err != nil {
+			return err
+		}
+		if existing[u] {
+			continue
+		}
+
+		if runtime.Debug {
+			fmt.Printf("Preloading font %q for %q\n", u, f.Family)
+		}
+		headNode.AppendChild(htmlparsing.PreloadFontTag(u, fontusage.MimeType(a.Type())))
+		existing[u] = true
+		count++
+	}
+
+	return nil
+}
+
+// pageFontRules returns the font rules of the page's CSS assets followed by
+// those of its style elements
+func pageFontRules(runtime manipulations.Runtime, doc *html.Node, keys []string) ([]css.FontRule, error) {
+	rules := []css.FontRule{}
+	for _, k := range keys {
+		for _, t := range pageCSS {
+			for _, a := range runtime.Assets.WithID(k)[t] {
+				if !a.IsLocal() {
+					continue
+				}
+				c, err := a.Contents()
+				if err != nil {
+					return nil, err
+				}
+				rules = append(rules, css.FontRules(c)...)
+			}
+		}
+	}
+
+	for _, s := range htmlparsing.FindNodesByTag("style", doc) {
+		if s.FirstChild != nil {
+			rules = append(rules, css.FontRules(s.FirstChild.Data)...)
+		}
+	}
+	return rules, nil
+}
+
+func existingPreloads(headNode *html.Node) map[string]bool {
+	existing := map[string]bool{}
+	for _, l := range htmlparsing.FindNodesByTag("link", headNode) {
+		attrs := htmlparsing.Attributes(l)
+		if attrs["rel"].Val != "preload" {
+			continue
+		}
+		existing[attrs["href"].Val] = true
+	}
+	return existing
+}
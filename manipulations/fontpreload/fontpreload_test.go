@@ -0,0 +1,263 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontpreload
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetstubs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+func Test_Manipulator(t *testing.T) {
+	fonts := map[assets.Type][]assetmanager.Asset{
+		assets.WOFF2: {
+			&assetstubs.Asset{TypeReturn: assets.WOFF2, URLReturn: "/fonts/sans.woff2", IsLocalReturn: true},
+			&assetstubs.Asset{TypeReturn: assets.WOFF2, URLReturn: "/fonts/sans-bold.woff2", IsLocalReturn: true},
+			&assetstubs.Asset{TypeReturn: assets.WOFF2, URLReturn: "/fonts/sans-italic.woff2", IsLocalReturn: true},
+			&assetstubs.Asset{TypeReturn: assets.WOFF2, URLReturn: "/fonts/sans-cyrillic.woff2", IsLocalReturn: true},
+			&assetstubs.Asset{TypeReturn: assets.WOFF2, URLReturn: "/fonts/serif.woff2", IsLocalReturn: true},
+			&assetstubs.Asset{TypeReturn: assets.WOFF2, URLReturn: "/fonts/mono.woff2", IsLocalReturn: true},
+		},
+		assets.WOFF: {
+			&assetstubs.Asset{TypeReturn: assets.WOFF, URLReturn: "/fonts/sans.woff", IsLocalReturn: true},
+		},
+	}
+	css := map[string]map[assets.Type][]assetmanager.Asset{
+		"always": {
+			assets.InlineCSS: {
+				&assetstubs.Asset{
+					URLReturn: "/css/always.css",
+					ContentsReturn: `@font-face{font-family:Sans;src:url(../fonts/sans.woff2) format("woff2"),url(../fonts/sans.woff) format("woff")}
+@font-face{font-family:Sans;font-weight:700;src:url(../fonts/sans-bold.woff2)}
+@font-face{font-family:Sans;font-style:italic;src:url(../fonts/sans-italic.woff2)}
+@font-face{font-family:Sans;unicode-range:U+0400-045F;src:url(../fonts/sans-cyrillic.woff2)}
+body{font-family:Sans,sans-serif}`,
+					IsLocalReturn: true,
+				},
+			},
+			assets.AsyncCSS: {
+				&assetstubs.Asset{
+					URLReturn:      "/css/always-async.css",
+					ContentsReturn: `@font-face{font-family:Mono;src:url(../fonts/mono.woff2)}code{font-family:Mono}`,
+					IsLocalReturn:  true,
+				},
+			},
+		},
+		"c-article": {
+			assets.SyncCSS: {
+				&assetstubs.Asset{
+					URLReturn:      "/css/c-article.css",
+					ContentsReturn: `@font-face{font-family:Serif;src:url(/fonts/serif.woff2)}.c-article h1{font-family:"Serif",serif}`,
+					IsLocalReturn:  true,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without config",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<div class="c-article"></div>`),
+			want: `<html><head></head><body><div class="c-article"></div></body></html>`,
+		},
+		{
+			description: "return error if css contents fail",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn: map[string]map[assets.Type][]assetmanager.Asset{
+						"always": {
+							assets.InlineCSS: {
+								&assetstubs.Asset{ContentsError: errInjected, IsLocalReturn: true},
+							},
+						},
+					},
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:       MustGetNode(t, ``),
+			want:      `<html><head></head><body></body></html>`,
+			wantError: errInjected,
+		},
+		{
+			description: "preload preferred fonts used by inline and sync css",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<div class="c-article"><h1>Title</h1><p>Text <code>code</code></p></div>`),
+			want: `<html><head><link rel="preload" as="font" href="/fonts/sans.woff2" type="font/woff2" crossorigin=""/><link rel="preload" as="font" href="/fonts/serif.woff2" type="font/woff2" crossorigin=""/></head><body><div class="c-article"><h1>Title</h1><p>Text <code>code</code></p></div></body></html>`,
+		},
+		{
+			description: "only preload fonts used by the page",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<div class="c-article"><p>Text</p></div>`),
+			want: `<html><head><link rel="preload" as="font" href="/fonts/sans.woff2" type="font/woff2" crossorigin=""/></head><body><div class="c-article"><p>Text</p></div></body></html>`,
+		},
+		{
+			description: "do nothing for pages without text",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<div class="c-article"><h1> </h1></div>`),
+			want: `<html><head></head><body><div class="c-article"><h1> </h1></div></body></html>`,
+		},
+		{
+			description: "preload faces for the weights, styles and unicode ranges of the text",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{
+						MaxPreload: 4,
+					},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<p>Привет <em>мир</em> <strong>Bold</strong></p>`),
+			want: `<html><head><link rel="preload" as="font" href="/fonts/sans.woff2" type="font/woff2" crossorigin=""/><link rel="preload" as="font" href="/fonts/sans-bold.woff2" type="font/woff2" crossorigin=""/><link rel="preload" as="font" href="/fonts/sans-italic.woff2" type="font/woff2" crossorigin=""/><link rel="preload" as="font" href="/fonts/sans-cyrillic.woff2" type="font/woff2" crossorigin=""/></head><body><p>Привет <em>мир</em> <strong>Bold</strong></p></body></html>`,
+		},
+		{
+			description: "preload fonts set by style elements",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<head><style>.note{font:italic 1rem Sans}</style></head><body><p class="note">Note</p></body>`),
+			want: `<html><head><style>.note{font:italic 1rem Sans}</style><link rel="preload" as="font" href="/fonts/sans-italic.woff2" type="font/woff2" crossorigin=""/></head><body><p class="note">Note</p></body></html>`,
+		},
+		{
+			description: "limit the number of preloaded fonts to the default",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<p>Привет <em>мир</em> <strong>Bold</strong></p>`),
+			want: `<html><head><link rel="preload" as="font" href="/fonts/sans.woff2" type="font/woff2" crossorigin=""/><link rel="preload" as="font" href="/fonts/sans-bold.woff2" type="font/woff2" crossorigin=""/></head><body><p>Привет <em>мир</em> <strong>Bold</strong></p></body></html>`,
+		},
+		{
+			description: "limit the number of preloaded fonts and skip existing preloads",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Fonts: &config.FontsConfig{
+						MaxPreload: 1,
+					},
+				},
+				Assets: &assetstubs.Manager{
+					WithIDReturn:   css,
+					WithTypeReturn: fonts,
+				},
+			},
+			doc:  MustGetNode(t, `<head><link rel="preload" as="font" href="/fonts/sans.woff2" crossorigin=""/></head><body><div class="c-article"><h1>Title</h1><p>Text</p></div></body>`),
+			want: `<html><head><link rel="preload" as="font" href="/fonts/sans.woff2" crossorigin=""/><link rel="preload" as="font" href="/fonts/serif.woff2" type="font/woff2" crossorigin=""/></head><body><div class="c-article"><h1>Title</h1><p>Text</p></div></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
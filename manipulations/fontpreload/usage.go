@@ -0,0 +1,318 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontpreload
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/fontusage"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/sets"
+	"golang.org/x/net/html"
+)
+
+var (
+	attributeSelectorRegex = regexp.MustCompile(`\[[^\]]*\]`)
+	pseudoSelectorRegex    = regexp.MustCompile(`::?[a-zA-Z-]+(?:\([^)]*\))?`)
+	simpleSelectorRegex    = regexp.MustCompile(`[.#]?[a-zA-Z0-9_-]+|\*`)
+
+	// Elements whose text is never rendered with the page's fonts
+	skipElements = sets.NewStringSet("head", "script", "style", "noscript", "template", "svg", "math")
+
+	// Browser default styles that change the font of an element
+	boldElements      = sets.NewStringSet("b", "strong", "th", "h1", "h2", "h3", "h4", "h5", "h6")
+	italicElements    = sets.NewStringSet("em", "i", "cite", "dfn", "var", "address")
+	monospaceElements = sets.NewStringSet("code", "kbd", "pre", "samp", "tt")
+)
+
+// textStyle is the computed font of an element
+type textStyle struct {
+	families []string
+	weight   int
+	style    string
+}
+
+// fontUse is a font the page's text is rendered with and the text using it
+type fontUse struct {
+	textStyle
+	text string
+}
+
+// pageFontUses returns the fonts the text of a page is rendered with. Rules
+// apply in source order and selectors are only matched on their last
+// compound selector, so a use may be reported that the page doesn't have,
+// which at worst preloads a font that isn't needed.
+func pageFontUses(doc *html.Node, rules []css.FontRule) []fontUse {
+	uses := []fontUse{}
+	index := map[string]int{}
+
+	var walk func(n *html.Node, s textStyle)
+	walk = func(n *html.Node, s textStyle) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.TextNode:
+				if n.Type != html.ElementNode || strings.TrimSpace(c.Data) == "" {
+					continue
+				}
+				key := strings.Join([]string{
+					strings.Join(s.families, ","),
+					strconv.Itoa(s.weight),
+					s.style,
+				}, "|")
+				i, ok := index[key]
+				if !ok {
+					i = len(uses)
+					index[key] = i
+					uses = append(uses, fontUse{textStyle: s})
+				}
+				uses[i].text += c.Data
+			case html.ElementNode:
+				if skipElements.Contains(c.Data) {
+					continue
+				}
+				walk(c, computeStyle(c, s, rules))
+			default:
+				walk(c, s)
+			}
+		}
+	}
+	walk(doc, textStyle{weight: 400, style: "normal"})
+	return uses
+}
+
+func computeStyle(n *html.Node, parent textStyle, rules []css.FontRule) textStyle {
+	s := parent
+	switch {
+	case boldElements.Contains(n.Data):
+		s.weight = resolveWeight("bolder", parent.weight)
+	case italicElements.Contains(n.Data):
+		s.style = "italic"
+	case monospaceElements.Contains(n.Data):
+		s.families = []string{"monospace"}
+	}
+
+	for _, r := range rules {
+		if !matchesAny(n, r.Selectors) {
+			continue
+		}
+		if r.Families != nil {
+			s.families = r.Families
+		}
+		if r.Weight != "" {
+			s.weight = resolveWeight(r.Weight, parent.weight)
+		}
+		if r.Style != "" {
+			s.style = strings.Fields(r.Style + " normal")[0]
+		}
+	}
+	return s
+}
+
+func matchesAny(n *html.Node, selectors []string) bool {
+	for _, s := range selectors {
+		if matches(n, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(n *html.Node, selector string) bool {
+	selector = attributeSelectorRegex.ReplaceAllString(selector, "")
+	selector = pseudoSelectorRegex.ReplaceAllString(selector, "")
+	compounds := strings.FieldsFunc(selector, func(r rune) bool {
+		return r == '>' || r == '+' || r == '~' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(compounds) == 0 {
+		return true
+	}
+
+	attrs := htmlparsing.Attributes(n)
+	classes := sets.NewStringSet(strings.Fields(attrs["class"].Val)...)
+	for _, s := range simpleSelectorRegex.FindAllString(compounds[len(compounds)-1], -1) {
+		switch {
+		case s == "*":
+		case strings.HasPrefix(s, "."):
+			if !classes.Contains(s[1:]) {
+				return false
+			}
+		case strings.HasPrefix(s, "#"):
+			if attrs["id"].Val != s[1:] {
+				return false
+			}
+		default:
+			if !strings.EqualFold(n.Data, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resolveWeight returns the numeric weight of a font-weight value, using
+// the parent weight for relative and unknown values
+func resolveWeight(value string, parent int) int {
+	switch value {
+	case "normal":
+		return 400
+	case "bold":
+		return 700
+	case "bolder":
+		switch {
+		case parent < 350:
+			return 400
+		case parent < 550:
+			return 700
+		}
+		return int(math.Max(900, float64(parent)))
+	case "lighter":
+		switch {
+		case parent < 550:
+			return int(math.Min(100, float64(parent)))
+		case parent < 750:
+			return 400
+		}
+		return 700
+	}
+	if w, err := strconv.ParseFloat(value, 64); err == nil {
+		return int(w)
+	}
+	return parent
+}
+
+// usedFaces returns the indexes of the fonts a browser would download to
+// render the text of a font use
+func usedFaces(fonts []fontusage.Font, use fontUse) []int {
+	for _, family := range use.families {
+		faces := []int{}
+		for i, f := range fonts {
+			if strings.EqualFold(f.Family, family) {
+				faces = append(faces, i)
+			}
+		}
+		if len(faces) == 0 {
+			// The browser falls back to the next family
+			continue
+		}
+
+		faces = matchStyle(fonts, faces, use.style)
+		faces = matchWeight(fonts, faces, use.weight)
+
+		used := []int{}
+		for _, i := range faces {
+			if coversText(fonts[i].UnicodeRange, use.text) {
+				used = append(used, i)
+			}
+		}
+		return used
+	}
+	return nil
+}
+
+func matchStyle(fonts []fontusage.Font, faces []int, style string) []int {
+	preferred := []string{style, "normal"}
+	switch style {
+	case "italic":
+		preferred = []string{"italic", "oblique", "normal"}
+	case "oblique":
+		preferred = []string{"oblique", "italic", "normal"}
+	}
+	for _, p := range preferred {
+		matched := []int{}
+		for _, i := range faces {
+			if faceStyle(fonts[i]) == p {
+				matched = append(matched, i)
+			}
+		}
+		if len(matched) > 0 {
+			return matched
+		}
+	}
+	return faces
+}
+
+func matchWeight(fonts []fontusage.Font, faces []int, weight int) []int {
+	best := math.MaxInt32
+	matched := []int{}
+	for _, i := range faces {
+		min, max := faceWeights(fonts[i])
+		d := 0
+		switch {
+		case weight < min:
+			d = min - weight
+		case weight > max:
+			d = weight - max
+		}
+		if d < best {
+			best = d
+			matched = matched[:0]
+		}
+		if d == best {
+			matched = append(matched, i)
+		}
+	}
+	return matched
+}
+
+func faceStyle(f fontusage.Font) string {
+	fields := strings.Fields(strings.ToLower(f.Style))
+	if len(fields) == 0 {
+		return "normal"
+	}
+	return fields[0]
+}
+
+// faceWeights returns the weight range of a face, which is a single weight
+// unless the face is a variable font
+func faceWeights(f fontusage.Font) (int, int) {
+	weights := []int{}
+	for _, v := range strings.Fields(strings.ToLower(f.Weight)) {
+		w := resolveWeight(v, -1)
+		if w != -1 {
+			weights = append(weights, w)
+		}
+	}
+	switch len(weights) {
+	case 0:
+		return 400, 400
+	case 1:
+		return weights[0], weights[0]
+	}
+	if weights[0] > weights[1] {
+		return weights[1], weights[0]
+	}
+	return weights[0], weights[1]
+}
+
+func coversText(unicodeRange, text string) bool {
+	if unicodeRange == "" {
+		return true
+	}
+	ranges := css.UnicodeRanges(unicodeRange)
+	for _, r := range text {
+		for _, ur := range ranges {
+			if r >= ur[0] && r <= ur[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
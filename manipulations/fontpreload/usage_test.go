@@ -0,0 +1,138 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontpreload
+
+import (
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/fontusage"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_matches(t *testing.T) {
+	doc := MustGetNode(t, `<main><p id="intro" class="lead note">Text</p></main>`)
+	p := htmlparsing.FindNodeByTag("p", doc)
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "*", want: true},
+		{selector: "p", want: true},
+		{selector: "P", want: true},
+		{selector: "main > p.lead", want: true},
+		{selector: "p.lead.note#intro", want: true},
+		{selector: "p:first-child::first-line", want: true},
+		{selector: `p[data-x="a b"]`, want: true},
+		{selector: ":root", want: true},
+		{selector: "div", want: false},
+		{selector: ".other", want: false},
+		{selector: "#other", want: false},
+		{selector: "p ~ span", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := matches(p, tt.selector); got != tt.want {
+				t.Fatalf("Unexpected result; got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolveWeight(t *testing.T) {
+	tests := []struct {
+		value  string
+		parent int
+		want   int
+	}{
+		{value: "normal", parent: 700, want: 400},
+		{value: "bold", parent: 400, want: 700},
+		{value: "600", parent: 400, want: 600},
+		{value: "bolder", parent: 300, want: 400},
+		{value: "bolder", parent: 400, want: 700},
+		{value: "bolder", parent: 700, want: 900},
+		{value: "lighter", parent: 400, want: 100},
+		{value: "lighter", parent: 700, want: 400},
+		{value: "lighter", parent: 900, want: 700},
+		{value: "inherit", parent: 300, want: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := resolveWeight(tt.value, tt.parent); got != tt.want {
+				t.Fatalf("Unexpected result; got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_usedFaces(t *testing.T) {
+	fonts := []fontusage.Font{
+		{Family: "Sans"},
+		{Family: "Sans", Weight: "bold"},
+		{Family: "Sans", Style: "oblique 10deg"},
+		{Family: "Sans", UnicodeRange: "U+0400-045F"},
+		{Family: "Variable", Weight: "900 100"},
+	}
+
+	tests := []struct {
+		description string
+		use         fontUse
+		want        []int
+	}{
+		{
+			description: "return nothing for families without faces",
+			use:         fontUse{textStyle: textStyle{families: []string{"serif"}, weight: 400, style: "normal"}, text: "Text"},
+		},
+		{
+			description: "return faces of the first family with faces",
+			use:         fontUse{textStyle: textStyle{families: []string{"Serif", "sans", "Variable"}, weight: 400, style: "normal"}, text: "Text"},
+			want:        []int{0},
+		},
+		{
+			description: "return faces covering the text",
+			use:         fontUse{textStyle: textStyle{families: []string{"Sans"}, weight: 400, style: "normal"}, text: "Текст"},
+			want:        []int{0, 3},
+		},
+		{
+			description: "return the nearest weight",
+			use:         fontUse{textStyle: textStyle{families: []string{"Sans"}, weight: 900, style: "normal"}, text: "Text"},
+			want:        []int{1},
+		},
+		{
+			description: "return oblique faces for italic text",
+			use:         fontUse{textStyle: textStyle{families: []string{"Sans"}, weight: 400, style: "italic"}, text: "Text"},
+			want:        []int{2},
+		},
+		{
+			description: "return variable faces covering the weight",
+			use:         fontUse{textStyle: textStyle{families: []string{"Variable"}, weight: 350, style: "italic"}, text: "Text"},
+			want:        []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := usedFaces(fonts, tt.use)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package iconlinks
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/icons"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+var (
+	osStat = os.Stat
+
+	// Icon files are checked once per run rather than for every page
+	existsCache sync.Map
+)
+
+// Manipulator adds links to the favicon, apple-touch-icon and web app
+// manifest generated by genicons to pages that don't link to their own.
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if runtime.Config == nil || runtime.Config.Icons == nil {
+		return nil
+	}
+
+	headNode := htmlparsing.FindNodeByTag("head", doc)
+	if headNode == nil {
+		return nil
+	}
+
+	dirURL, err := icons.URL(runtime.Config)
+	if err != nil {
+		return err
+	}
+
+	sizes := []string{}
+	for _, s := range icons.FaviconSizes {
+		sizes = append(sizes, fmt.Sprintf("%vx%v", s, s))
+	}
+
+	links := []struct {
+		rel   string
+		file  string
+		attrs []html.Attribute
+	}{
+		{rel: "icon", file: icons.Favicon, attrs: []html.Attribute{{Key: "sizes", Val: strings.Join(sizes, " ")}}},
+		{rel: "apple-touch-icon", file: icons.AppleTouchIcon},
+		{rel: "manifest", file: icons.Manifest},
+	}
+
+	existing := existingRels(headNode)
+	for _, l := range links {
+		if existing[l.rel] {
+			continue
+		}
+
+		if !exists(filepath.Join(runtime.Config.Icons.OutputDir, l.file)) {
+			continue
+		}
+
+		if runtime.Debug {
+			fmt.Printf("Adding %v link to %q\n", l.rel, dirURL+"/"+l.file)
+		}
+		attrs := append([]html.Attribute{
+			{Key: "rel", Val: l.rel},
+			{Key: "href", Val: dirURL + "/" + l.file},
+		}, l.attrs...)
+		headNode.AppendChild(&html.Node{
+			Type: html.ElementNode,
+			Data: "link",
+			Attr: attrs,
+		})
+	}
+
+	return nil
+}
+
+// exists returns true if an icon file was generated, warning once for each
+// missing file
+func exists(p string) bool {
+	if e, ok := existsCache.Load(p); ok {
+		return e.(bool)
+	}
+
+	_, err := osStat(p)
+	if _, loaded := existsCache.LoadOrStore(p, err == nil); !loaded && err != nil {
+		log.Printf("Warning: Unable to find %q, run genicons to generate it: %v", filepath.Base(p), err)
+	}
+	return err == nil
+}
+
+// existingRels returns the link rels in the head, treating "shortcut icon"
+// as icon
+func existingRels(headNode *html.Node) map[string]bool {
+	existing := map[string]bool{}
+	for _, l := range htmlparsing.FindNodesByTag("link", headNode) {
+		for _, r := range strings.Fields(strings.ToLower(htmlparsing.Attributes(l)["rel"].Val)) {
+			existing[r] = true
+		}
+	}
+	return existing
+}
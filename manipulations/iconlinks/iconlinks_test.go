@@ -0,0 +1,171 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package iconlinks
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/icons"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_Manipulator(t *testing.T) {
+	conf := &config.Config{
+		Assets: &config.AssetsConfig{StaticDir: "/static"},
+		Icons:  &config.IconsConfig{OutputDir: "/static/icons"},
+	}
+
+	tests := []struct {
+		description string
+		conf        *config.Config
+		doc         string
+		osStat      func(string) (os.FileInfo, error)
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without icons config",
+			conf:        &config.Config{},
+			doc:         `<html><head></head><body></body></html>`,
+			want:        `<html><head></head><body></body></html>`,
+		},
+		{
+			description: "return error when the output dir is outside the static dir",
+			conf: &config.Config{
+				Assets: &config.AssetsConfig{StaticDir: "/static"},
+				Icons:  &config.IconsConfig{OutputDir: "/icons"},
+			},
+			doc:       `<html><head></head><body></body></html>`,
+			wantError: icons.ErrOutsideStatic,
+		},
+		{
+			description: "add every link",
+			conf:        conf,
+			doc:         `<html><head><title>Title</title></head><body></body></html>`,
+			want:        `<html><head><title>Title</title><link rel="icon" href="/icons/favicon.ico" sizes="16x16 32x32 48x48"/><link rel="apple-touch-icon" href="/icons/apple-touch-icon.png"/><link rel="manifest" href="/icons/manifest.webmanifest"/></head><body></body></html>`,
+		},
+		{
+			description: "keep existing icon and manifest links",
+			conf:        conf,
+			doc:         `<html><head><link rel="shortcut icon" href="/favicon.png"/><link rel="manifest" href="/app.webmanifest"/></head><body></body></html>`,
+			want:        `<html><head><link rel="shortcut icon" href="/favicon.png"/><link rel="manifest" href="/app.webmanifest"/><link rel="apple-touch-icon" href="/icons/apple-touch-icon.png"/></head><body></body></html>`,
+		},
+		{
+			description: "skip files that weren't generated",
+			conf:        conf,
+			doc:         `<html><head></head><body></body></html>`,
+			osStat: func(p string) (os.FileInfo, error) {
+				if filepath.Base(p) == "manifest.webmanifest" {
+					return nil, nil
+				}
+				return nil, os.ErrNotExist
+			},
+			want: `<html><head><link rel="manifest" href="/icons/manifest.webmanifest"/></head><body></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			existsCache = sync.Map{}
+			osStat = func(string) (os.FileInfo, error) {
+				return nil, nil
+			}
+			if tt.osStat != nil {
+				osStat = tt.osStat
+			}
+			t.Cleanup(func() {
+				osStat = os.Stat
+			})
+
+			doc := MustGetNode(t, tt.doc)
+			err := Manipulator(manipulations.Runtime{Config: tt.conf}, doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_Manipulator_StatsOnce(t *testing.T) {
+	existsCache = sync.Map{}
+	stats := map[string]int{}
+	osStat = func(p string) (os.FileInfo, error) {
+		stats[filepath.Base(p)]++
+		return nil, os.ErrNotExist
+	}
+	t.Cleanup(func() {
+		osStat = os.Stat
+		existsCache = sync.Map{}
+	})
+
+	conf := &config.Config{
+		Assets: &config.AssetsConfig{StaticDir: "/static"},
+		Icons:  &config.IconsConfig{OutputDir: "/static/icons"},
+	}
+	for i := 0; i < 3; i++ {
+		doc := MustGetNode(t, `<html><head></head><body></body></html>`)
+		if err := Manipulator(manipulations.Runtime{Config: conf}, doc); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	want := map[string]int{
+		"favicon.ico":          1,
+		"apple-touch-icon.png": 1,
+		"manifest.webmanifest": 1,
+	}
+	if diff := cmp.Diff(stats, want); diff != "" {
+		t.Fatalf("Unexpected stats; diff %v", diff)
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+	return buf.String()
+}
@@ -28,7 +28,8 @@ import (
 )
 
 var (
-	genimgsOpen = genimgs.Open
+	genimgsOpen    = genimgs.Open
+	genimgsSVGSize = genimgs.SVGSize
 )
 
 func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
@@ -49,23 +50,31 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 			continue
 		}
 
+		var origWidth, origHeight int
+		var err error
 		if strings.HasPrefix(srcAttr.Val, "http") || strings.HasPrefix(srcAttr.Val, "//") {
-			if runtime.Debug {
-				fmt.Printf("Skipping img with abs URL %q\n", srcAttr.Val)
+			if !runtime.HasRemoteImages {
+				if runtime.Debug {
+					fmt.Printf("Skipping img with abs URL %q\n", srcAttr.Val)
+				}
+				continue
 			}
-			continue
-		}
 
-		// Get the src image
-		i, err := genimgsOpen(runtime.Config, srcAttr.Val)
-		if err != nil {
-			fmt.Printf("Failed to open img %q\n", srcAttr.Val)
-			continue
+			// Get width and height from the remote image header or cache
+			origWidth, origHeight, err = runtime.RemoteImages.Size(srcAttr.Val)
+			if err != nil {
+				fmt.Printf("Failed to get size of remote img %q: %v\n", srcAttr.Val, err)
+				continue
+			}
+		} else {
+			// Get width and height from the image
+			origWidth, origHeight, err = imgSize(runtime.Config, srcAttr.Val)
+			if err != nil {
+				fmt.Printf("Failed to open img %q\n", srcAttr.Val)
+				continue
+			}
 		}
 
-		// Get width and height from the image
-		origWidth, origHeight := i.Bounds().Size().X, i.Bounds().Size().Y
-
 		attributes["width"] = html.Attribute{
 			Key: "width",
 			Val: fmt.Sprintf("%v", origWidth),
@@ -81,6 +90,18 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 	return nil
 }
 
+func imgSize(conf *config.Config, src string) (int, int, error) {
+	if genimgs.IsSVG(src) {
+		return genimgsSVGSize(conf, src)
+	}
+
+	i, err := genimgsOpen(conf, src)
+	if err != nil {
+		return 0, 0, err
+	}
+	return i.Bounds().Size().X, i.Bounds().Size().Y, nil
+}
+
 func shouldRun(conf *config.Config) bool {
 	if conf == nil {
 		return false
@@ -36,9 +36,11 @@ var reset func()
 
 func TestMain(m *testing.M) {
 	origGenimgsOpen := genimgsOpen
+	origGenimgsSVGSize := genimgsSVGSize
 
 	reset = func() {
 		genimgsOpen = origGenimgsOpen
+		genimgsSVGSize = origGenimgsSVGSize
 	}
 
 	os.Exit(m.Run())
@@ -50,6 +52,7 @@ func Test_Manipulator(t *testing.T) {
 		runtime     manipulations.Runtime
 		doc         *html.Node
 		open        func(conf *config.Config, imgPath string) (image.Image, error)
+		svgSize     func(conf *config.Config, imgPath string) (int, int, error)
 		want        string
 		wantError   error
 	}{
@@ -107,6 +110,93 @@ func Test_Manipulator(t *testing.T) {
 			},
 			want: `<html><head></head><body><img height="4" src="/example.jpg" width="3"/></body></html>`,
 		},
+		{
+			description: "add width and height to svg without rasterizing",
+			doc:         MustGetNode(t, `<img src="/icon.svg"/>`),
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Assets: &config.AssetsConfig{
+						StaticDir: "/static",
+					},
+				},
+			},
+			open: func(conf *config.Config, imgPath string) (image.Image, error) {
+				t.Errorf("unexpected raster open for %v", imgPath)
+				return nil, errInjected
+			},
+			svgSize: func(conf *config.Config, imgPath string) (int, int, error) {
+				wantPath := "/icon.svg"
+				if imgPath != wantPath {
+					t.Errorf("unexpected img path; got %v, want %v", imgPath, wantPath)
+				}
+				return 24, 12, nil
+			},
+			want: `<html><head></head><body><img height="12" src="/icon.svg" width="24"/></body></html>`,
+		},
+		{
+			description: "do nothing if svg size is unknown",
+			doc:         MustGetNode(t, `<img src="/icon.svg"/>`),
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Assets: &config.AssetsConfig{
+						StaticDir: "/static",
+					},
+				},
+			},
+			svgSize: func(conf *config.Config, imgPath string) (int, int, error) {
+				return 0, 0, errInjected
+			},
+			want: `<html><head></head><body><img src="/icon.svg"/></body></html>`,
+		},
+		{
+			description: "do nothing for remote image without remote image client",
+			doc:         MustGetNode(t, `<img src="https://example.com/example.jpg"/>`),
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Assets: &config.AssetsConfig{
+						StaticDir: "/static",
+					},
+				},
+			},
+			want: `<html><head></head><body><img src="https://example.com/example.jpg"/></body></html>`,
+		},
+		{
+			description: "add width and height to remote image",
+			doc:         MustGetNode(t, `<img src="//example.com/example.jpg"/>`),
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Assets: &config.AssetsConfig{
+						StaticDir: "/static",
+					},
+				},
+				HasRemoteImages: true,
+				RemoteImages: &remoteImagesStub{
+					t:       t,
+					wantURL: "//example.com/example.jpg",
+					width:   5,
+					height:  6,
+				},
+			},
+			want: `<html><head></head><body><img height="6" src="//example.com/example.jpg" width="5"/></body></html>`,
+		},
+		{
+			description: "do nothing if remote image size is unknown",
+			doc:         MustGetNode(t, `<img src="https://example.com/example.jpg"/>`),
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Assets: &config.AssetsConfig{
+						StaticDir: "/static",
+					},
+				},
+				HasRemoteImages: true,
+				RemoteImages: &remoteImagesStub{
+					t:       t,
+					wantURL: "https://example.com/example.jpg",
+					err:     errInjected,
+				},
+			},
+			want: `<html><head></head><body><img src="https://example.com/example.jpg"/></body></html>`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,6 +204,7 @@ func Test_Manipulator(t *testing.T) {
 			t.Cleanup(reset)
 
 			genimgsOpen = tt.open
+			genimgsSVGSize = tt.svgSize
 
 			err := Manipulator(tt.runtime, tt.doc)
 			if !errors.Is(err, tt.wantError) {
@@ -127,6 +218,21 @@ func Test_Manipulator(t *testing.T) {
 	}
 }
 
+type remoteImagesStub struct {
+	t       *testing.T
+	wantURL string
+	width   int
+	height  int
+	err     error
+}
+
+func (r *remoteImagesStub) Size(url string) (int, int, error) {
+	if url != r.wantURL {
+		r.t.Errorf("unexpected remote URL; got %v, want %v", url, r.wantURL)
+	}
+	return r.width, r.height, r.err
+}
+
 func MustGetNode(t *testing.T, input string) *html.Node {
 	t.Helper()
 
@@ -17,6 +17,7 @@
 package imgtopicture
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -29,7 +30,17 @@ import (
 	"golang.org/x/net/html"
 )
 
+const (
+	// ModePicture wraps images in a picture element with a source per format
+	ModePicture = "picture"
+	// ModeImg adds srcset and sizes to images for their original format
+	ModeImg = "img"
+)
+
 var (
+	errUnknownMode    = errors.New("unknown img-to-picture mode")
+	errSizesAndLayout = errors.New("img-to-picture can't have both source-sizes and a layout")
+
 	genimgsOpen        = genimgs.Open
 	genimgsLookupSizes = genimgs.LookupSizes
 )
@@ -65,6 +76,23 @@ func shouldRun(conf *config.Config) bool {
 }
 
 func manipulateWithConfig(s3Client *s3.Client, debug bool, conf *config.Config, imgtopic *config.ImgToPicConfig, doc *html.Node) error {
+	if imgtopic.Mode != "" && imgtopic.Mode != ModePicture && imgtopic.Mode != ModeImg {
+		return fmt.Errorf("%w %q for %q, expected %q or %q", errUnknownMode, imgtopic.Mode, imgtopic.ID, ModePicture, ModeImg)
+	}
+
+	if imgtopic.Layout != nil {
+		if len(imgtopic.SourceSizes) > 0 {
+			return fmt.Errorf("%w for %q", errSizesAndLayout, imgtopic.ID)
+		}
+		sizes, err := layoutSizes(conf, imgtopic.Layout)
+		if err != nil {
+			return fmt.Errorf("invalid layout for %q: %w", imgtopic.ID, err)
+		}
+		withSizes := *imgtopic
+		withSizes.SourceSizes = sizes
+		imgtopic = &withSizes
+	}
+
 	rawElements := htmlparsing.FindNodesByTag(imgtopic.ID, doc)
 	rawElements = append(rawElements, htmlparsing.FindNodesByClassname(imgtopic.ID, doc)...)
 
@@ -108,6 +136,14 @@ func manipulateImg(s3Client *s3.Client, debug bool, conf *config.Config, imgtopi
 		return nil
 	}
 
+	// SVGs scale without generated sizes and should never be rasterized
+	if genimgs.IsSVG(srcAttr.Val) {
+		if debug {
+			fmt.Printf("Skipping svg img %q\n", srcAttr.Val)
+		}
+		return nil
+	}
+
 	// Get the src image
 	i, err := genimgsOpen(conf, srcAttr.Val)
 	if err != nil {
@@ -129,6 +165,11 @@ func manipulateImg(s3Client *s3.Client, debug bool, conf *config.Config, imgtopi
 		return nil
 	}
 
+	if imgtopic.Mode == ModeImg {
+		addSrcset(debug, imgtopic, ie, sizes)
+		return nil
+	}
+
 	// Remove element from it's parent so it can be wrapped by picture
 	p := ie.Parent
 	s := ie.NextSibling
@@ -178,6 +219,31 @@ func pictureElement(imgtopic *config.ImgToPicConfig, imgElement *html.Node, size
 	return picture
 }
 
+// addSrcset adds the generated sizes of the img's original format to the img
+// itself, leaving it in place so styles targeting it keep working
+func addSrcset(debug bool, imgtopic *config.ImgToPicConfig, imgElement *html.Node, sizes []genimgs.GenImg) {
+	attributes := htmlparsing.Attributes(imgElement)
+	if _, ok := attributes["srcset"]; ok {
+		if debug {
+			fmt.Printf("Skipping img with a srcset %q\n", attributes["src"].Val)
+		}
+		return
+	}
+
+	imgs := genimgs.GroupByType(sizes)[""]
+	if len(imgs) == 0 {
+		if debug {
+			fmt.Printf("No sizes in the original format found for %q\n", attributes["src"].Val)
+		}
+		return
+	}
+
+	attributes["src"] = html.Attribute{Key: "src", Val: imgs[len(imgs)-1].URL}
+	attributes["srcset"] = html.Attribute{Key: "srcset", Val: srcsetValue(imgs)}
+	attributes["sizes"] = html.Attribute{Key: "sizes", Val: strings.Join(imgtopic.SourceSizes, ",")}
+	imgElement.Attr = htmlparsing.AttributesList(attributes)
+}
+
 func createSourceElement(imgtopic *config.ImgToPicConfig, imgs []genimgs.GenImg) *html.Node {
 	source := &html.Node{
 		Type: html.ElementNode,
@@ -203,7 +269,16 @@ func createSourceElement(imgtopic *config.ImgToPicConfig, imgs []genimgs.GenImg)
 		Val: strings.Join(imgtopic.SourceSizes, ","),
 	})
 
-	// Sort and add srcset attribute
+	source.Attr = append(source.Attr, html.Attribute{
+		Key: "srcset",
+		Val: srcsetValue(imgs),
+	})
+
+	return source
+}
+
+// srcsetValue sorts the images by size and returns them as a srcset
+func srcsetValue(imgs []genimgs.GenImg) string {
 	sort.Slice(imgs, func(i, j int) bool {
 		return imgs[i].Size < imgs[j].Size
 	})
@@ -212,13 +287,7 @@ func createSourceElement(imgtopic *config.ImgToPicConfig, imgs []genimgs.GenImg)
 	for _, s := range imgs {
 		srcsetValues = append(srcsetValues, fmt.Sprintf("%v %vw", s.URL, s.Size))
 	}
-
-	source.Attr = append(source.Attr, html.Attribute{
-		Key: "srcset",
-		Val: strings.Join(srcsetValues, ","),
-	})
-
-	return source
+	return strings.Join(srcsetValues, ",")
 }
 
 func orderedSourceSets(sourceSetByType map[string][]genimgs.GenImg) [][]genimgs.GenImg {
@@ -446,6 +446,61 @@ func Test_manipulateImg(t *testing.T) {
 			},
 			want: `<html><head></head><body><picture><source sizes="100vw" srcset="/example-100.png 100w"/><img src="/example-100.png"/></picture></body></html>`,
 		},
+		{
+			description: "add srcset and sizes to the img in img mode",
+			imgtopic: &config.ImgToPicConfig{
+				SourceSizes: []string{"(min-width: 800px) 400px", "100vw"},
+				Mode:        ModeImg,
+			},
+			doc: MustGetNode(t, `<figure><img src="/example.png" alt="Example"/></figure>`),
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				return &image.RGBA{}, nil
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{
+					{Type: "image/webp", Size: 100, URL: "/example-100.webp"},
+					{Type: "", Size: 200, URL: "/example-200.png"},
+					{Type: "", Size: 100, URL: "/example-100.png"},
+				}, nil
+			},
+			want: `<html><head></head><body><figure><img alt="Example" sizes="(min-width: 800px) 400px,100vw" src="/example-200.png" srcset="/example-100.png 100w,/example-200.png 200w"/></figure></body></html>`,
+		},
+		{
+			description: "keep an existing srcset in img mode",
+			imgtopic: &config.ImgToPicConfig{
+				SourceSizes: []string{"100vw"},
+				Mode:        ModeImg,
+			},
+			doc: MustGetNode(t, `<img src="/example.png" srcset="/example.png 1x"/>`),
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				return &image.RGBA{}, nil
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{
+					{Type: "image/webp", Size: 100, URL: "/example-100.webp"},
+					{Type: "", Size: 200, URL: "/example-200.png"},
+					{Type: "", Size: 100, URL: "/example-100.png"},
+				}, nil
+			},
+			want: `<html><head></head><body><img src="/example.png" srcset="/example.png 1x"/></body></html>`,
+		},
+		{
+			description: "do nothing in img mode without sizes in the original format",
+			imgtopic: &config.ImgToPicConfig{
+				SourceSizes: []string{"100vw"},
+				Mode:        ModeImg,
+			},
+			doc: MustGetNode(t, `<img src="/example.png"/>`),
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				return &image.RGBA{}, nil
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{
+					{Type: "image/webp", Size: 100, URL: "/example-100.webp"},
+				}, nil
+			},
+			want: `<html><head></head><body><img src="/example.png"/></body></html>`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -538,6 +593,64 @@ func Test_manipulateWithConfig(t *testing.T) {
 			wantError: errInjected,
 			want:      `<html><head></head><body><img src="/example.png"/></body></html>`,
 		},
+		{
+			description: "use sizes from the layout",
+			imgtopic: &config.ImgToPicConfig{
+				ID: "img",
+				Layout: &config.LayoutConfig{
+					MaxWidth: 800,
+				},
+			},
+			doc: MustGetNode(t, `<img src="/example.png"/>`),
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				return &image.RGBA{}, nil
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{
+					{
+						Type: "",
+						Size: 100,
+						URL:  "/example-100.png",
+					},
+				}, nil
+			},
+			conf: &config.Config{},
+			want: `<html><head></head><body><picture><source sizes="(min-width: 800px) 800px,100vw" srcset="/example-100.png 100w"/><img src="/example-100.png"/></picture></body></html>`,
+		},
+		{
+			description: "return error for source sizes and a layout",
+			imgtopic: &config.ImgToPicConfig{
+				ID:          "img",
+				SourceSizes: []string{"100vw"},
+				Layout: &config.LayoutConfig{
+					MaxWidth: 800,
+				},
+			},
+			doc:       MustGetNode(t, `<img src="/example.png"/>`),
+			wantError: errSizesAndLayout,
+			want:      `<html><head></head><body><img src="/example.png"/></body></html>`,
+		},
+		{
+			description: "return error for an invalid layout",
+			imgtopic: &config.ImgToPicConfig{
+				ID:     "img",
+				Layout: &config.LayoutConfig{},
+			},
+			doc:       MustGetNode(t, `<img src="/example.png"/>`),
+			conf:      &config.Config{},
+			wantError: errLayoutMaxWidth,
+			want:      `<html><head></head><body><img src="/example.png"/></body></html>`,
+		},
+		{
+			description: "return error for an unknown mode",
+			imgtopic: &config.ImgToPicConfig{
+				ID:   "img",
+				Mode: "figure",
+			},
+			doc:       MustGetNode(t, `<img src="/example.png"/>`),
+			wantError: errUnknownMode,
+			want:      `<html><head></head><body><img src="/example.png"/></body></html>`,
+		},
 	}
 
 	for _, tt := range tests {
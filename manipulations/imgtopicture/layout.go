@@ -0,0 +1,138 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package imgtopicture
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+)
+
+var (
+	errLayoutMaxWidth = errors.New("layout needs a max-width")
+	errLayoutColumns  = errors.New("layout columns need a count of at least 1")
+	errLayoutGutter   = errors.New("layout gutters leave no space for images")
+	errLayoutTooWide  = errors.New("layout is wider than gen-assets max-width")
+)
+
+// layoutBreakpoint is a viewport width from which images are a number of
+// columns wide
+type layoutBreakpoint struct {
+	minWidth int64
+	columns  int64
+}
+
+// layoutSizes returns the source sizes for images in a layout. Every
+// breakpoint at or above the layout's max-width has a fixed size and those
+// below are relative to the viewport. An error is returned if the widest
+// image is wider than gen-assets max-width, as the largest generated image
+// wouldn't cover it at gen-assets max-density.
+func layoutSizes(conf *config.Config, layout *config.LayoutConfig) ([]string, error) {
+	if layout.MaxWidth <= 0 {
+		return nil, errLayoutMaxWidth
+	}
+
+	byWidth := map[int64]int64{0: 1}
+	for _, c := range layout.Columns {
+		if c.Count < 1 {
+			return nil, fmt.Errorf("%w, got %v at min-width %vpx", errLayoutColumns, c.Count, c.MinWidth)
+		}
+		byWidth[c.MinWidth] = c.Count
+	}
+
+	breakpoints := []layoutBreakpoint{}
+	for w, c := range byWidth {
+		breakpoints = append(breakpoints, layoutBreakpoint{minWidth: w, columns: c})
+	}
+	sort.Slice(breakpoints, func(i, j int) bool {
+		return breakpoints[i].minWidth < breakpoints[j].minWidth
+	})
+
+	// The container stops growing at max-width so it needs a breakpoint
+	if _, ok := byWidth[layout.MaxWidth]; !ok {
+		columns := int64(1)
+		for _, b := range breakpoints {
+			if b.minWidth < layout.MaxWidth {
+				columns = b.columns
+			}
+		}
+		breakpoints = append(breakpoints, layoutBreakpoint{minWidth: layout.MaxWidth, columns: columns})
+		sort.Slice(breakpoints, func(i, j int) bool {
+			return breakpoints[i].minWidth < breakpoints[j].minWidth
+		})
+	}
+
+	widest := 0.0
+	sizes := []string{}
+	values := []string{}
+	for i := len(breakpoints) - 1; i >= 0; i-- {
+		b := breakpoints[i]
+		gutters := (b.columns + 1) * layout.Gutter
+
+		var v string
+		var width float64
+		if b.minWidth >= layout.MaxWidth {
+			width = float64(layout.MaxWidth-gutters) / float64(b.columns)
+			v = fmt.Sprintf("%vpx", math.Ceil(width))
+		} else {
+			// Images are widest just before the next breakpoint
+			width = float64(breakpoints[i+1].minWidth-gutters) / float64(b.columns)
+			v = fluidSize(b.columns, gutters)
+		}
+		if width <= 0 {
+			return nil, fmt.Errorf("%w at min-width %vpx", errLayoutGutter, b.minWidth)
+		}
+		widest = math.Max(widest, width)
+
+		// Drop a breakpoint with the same size as the one below it
+		if len(values) > 0 && values[len(values)-1] == v {
+			sizes = sizes[:len(sizes)-1]
+			values = values[:len(values)-1]
+		}
+
+		values = append(values, v)
+		if b.minWidth == 0 {
+			sizes = append(sizes, v)
+		} else {
+			sizes = append(sizes, fmt.Sprintf("(min-width: %vpx) %v", b.minWidth, v))
+		}
+	}
+
+	if conf.GenAssets != nil && conf.GenAssets.MaxWidth > 0 && widest > float64(conf.GenAssets.MaxWidth) {
+		g := conf.GenAssets
+		return nil, fmt.Errorf("%w, images are up to %vpx wide but the largest generated image of %vpx x %v density only covers %vpx", errLayoutTooWide, math.Ceil(widest), g.MaxWidth, g.MaxDensity, g.MaxWidth)
+	}
+
+	return sizes, nil
+}
+
+// fluidSize returns the size of a column relative to the viewport
+func fluidSize(columns, gutters int64) string {
+	switch {
+	case columns == 1 && gutters == 0:
+		return "100vw"
+	case columns == 1:
+		return fmt.Sprintf("calc(100vw - %vpx)", gutters)
+	case gutters == 0:
+		return fmt.Sprintf("calc(100vw / %v)", columns)
+	default:
+		return fmt.Sprintf("calc((100vw - %vpx) / %v)", gutters, columns)
+	}
+}
@@ -0,0 +1,130 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package imgtopicture
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_layoutSizes(t *testing.T) {
+	tests := []struct {
+		description string
+		genAssets   *config.GeneratedImagesConfig
+		layout      *config.LayoutConfig
+		want        []string
+		wantError   error
+	}{
+		{
+			description: "return error without max-width",
+			layout:      &config.LayoutConfig{},
+			wantError:   errLayoutMaxWidth,
+		},
+		{
+			description: "return error for columns without a count",
+			layout: &config.LayoutConfig{
+				MaxWidth: 800,
+				Columns:  []*config.LayoutColumnsConfig{{MinWidth: 600}},
+			},
+			wantError: errLayoutColumns,
+		},
+		{
+			description: "return error when gutters are wider than the container",
+			layout: &config.LayoutConfig{
+				MaxWidth: 800,
+				Gutter:   500,
+			},
+			wantError: errLayoutGutter,
+		},
+		{
+			description: "return sizes for a single column",
+			layout: &config.LayoutConfig{
+				MaxWidth: 800,
+			},
+			want: []string{"(min-width: 800px) 800px", "100vw"},
+		},
+		{
+			description: "return sizes for columns with gutters",
+			layout: &config.LayoutConfig{
+				MaxWidth: 1200,
+				Gutter:   16,
+				Columns: []*config.LayoutColumnsConfig{
+					{MinWidth: 1000, Count: 3},
+					{MinWidth: 600, Count: 2},
+				},
+			},
+			want: []string{
+				"(min-width: 1200px) 379px",
+				"(min-width: 1000px) calc((100vw - 64px) / 3)",
+				"(min-width: 600px) calc((100vw - 48px) / 2)",
+				"calc(100vw - 32px)",
+			},
+		},
+		{
+			description: "drop breakpoints with the same size",
+			layout: &config.LayoutConfig{
+				MaxWidth: 1000,
+				Columns: []*config.LayoutColumnsConfig{
+					{MinWidth: 900, Count: 2},
+					{MinWidth: 1200, Count: 2},
+				},
+			},
+			want: []string{
+				"(min-width: 1000px) 500px",
+				"(min-width: 900px) calc(100vw / 2)",
+				"100vw",
+			},
+		},
+		{
+			description: "return sizes when the largest generated image covers the layout",
+			genAssets: &config.GeneratedImagesConfig{
+				MaxWidth:   800,
+				MaxDensity: 2,
+			},
+			layout: &config.LayoutConfig{
+				MaxWidth: 800,
+			},
+			want: []string{"(min-width: 800px) 800px", "100vw"},
+		},
+		{
+			description: "return error when the largest generated image doesn't cover the layout",
+			genAssets: &config.GeneratedImagesConfig{
+				MaxWidth:   500,
+				MaxDensity: 2,
+			},
+			layout: &config.LayoutConfig{
+				MaxWidth: 800,
+			},
+			wantError: errLayoutTooWide,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := layoutSizes(&config.Config{GenAssets: tt.genAssets}, tt.layout)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected sizes; diff %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package injectassets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/minify"
+	"golang.org/x/net/html"
+)
+
+var (
+	errInvalidBundleType = errors.New("invalid bundle type")
+	errBundleWriteFailed = errors.New("unable to write bundle")
+	errBundleParseFailed = errors.New("unable to parse HTML file for bundling")
+
+	// bundleTypes maps the supported bundle types to the file name suffix
+	// used to identify the type of the bundle
+	bundleTypes = map[assets.Type]string{
+		assets.SyncCSS:  "-sync",
+		assets.AsyncCSS: "-async",
+		assets.SyncJS:   "-sync",
+		assets.AsyncJS:  "-async",
+	}
+
+	// bundleMu ensures pages manipulated concurrently share a single bundle
+	// for the same combination of assets
+	bundleMu sync.Mutex
+
+	// groupsCache holds the bundle groups for each asset manager so the HTML
+	// files are only read once
+	groupsMu    sync.Mutex
+	groupsCache = map[manipulations.AssetManager]map[assetmanager.Asset][]assetmanager.Asset{}
+
+	ioutilWriteFile = ioutil.WriteFile
+	osMkdirAll      = os.MkdirAll
+)
+
+// bundle replaces the local assets of a type that always appear together
+// across pages with a single bundled asset, positioned where the first of
+// them was.
+func bundle(runtime manipulations.Runtime, t assets.Type, as []assetmanager.Asset) ([]assetmanager.Asset, error) {
+	ok, err := shouldBundle(runtime.Config, t)
+	if err != nil || !ok {
+		return as, err
+	}
+
+	groups, err := bundleGroups(runtime.Assets)
+	if err != nil {
+		return nil, err
+	}
+
+	onPage := map[assetmanager.Asset]bool{}
+	for _, a := range as {
+		onPage[a] = true
+	}
+
+	bundles := map[assetmanager.Asset]assetmanager.Asset{}
+	for _, a := range as {
+		g, ok := groups[a]
+		if !ok || bundles[a] != nil || !containsAll(onPage, g) {
+			continue
+		}
+		b, err := writeBundle(runtime, t, a.Media(), g)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			continue
+		}
+		for _, m := range g {
+			bundles[m] = b
+		}
+	}
+
+	result := []assetmanager.Asset{}
+	added := map[assetmanager.Asset]bool{}
+	for _, a := range as {
+		b, ok := bundles[a]
+		if !ok {
+			result = append(result, a)
+			continue
+		}
+		if !added[b] {
+			result = append(result, b)
+			added[b] = true
+		}
+	}
+	return result, nil
+}
+
+// bundleGroups returns the groups of local assets with the same type and
+// media that appear on exactly the same HTML files, keyed by each asset in a
+// group. The groups are worked out once per asset manager from the HTML files
+// as they were before any manipulations, so an asset only used after a
+// manipulation adds its key to a page isn't bundled.
+func bundleGroups(manager manipulations.AssetManager) (map[assetmanager.Asset][]assetmanager.Asset, error) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+
+	if g, ok := groupsCache[manager]; ok {
+		return g, nil
+	}
+
+	// pages holds the indexes of the HTML files each asset is used on and
+	// order is where the asset was first injected, which is the same for
+	// every page as assets are injected in key order
+	pages := map[assetmanager.Asset][]string{}
+	order := map[assetmanager.Asset]int{}
+	for i, h := range manager.WithType(assets.HTML) {
+		if !h.IsLocal() {
+			continue
+		}
+		c, err := h.Contents()
+		if err != nil {
+			return nil, err
+		}
+		doc, err := html.Parse(strings.NewReader(c))
+		if err != nil {
+			return nil, fmt.Errorf("%w; %v", errBundleParseFailed, err)
+		}
+
+		sortedKeys := htmlparsing.GetKeys(doc).Sorted()
+		for t := range bundleTypes {
+			for j, a := range assetsOfType(manager, sortedKeys, t) {
+				if !a.IsLocal() {
+					continue
+				}
+				if _, ok := order[a]; !ok {
+					order[a] = j
+				}
+				pages[a] = append(pages[a], strconv.Itoa(i))
+			}
+		}
+	}
+
+	byUsage := map[string][]assetmanager.Asset{}
+	for a, p := range pages {
+		k := fmt.Sprintf("%v|%v|%v", a.Type(), a.Media(), strings.Join(p, ","))
+		byUsage[k] = append(byUsage[k], a)
+	}
+
+	groups := map[assetmanager.Asset][]assetmanager.Asset{}
+	for _, g := range byUsage {
+		if len(g) < 2 {
+			continue
+		}
+		sort.Slice(g, func(i, j int) bool {
+			return order[g[i]] < order[g[j]]
+		})
+		for _, a := range g {
+			groups[a] = g
+		}
+	}
+
+	groupsCache[manager] = groups
+	return groups, nil
+}
+
+func containsAll(set map[assetmanager.Asset]bool, as []assetmanager.Asset) bool {
+	for _, a := range as {
+		if !set[a] {
+			return false
+		}
+	}
+	return true
+}
+
+func shouldBundle(conf *config.Config, t assets.Type) (bool, error) {
+	if conf == nil || conf.Bundle == nil {
+		return false, nil
+	}
+	if _, ok := bundleTypes[t]; !ok {
+		return false, nil
+	}
+	if len(conf.Bundle.Types) == 0 {
+		return true, nil
+	}
+
+	for _, bt := range conf.Bundle.Types {
+		if _, ok := bundleTypes[assets.Type(bt)]; !ok {
+			return false, fmt.Errorf("%w %q", errInvalidBundleType, bt)
+		}
+		if assets.Type(bt) == t {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// writeBundle writes the combined contents of the assets to a file named
+// after its hash so every page using the group shares the bundle. Nil is
+// returned if the assets can't be combined safely.
+func writeBundle(runtime manipulations.Runtime, t assets.Type, media string, as []assetmanager.Asset) (assetmanager.Asset, error) {
+	isCSS := t == assets.SyncCSS || t == assets.AsyncCSS
+
+	contents := []string{}
+	for _, a := range as {
+		c, err := a.Contents()
+		if err != nil {
+			return nil, err
+		}
+		c = minify.StripSourceMapComments(c)
+
+		if isCSS {
+			// @import rules are only valid at the start of a stylesheet
+			if strings.Contains(c, "@import") {
+				return nil, nil
+			}
+
+			// The bundle lives in a different directory so relative
+			// references have to be made absolute
+			u, err := a.URL()
+			if err != nil {
+				return nil, err
+			}
+			c = css.ReplaceReferences(c, func(ref string) string {
+				if strings.HasPrefix(ref, "#") {
+					return ref
+				}
+				return css.ResolveURL(u, ref)
+			})
+		}
+		contents = append(contents, c)
+	}
+
+	separator := "\n"
+	ext := ".css"
+	if !isCSS {
+		// Guard against scripts that don't end with a semicolon or end
+		// with a line comment
+		separator = "\n;\n"
+		ext = ".js"
+	}
+	combined := strings.Join(contents, separator)
+
+	h := sha256.Sum256([]byte(combined))
+	name := fmt.Sprintf("bundle-%v%v", hex.EncodeToString(h[:])[0:7], bundleTypes[t])
+	if media != "" {
+		name += "." + media
+	}
+	name += ext
+
+	bundleMu.Lock()
+	defer bundleMu.Unlock()
+
+	dir := filepath.Join(runtime.Assets.StaticDir(), "__ham", "bundles")
+	p := filepath.Join(dir, name)
+	for _, a := range runtime.Assets.All() {
+		if la, ok := a.(*assetmanager.LocalAsset); ok && la.Path() == p {
+			return la, nil
+		}
+	}
+
+	if runtime.Debug {
+		fmt.Printf("Writing bundle %q for %v assets\n", p, len(as))
+	}
+	if err := osMkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("%w %q; %v", errBundleWriteFailed, p, err)
+	}
+	if err := ioutilWriteFile(p, []byte(combined), 0644); err != nil {
+		return nil, fmt.Errorf("%w %q; %v", errBundleWriteFailed, p, err)
+	}
+
+	la, err := assetmanager.NewLocalAsset(runtime.Assets.StaticDir(), p)
+	if err != nil {
+		return nil, err
+	}
+	runtime.Assets.AddLocal(la)
+	return la, nil
+}
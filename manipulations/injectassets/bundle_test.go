@@ -0,0 +1,271 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package injectassets
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_bundle(t *testing.T) {
+	tests := []struct {
+		description  string
+		config       *config.Config
+		pages        map[string]string
+		files        map[string]string
+		keys         []string
+		assetType    assets.Type
+		remote       []assetmanager.Asset
+		want         []string
+		wantContents map[string]string
+		wantError    error
+	}{
+		{
+			description: "do nothing without bundle config",
+			config:      &config.Config{},
+			pages: map[string]string{
+				"index.html": `<div class="a b"></div>`,
+			},
+			assetType: assets.AsyncCSS,
+			files: map[string]string{
+				"a-async.css": `a{}`,
+				"b-async.css": `b{}`,
+			},
+			keys: []string{"a", "b"},
+			want: []string{"/a-async.css", "/b-async.css"},
+		},
+		{
+			description: "do nothing for types that aren't configured",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{Types: []string{"sync-js"}},
+			},
+			pages: map[string]string{
+				"index.html": `<div class="a b"></div>`,
+			},
+			assetType: assets.AsyncCSS,
+			files: map[string]string{
+				"a-async.css": `a{}`,
+				"b-async.css": `b{}`,
+			},
+			keys: []string{"a", "b"},
+			want: []string{"/a-async.css", "/b-async.css"},
+		},
+		{
+			description: "return error for an invalid type",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{Types: []string{"inline-css"}},
+			},
+			assetType: assets.AsyncCSS,
+			files: map[string]string{
+				"a-async.css": `a{}`,
+			},
+			keys:      []string{"a"},
+			wantError: errInvalidBundleType,
+		},
+		{
+			description: "bundle CSS with the same media used on the same pages and make references absolute",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{},
+			},
+			pages: map[string]string{
+				"index.html":      `<div class="a b c d"></div>`,
+				"blog/index.html": `<div class="a b"></div>`,
+			},
+			assetType: assets.AsyncCSS,
+			files: map[string]string{
+				"css/a-async.css":       `a{background:url(../img/a.png)}`,
+				"css/b-async.css":       `b{filter:url(#f)}`,
+				"css/c-async.print.css": `c{}`,
+				"css/d-async.css":       `d{}`,
+			},
+			keys: []string{"a", "b", "c", "d"},
+			remote: []assetmanager.Asset{
+				assetmanager.NewRemoteAsset("d", "https://example.com/d-async.css", []html.Attribute{}, assets.AsyncCSS),
+			},
+			want: []string{
+				"/__ham/bundles/bundle-7a4539d-async.css",
+				"/css/c-async.print.css",
+				"/css/d-async.css",
+				"https://example.com/d-async.css",
+			},
+			wantContents: map[string]string{
+				"__ham/bundles/bundle-7a4539d-async.css": "a{background:url(/img/a.png)}\nb{filter:url(#f)}",
+			},
+		},
+		{
+			description: "do not bundle assets that are only sometimes used together",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{},
+			},
+			pages: map[string]string{
+				"index.html": `<div class="a b"></div>`,
+				"about.html": `<div class="a"></div>`,
+			},
+			assetType: assets.AsyncCSS,
+			files: map[string]string{
+				"a-async.css": `a{}`,
+				"b-async.css": `b{}`,
+			},
+			keys: []string{"a", "b"},
+			want: []string{"/a-async.css", "/b-async.css"},
+		},
+		{
+			description: "do not bundle a group that is only partly on the page",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{},
+			},
+			pages: map[string]string{
+				"index.html": `<div class="a b"></div>`,
+			},
+			assetType: assets.AsyncCSS,
+			files: map[string]string{
+				"a-async.css": `a{}`,
+				"b-async.css": `b{}`,
+			},
+			keys: []string{"a"},
+			want: []string{"/a-async.css"},
+		},
+		{
+			description: "bundle JS without source map comments",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{Types: []string{"sync-js"}},
+			},
+			pages: map[string]string{
+				"index.html": `<div class="a b"></div>`,
+			},
+			assetType: assets.SyncJS,
+			files: map[string]string{
+				"a-sync.js": "console.log(\"a\")\n//# sourceMappingURL=a-sync.js.map",
+				"b-sync.js": `(function(){console.log("b")})()`,
+			},
+			keys: []string{"a", "b"},
+			want: []string{"/__ham/bundles/bundle-8dd02c3-sync.js"},
+			wantContents: map[string]string{
+				"__ham/bundles/bundle-8dd02c3-sync.js": "console.log(\"a\")\n;\n(function(){console.log(\"b\")})()",
+			},
+		},
+		{
+			description: "do not bundle CSS with @import rules",
+			config: &config.Config{
+				Bundle: &config.BundleConfig{},
+			},
+			pages: map[string]string{
+				"index.html": `<div class="a b"></div>`,
+			},
+			assetType: assets.SyncCSS,
+			files: map[string]string{
+				"a-sync.css": `@import "b.css";`,
+				"b-sync.css": `b{}`,
+			},
+			keys: []string{"a", "b"},
+			want: []string{"/a-sync.css", "/b-sync.css"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			htmlDir := t.TempDir()
+			mustWriteFiles(t, htmlDir, tt.pages)
+			dir := t.TempDir()
+			mustWriteFiles(t, dir, tt.files)
+
+			manager, err := assetmanager.NewManager(htmlDir, dir, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			as := append(assetsOfType(manager, tt.keys, tt.assetType), tt.remote...)
+
+			runtime := manipulations.Runtime{
+				Assets: manager,
+				Config: tt.config,
+			}
+			got, err := bundle(runtime, tt.assetType, as)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+
+			// Bundling the same assets again must reuse the bundle
+			again, err := bundle(runtime, tt.assetType, as)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(assetURLs(t, again), assetURLs(t, got)); diff != "" {
+				t.Fatalf("Bundle not reused; diff %v", diff)
+			}
+			wantAssets := len(tt.pages) + len(tt.files) + len(tt.wantContents)
+			if len(manager.All()) != wantAssets {
+				t.Fatalf("Unexpected number of assets; got %v, want %v", len(manager.All()), wantAssets)
+			}
+
+			if diff := cmp.Diff(assetURLs(t, got), tt.want); diff != "" {
+				t.Fatalf("Unexpected assets; diff %v", diff)
+			}
+
+			for p, want := range tt.wantContents {
+				b, err := ioutil.ReadFile(filepath.Join(dir, p))
+				if err != nil {
+					t.Fatalf("Failed to read bundle: %v", err)
+				}
+				if diff := cmp.Diff(string(b), want); diff != "" {
+					t.Fatalf("Unexpected bundle contents; diff %v", diff)
+				}
+			}
+		})
+	}
+}
+
+func mustWriteFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for p, c := range files {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0777); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func assetURLs(t *testing.T, as []assetmanager.Asset) []string {
+	t.Helper()
+
+	urls := []string{}
+	for _, a := range as {
+		u, err := a.URL()
+		if err != nil {
+			t.Fatalf("Failed to get URL: %v", err)
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
@@ -59,6 +59,7 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 		assets.SyncJS:    addSyncJS,
 		assets.AsyncJS:   addAsyncJS,
 		assets.PreloadJS: addPreloadJS,
+		assets.ModuleJS:  addModuleJS,
 	}
 
 	assetOrder := []assets.Type{
@@ -73,26 +74,26 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 
 		assets.SyncCSS,
 		assets.SyncJS,
+
+		assets.ModuleJS,
 	}
 
 	sortedKeys := keys.Sorted()
 
 	for _, a := range assetOrder {
-		for _, k := range sortedKeys {
-			assetsByType := runtime.Assets.WithID(k)
-			assets := toArray(assetsByType)
-			for _, as := range assets {
-				if as.Type() != a {
-					continue
-				}
-
-				injector, ok := injectMap[as.Type()]
-				if ok {
-					err := injector(headNode, bodyNode, as)
-					if err != nil {
-						return err
-					}
-				}
+		typeAssets, err := bundle(runtime, a, assetsOfType(runtime.Assets, sortedKeys, a))
+		if err != nil {
+			return err
+		}
+
+		injector, ok := injectMap[a]
+		if !ok {
+			continue
+		}
+		for _, as := range typeAssets {
+			err := injector(headNode, bodyNode, as)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -100,6 +101,20 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 	return nil
 }
 
+// assetsOfType returns the assets of a type for the keys in the order they
+// are injected
+func assetsOfType(manager manipulations.AssetManager, sortedKeys []string, t assets.Type) []assetmanager.Asset {
+	typeAssets := []assetmanager.Asset{}
+	for _, k := range sortedKeys {
+		for _, as := range toArray(manager.WithID(k)) {
+			if as.Type() == t {
+				typeAssets = append(typeAssets, as)
+			}
+		}
+	}
+	return typeAssets
+}
+
 func toArray(assetsByType map[assets.Type][]assetmanager.Asset) []assetmanager.Asset {
 	ar := []assetmanager.Asset{}
 	for _, a := range assetsByType {
@@ -216,6 +231,18 @@ func addPreloadJS(headNode, bodyNode *html.Node, asset assetmanager.Asset) error
 	return nil
 }
 
+func addModuleJS(headNode, bodyNode *html.Node, asset assetmanager.Asset) error {
+	u, err := asset.URL()
+	if err != nil {
+		return err
+	}
+	bodyNode.AppendChild(htmlparsing.ModuleJSTag(htmlparsing.JSTagData{
+		URL:        u,
+		Attributes: asset.Attributes(),
+	}))
+	return nil
+}
+
 func prettyPrintKeys(debug bool, keys []string) {
 	if !debug {
 		return
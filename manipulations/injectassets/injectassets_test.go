@@ -585,6 +585,52 @@ func TestAddPreloadJS(t *testing.T) {
 	}
 }
 
+func TestAddModuleJS(t *testing.T) {
+	tests := []struct {
+		description string
+		asset       assetmanager.Asset
+		want        string
+		wantError   error
+	}{
+		{
+			description: "return error if url fails",
+			asset: &assetstubs.Asset{
+				URLError: errInjected,
+			},
+			wantError: errInjected,
+		},
+		{
+			description: "add asset to page",
+			asset: &assetstubs.Asset{
+				URLReturn: "/url-module.js",
+			},
+			want: `<html><head></head><body><script type="module" src="/url-module.js"></script></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			doc := MustGetNode(t, "")
+			head := htmlparsing.FindNodeByTag("head", doc)
+			body := htmlparsing.FindNodeByTag("body", doc)
+
+			err := addModuleJS(head, body, tt.asset)
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got := MustRenderNode(t, doc)
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Unexpected result; Diff %v", cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
 func MustGetNode(t *testing.T, input string) *html.Node {
 	t.Helper()
 
@@ -17,11 +17,15 @@
 package lazyload
 
 import (
+	"strings"
+
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
 	"golang.org/x/net/html"
 )
 
+// Manipulator lazy loads images and iframes without a loading attribute and
+// optionally adds "auto" to the sizes of lazy images.
 func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 	allElements := getElementsToLazyLoad(doc)
 	for _, ele := range allElements {
@@ -38,9 +42,44 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 			Val: "lazy",
 		})
 	}
+
+	if runtime.Config != nil && runtime.Config.LazyLoad != nil && runtime.Config.LazyLoad.AutoSizes {
+		for _, img := range htmlparsing.FindNodesByTag("img", doc) {
+			if strings.EqualFold(htmlparsing.Attributes(img)["loading"].Val, "lazy") {
+				addAutoSizes(img)
+			}
+		}
+	}
 	return nil
 }
 
+// addAutoSizes adds "auto" to the sizes of a lazy img and the sources of its
+// picture, keeping the existing sizes for browsers without support. Only
+// lazy images can use auto as their layout is known before they load.
+func addAutoSizes(img *html.Node) {
+	els := []*html.Node{img}
+	if p := img.Parent; p != nil && p.Type == html.ElementNode && p.Data == "picture" {
+		for c := p.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "source" {
+				els = append(els, c)
+			}
+		}
+	}
+
+	for _, e := range els {
+		for i, a := range e.Attr {
+			if a.Key != "sizes" || a.Val == "" {
+				continue
+			}
+			first := strings.TrimSpace(strings.Split(a.Val, ",")[0])
+			if strings.EqualFold(first, "auto") {
+				continue
+			}
+			e.Attr[i].Val = "auto, " + a.Val
+		}
+	}
+}
+
 func getElementsToLazyLoad(doc *html.Node) []*html.Node {
 	tags := []string{
 		"iframe",
@@ -23,11 +23,18 @@ import (
 	"testing"
 
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/net/html"
 )
 
 func Test_Manipulator(t *testing.T) {
+	autoSizesRuntime := manipulations.Runtime{
+		Config: &config.Config{
+			LazyLoad: &config.LazyLoadConfig{AutoSizes: true},
+		},
+	}
+
 	tests := []struct {
 		description string
 		runtime     manipulations.Runtime
@@ -55,6 +62,23 @@ func Test_Manipulator(t *testing.T) {
 			doc:         MustGetNode(t, `<iframe src="/example.jpg"></iframe>`),
 			want:        `<html><head></head><body><iframe src="/example.jpg" loading="lazy"></iframe></body></html>`,
 		},
+		{
+			description: "do not add auto sizes unless configured",
+			doc:         MustGetNode(t, `<img src="/example.jpg" sizes="100vw"/>`),
+			want:        `<html><head></head><body><img src="/example.jpg" sizes="100vw" loading="lazy"/></body></html>`,
+		},
+		{
+			description: "add auto sizes to lazy images and their sources",
+			runtime:     autoSizesRuntime,
+			doc:         MustGetNode(t, `<picture><source type="image/webp" sizes="(min-width: 800px) 800px,100vw" srcset="/example-800.webp 800w"/><img src="/example.jpg" sizes="(min-width: 800px) 800px,100vw" srcset="/example-800.jpg 800w"/></picture><img src="/other.jpg" sizes="auto, 50vw"/><img src="/no-sizes.jpg"/>`),
+			want:        `<html><head></head><body><picture><source type="image/webp" sizes="auto, (min-width: 800px) 800px,100vw" srcset="/example-800.webp 800w"/><img src="/example.jpg" sizes="auto, (min-width: 800px) 800px,100vw" srcset="/example-800.jpg 800w" loading="lazy"/></picture><img src="/other.jpg" sizes="auto, 50vw" loading="lazy"/><img src="/no-sizes.jpg" loading="lazy"/></body></html>`,
+		},
+		{
+			description: "do not add auto sizes to eager images",
+			runtime:     autoSizesRuntime,
+			doc:         MustGetNode(t, `<img src="/example.jpg" sizes="100vw" loading="eager"/>`),
+			want:        `<html><head></head><body><img src="/example.jpg" sizes="100vw" loading="eager"/></body></html>`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,148 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package lcpimage
+
+import (
+	"fmt"
+
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultMainImageCount = 1
+)
+
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if runtime.Config == nil || runtime.Config.LCPImage == nil {
+		return nil
+	}
+
+	imgs := aboveTheFoldImages(runtime.Config.LCPImage, doc)
+	if len(imgs) == 0 {
+		if runtime.Debug {
+			fmt.Printf("No LCP image candidates found\n")
+		}
+		return nil
+	}
+
+	for _, img := range imgs {
+		setAttribute(img, "loading", "eager")
+	}
+
+	// The first image is treated as the LCP element
+	lcp := imgs[0]
+	setAttribute(lcp, "fetchpriority", "high")
+
+	headNode := htmlparsing.FindNodeByTag("head", doc)
+	if headNode == nil {
+		return nil
+	}
+
+	preload, ok := preloadData(lcp)
+	if !ok || hasPreload(headNode, preload) {
+		return nil
+	}
+	headNode.AppendChild(htmlparsing.PreloadImageTag(preload))
+	return nil
+}
+
+func aboveTheFoldImages(conf *config.LCPImageConfig, doc *html.Node) []*html.Node {
+	for _, s := range conf.Selectors {
+		rawElements := htmlparsing.FindNodesByTag(s, doc)
+		rawElements = append(rawElements, htmlparsing.FindNodesByClassname(s, doc)...)
+		for _, e := range rawElements {
+			if img := htmlparsing.FindNodeByTag("img", e); img != nil {
+				return []*html.Node{img}
+			}
+		}
+	}
+
+	main := htmlparsing.FindNodeByTag("main", doc)
+	if main == nil {
+		return nil
+	}
+
+	count := conf.MainImageCount
+	if count <= 0 {
+		count = defaultMainImageCount
+	}
+
+	imgs := htmlparsing.FindNodesByTag("img", main)
+	if len(imgs) > count {
+		imgs = imgs[:count]
+	}
+	return imgs
+}
+
+func preloadData(img *html.Node) (htmlparsing.ImagePreloadData, bool) {
+	// Prefer the first, and most preferred, source of a picture element
+	if img.Parent != nil && img.Parent.Type == html.ElementNode && img.Parent.Data == "picture" {
+		source := htmlparsing.FindNodeByTag("source", img.Parent)
+		if source != nil {
+			attrs := htmlparsing.Attributes(source)
+			if attrs["srcset"].Val != "" {
+				return htmlparsing.ImagePreloadData{
+					Srcset: attrs["srcset"].Val,
+					Sizes:  attrs["sizes"].Val,
+					Type:   attrs["type"].Val,
+				}, true
+			}
+		}
+	}
+
+	attrs := htmlparsing.Attributes(img)
+	if attrs["src"].Val == "" && attrs["srcset"].Val == "" {
+		return htmlparsing.ImagePreloadData{}, false
+	}
+	return htmlparsing.ImagePreloadData{
+		URL:    attrs["src"].Val,
+		Srcset: attrs["srcset"].Val,
+		Sizes:  attrs["sizes"].Val,
+	}, true
+}
+
+func hasPreload(headNode *html.Node, preload htmlparsing.ImagePreloadData) bool {
+	for _, l := range htmlparsing.FindNodesByTag("link", headNode) {
+		attrs := htmlparsing.Attributes(l)
+		if attrs["rel"].Val != "preload" || attrs["as"].Val != "image" {
+			continue
+		}
+		if preload.URL != "" && attrs["href"].Val == preload.URL {
+			return true
+		}
+		if preload.Srcset != "" && attrs["imagesrcset"].Val == preload.Srcset {
+			return true
+		}
+	}
+	return false
+}
+
+func setAttribute(ele *html.Node, key, val string) {
+	for i, a := range ele.Attr {
+		if a.Key == key {
+			ele.Attr[i].Val = val
+			return
+		}
+	}
+	ele.Attr = append(ele.Attr, html.Attribute{
+		Key: key,
+		Val: val,
+	})
+}
@@ -0,0 +1,148 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package lcpimage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_Manipulator(t *testing.T) {
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without config",
+			doc:         MustGetNode(t, `<main><img src="/example.jpg"/></main>`),
+			want:        `<html><head></head><body><main><img src="/example.jpg"/></main></body></html>`,
+		},
+		{
+			description: "do nothing if no candidates exist",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					LCPImage: &config.LCPImageConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="/example.jpg"/>`),
+			want: `<html><head></head><body><img src="/example.jpg"/></body></html>`,
+		},
+		{
+			description: "prioritize first image in main by default",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					LCPImage: &config.LCPImageConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="/logo.jpg"/><main><img src="/hero.jpg"/><img src="/second.jpg"/></main>`),
+			want: `<html><head><link rel="preload" as="image" href="/hero.jpg" fetchpriority="high"/></head><body><img src="/logo.jpg"/><main><img src="/hero.jpg" loading="eager" fetchpriority="high"/><img src="/second.jpg"/></main></body></html>`,
+		},
+		{
+			description: "eagerly load the first N images in main",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					LCPImage: &config.LCPImageConfig{
+						MainImageCount: 2,
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<main><img src="/hero.jpg" loading="lazy"/><img src="/second.jpg"/><img src="/third.jpg"/></main>`),
+			want: `<html><head><link rel="preload" as="image" href="/hero.jpg" fetchpriority="high"/></head><body><main><img src="/hero.jpg" loading="eager" fetchpriority="high"/><img src="/second.jpg" loading="eager"/><img src="/third.jpg"/></main></body></html>`,
+		},
+		{
+			description: "prioritize first image matching a selector",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					LCPImage: &config.LCPImageConfig{
+						Selectors: []string{"missing", "c-hero"},
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<main><img src="/first.jpg"/><div class="c-hero"><img src="/hero.jpg" srcset="/hero-1.jpg 100w" sizes="100vw"/></div></main>`),
+			want: `<html><head><link rel="preload" as="image" href="/hero.jpg" imagesrcset="/hero-1.jpg 100w" imagesizes="100vw" fetchpriority="high"/></head><body><main><img src="/first.jpg"/><div class="c-hero"><img src="/hero.jpg" srcset="/hero-1.jpg 100w" sizes="100vw" loading="eager" fetchpriority="high"/></div></main></body></html>`,
+		},
+		{
+			description: "preload the preferred picture source",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					LCPImage: &config.LCPImageConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<main><picture><source type="image/avif" srcset="/hero.avif 100w" sizes="100vw"/><source srcset="/hero.jpg 100w" sizes="100vw"/><img src="/hero.jpg"/></picture></main>`),
+			want: `<html><head><link rel="preload" as="image" imagesrcset="/hero.avif 100w" imagesizes="100vw" type="image/avif" fetchpriority="high"/></head><body><main><picture><source type="image/avif" srcset="/hero.avif 100w" sizes="100vw"/><source srcset="/hero.jpg 100w" sizes="100vw"/><img src="/hero.jpg" loading="eager" fetchpriority="high"/></picture></main></body></html>`,
+		},
+		{
+			description: "do not duplicate an existing preload",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					LCPImage: &config.LCPImageConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<head><link rel="preload" as="image" href="/hero.jpg"/></head><main><img src="/hero.jpg"/></main>`),
+			want: `<html><head><link rel="preload" as="image" href="/hero.jpg"/></head><body><main><img src="/hero.jpg" loading="eager" fetchpriority="high"/></main></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
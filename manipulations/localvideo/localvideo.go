@@ -0,0 +1,230 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package localvideo
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/genimgs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/video"
+	"golang.org/x/net/html"
+)
+
+var (
+	osStat             = os.Stat
+	videoProbe         = video.Probe
+	genimgsLookupSizes = genimgs.LookupSizes
+
+	// Videos are probed once for every page using them
+	probeCache sync.Map
+)
+
+type probeResult struct {
+	info video.Info
+	err  error
+}
+
+// Manipulator defers loading local videos, sizes them from their stream
+// metadata and adds the poster frame extracted by genimgs. With fallback
+// enabled the poster is also added as an img for browsers that can't play
+// the video.
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if !shouldRun(runtime.Config) {
+		return nil
+	}
+
+	for _, ele := range htmlparsing.FindNodesByTag("video", doc) {
+		manipulateVideo(runtime, ele)
+	}
+	return nil
+}
+
+func shouldRun(conf *config.Config) bool {
+	if conf == nil || conf.Video == nil {
+		return false
+	}
+	return conf.Assets != nil && conf.Assets.StaticDir != ""
+}
+
+func manipulateVideo(runtime manipulations.Runtime, ele *html.Node) {
+	src := videoSrc(ele)
+	if !isLocal(src) {
+		if runtime.Debug && src != "" {
+			fmt.Printf("Skipping video with abs URL %q\n", src)
+		}
+		return
+	}
+
+	srcPath := urlPath(src)
+	videoPath := filepath.Join(runtime.Config.Assets.StaticDir, srcPath)
+	if _, err := osStat(videoPath); err != nil {
+		if runtime.Debug {
+			fmt.Printf("Skipping video that isn't in the static dir %q\n", src)
+		}
+		return
+	}
+
+	attributes := htmlparsing.Attributes(ele)
+
+	// Autoplaying videos load regardless of preload
+	_, hasPreload := attributes["preload"]
+	_, hasAutoplay := attributes["autoplay"]
+	if !hasPreload && !hasAutoplay {
+		attributes["preload"] = html.Attribute{Key: "preload", Val: "none"}
+	}
+
+	info, err := probe(videoPath)
+	if err != nil {
+		log.Printf("Warning: Unable to read the size of video %q: %v", src, err)
+	} else {
+		// The aspect ratio is only added with the size so it can't conflict
+		// with a size set by the page
+		_, hasWidth := attributes["width"]
+		_, hasHeight := attributes["height"]
+		if !hasWidth && !hasHeight {
+			attributes["width"] = html.Attribute{Key: "width", Val: fmt.Sprintf("%v", info.Width)}
+			attributes["height"] = html.Attribute{Key: "height", Val: fmt.Sprintf("%v", info.Height)}
+
+			style := strings.TrimSpace(attributes["style"].Val)
+			if !strings.Contains(style, "aspect-ratio") {
+				if style != "" && !strings.HasSuffix(style, ";") {
+					style += ";"
+				}
+				ratio := fmt.Sprintf("aspect-ratio: %v / %v;", info.Width, info.Height)
+				attributes["style"] = html.Attribute{Key: "style", Val: strings.TrimSpace(style + " " + ratio)}
+			}
+		}
+	}
+
+	poster, hasPoster := attributes["poster"]
+	if !hasPoster {
+		posterPath := video.PosterPath(srcPath)
+		if _, err := osStat(filepath.Join(runtime.Config.Assets.StaticDir, posterPath)); err != nil {
+			log.Printf("Warning: No poster for video %q, run genimgs to create %q", src, posterPath)
+		} else {
+			poster = html.Attribute{Key: "poster", Val: posterURL(runtime, posterPath, info.Width)}
+			attributes["poster"] = poster
+		}
+	}
+
+	ele.Attr = htmlparsing.AttributesList(attributes)
+
+	if runtime.Config.Video.Fallback && poster.Val != "" && !hasFallback(ele) {
+		ele.AppendChild(&html.Node{
+			Type: html.ElementNode,
+			Data: "img",
+			Attr: []html.Attribute{
+				{Key: "src", Val: poster.Val},
+				{Key: "alt", Val: fallbackAlt(attributes)},
+			},
+		})
+	}
+}
+
+// videoSrc returns the src of the video or its first source
+func videoSrc(ele *html.Node) string {
+	if src := htmlparsing.Attributes(ele)["src"].Val; src != "" {
+		return src
+	}
+	for c := ele.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "source" {
+			return htmlparsing.Attributes(c)["src"].Val
+		}
+	}
+	return ""
+}
+
+func isLocal(src string) bool {
+	return strings.HasPrefix(src, "/") && !strings.HasPrefix(src, "//")
+}
+
+func urlPath(src string) string {
+	if u, err := url.Parse(src); err == nil {
+		return u.Path
+	}
+	return src
+}
+
+func probe(p string) (video.Info, error) {
+	if r, ok := probeCache.Load(p); ok {
+		return r.(probeResult).info, r.(probeResult).err
+	}
+	info, err := videoProbe(p)
+	probeCache.Store(p, probeResult{info: info, err: err})
+	return info, err
+}
+
+// posterURL returns the smallest generated size of the poster at least as
+// wide as the video, the largest when none are or the video width is unknown,
+// or the poster itself when there are no generated sizes. Only the original format is used as the
+// poster attribute can't offer a choice of formats.
+func posterURL(runtime manipulations.Runtime, posterPath string, width int) string {
+	if runtime.Config.GenAssets == nil {
+		return posterPath
+	}
+
+	sizes, err := genimgsLookupSizes(runtime.S3, runtime.Config, posterPath)
+	if err != nil {
+		log.Printf("Warning: Unable to find generated sizes for %q: %v", posterPath, err)
+		return posterPath
+	}
+	imgs := genimgs.GroupByType(sizes)[""]
+	if len(imgs) == 0 {
+		return posterPath
+	}
+
+	sort.Slice(imgs, func(i, j int) bool {
+		return imgs[i].Size < imgs[j].Size
+	})
+	for _, i := range imgs {
+		if width > 0 && i.Size >= int64(width) {
+			return i.URL
+		}
+	}
+	return imgs[len(imgs)-1].URL
+}
+
+// hasFallback returns true if the video has content other than sources and
+// tracks
+func hasFallback(ele *html.Node) bool {
+	for c := ele.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data != "source" && c.Data != "track" {
+			return true
+		}
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func fallbackAlt(attributes map[string]html.Attribute) string {
+	if a := attributes["aria-label"].Val; a != "" {
+		return a
+	}
+	return attributes["title"].Val
+}
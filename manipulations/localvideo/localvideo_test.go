@@ -0,0 +1,213 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package localvideo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/genimgs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/video"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+func Test_Manipulator(t *testing.T) {
+	conf := &config.Config{
+		Assets: &config.AssetsConfig{StaticDir: "/static"},
+		Video:  &config.VideoConfig{},
+	}
+	fallbackConf := &config.Config{
+		Assets: &config.AssetsConfig{StaticDir: "/static"},
+		Video:  &config.VideoConfig{Fallback: true},
+	}
+	genConf := &config.Config{
+		Assets:    &config.AssetsConfig{StaticDir: "/static"},
+		GenAssets: &config.GeneratedImagesConfig{},
+		Video:     &config.VideoConfig{},
+	}
+	probe1080p := func(string) (video.Info, error) {
+		return video.Info{Width: 1920, Height: 1080}, nil
+	}
+
+	tests := []struct {
+		description        string
+		conf               *config.Config
+		doc                string
+		osStat             func(string) (os.FileInfo, error)
+		videoProbe         func(string) (video.Info, error)
+		genimgsLookupSizes func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error)
+		want               string
+	}{
+		{
+			description: "do nothing without video config",
+			conf:        &config.Config{Assets: &config.AssetsConfig{StaticDir: "/static"}},
+			doc:         `<video src="/videos/intro.mp4"></video>`,
+			want:        `<html><head></head><body><video src="/videos/intro.mp4"></video></body></html>`,
+		},
+		{
+			description: "do nothing for remote videos",
+			conf:        conf,
+			doc:         `<video src="https://example.com/intro.mp4"></video>`,
+			want:        `<html><head></head><body><video src="https://example.com/intro.mp4"></video></body></html>`,
+		},
+		{
+			description: "do nothing for videos that aren't in the static dir",
+			conf:        conf,
+			doc:         `<video src="/videos/intro.mp4"></video>`,
+			osStat: func(string) (os.FileInfo, error) {
+				return nil, os.ErrNotExist
+			},
+			want: `<html><head></head><body><video src="/videos/intro.mp4"></video></body></html>`,
+		},
+		{
+			description: "add preload, size and the generated poster",
+			conf:        genConf,
+			doc:         `<video src="/videos/intro.mp4" controls></video>`,
+			videoProbe:  probe1080p,
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				if imgPath != "/videos/intro.poster.jpg" {
+					t.Fatalf("Unexpected poster path; got %q", imgPath)
+				}
+				return []genimgs.GenImg{
+					{URL: "/gen/intro.poster.abc/2400.jpg", Size: 2400},
+					{URL: "/gen/intro.poster.abc/400.jpg", Size: 400},
+					{URL: "/gen/intro.poster.abc/1920.webp", Size: 1920, Type: "image/webp"},
+					{URL: "/gen/intro.poster.abc/1920.jpg", Size: 1920},
+				}, nil
+			},
+			want: `<html><head></head><body><video controls="" height="1080" poster="/gen/intro.poster.abc/1920.jpg" preload="none" src="/videos/intro.mp4" style="aspect-ratio: 1920 / 1080;" width="1920"></video></body></html>`,
+		},
+		{
+			description: "keep existing attributes and use the source src",
+			conf:        conf,
+			doc:         `<video autoplay muted width="640" style="max-width: 100%" poster="/custom.jpg"><source src="/videos/intro.webm?v=1" type="video/webm"/></video>`,
+			videoProbe:  probe1080p,
+			want:        `<html><head></head><body><video autoplay="" muted="" poster="/custom.jpg" style="max-width: 100%" width="640"><source src="/videos/intro.webm?v=1" type="video/webm"/></video></body></html>`,
+		},
+		{
+			description: "add the size with the aspect ratio to the existing style",
+			conf:        conf,
+			doc:         `<video src="/videos/intro.mp4" style="max-width: 100%" poster="/custom.jpg"></video>`,
+			videoProbe:  probe1080p,
+			want:        `<html><head></head><body><video height="1080" poster="/custom.jpg" preload="none" src="/videos/intro.mp4" style="max-width: 100%; aspect-ratio: 1920 / 1080;" width="1920"></video></body></html>`,
+		},
+		{
+			description: "use the poster as is when the video can't be probed",
+			conf:        conf,
+			doc:         `<video src="/videos/intro.mp4"></video>`,
+			videoProbe: func(string) (video.Info, error) {
+				return video.Info{}, errInjected
+			},
+			want: `<html><head></head><body><video poster="/videos/intro.poster.jpg" preload="none" src="/videos/intro.mp4"></video></body></html>`,
+		},
+		{
+			description: "use the largest generated poster when the video can't be probed",
+			conf:        genConf,
+			doc:         `<video src="/videos/intro.mp4"></video>`,
+			videoProbe: func(string) (video.Info, error) {
+				return video.Info{}, errInjected
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{
+					{URL: "/gen/intro.poster.abc/400.jpg", Size: 400},
+					{URL: "/gen/intro.poster.abc/2400.jpg", Size: 2400},
+					{URL: "/gen/intro.poster.abc/1920.jpg", Size: 1920},
+				}, nil
+			},
+			want: `<html><head></head><body><video poster="/gen/intro.poster.abc/2400.jpg" preload="none" src="/videos/intro.mp4"></video></body></html>`,
+		},
+		{
+			description: "skip the poster when it hasn't been created",
+			conf:        conf,
+			doc:         `<video src="/videos/intro.mp4"></video>`,
+			osStat: func(p string) (os.FileInfo, error) {
+				if strings.HasSuffix(p, ".poster.jpg") {
+					return nil, os.ErrNotExist
+				}
+				return nil, nil
+			},
+			videoProbe: probe1080p,
+			want:       `<html><head></head><body><video height="1080" preload="none" src="/videos/intro.mp4" style="aspect-ratio: 1920 / 1080;" width="1920"></video></body></html>`,
+		},
+		{
+			description: "add the poster as a fallback",
+			conf:        fallbackConf,
+			doc:         `<video src="/videos/intro.mp4" aria-label="Product intro"><track kind="captions" src="/videos/intro.vtt"/></video><video src="/videos/other.mp4">Your browser can't play this video</video>`,
+			videoProbe:  probe1080p,
+			want:        `<html><head></head><body><video aria-label="Product intro" height="1080" poster="/videos/intro.poster.jpg" preload="none" src="/videos/intro.mp4" style="aspect-ratio: 1920 / 1080;" width="1920"><track kind="captions" src="/videos/intro.vtt"/><img src="/videos/intro.poster.jpg" alt="Product intro"/></video><video height="1080" poster="/videos/other.poster.jpg" preload="none" src="/videos/other.mp4" style="aspect-ratio: 1920 / 1080;" width="1920">Your browser can&#39;t play this video</video></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			probeCache = sync.Map{}
+			osStat = func(string) (os.FileInfo, error) {
+				return nil, nil
+			}
+			if tt.osStat != nil {
+				osStat = tt.osStat
+			}
+			videoProbe = tt.videoProbe
+			genimgsLookupSizes = tt.genimgsLookupSizes
+			t.Cleanup(func() {
+				osStat = os.Stat
+				videoProbe = video.Probe
+				genimgsLookupSizes = genimgs.LookupSizes
+			})
+
+			doc := MustGetNode(t, tt.doc)
+			err := Manipulator(manipulations.Runtime{Config: tt.conf}, doc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+	return buf.String()
+}
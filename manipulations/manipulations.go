@@ -36,19 +36,33 @@ type Runtime struct {
 	Assets AssetManager
 	Config *config.Config
 
+	// PageURL is the root relative URL of the HTML file being manipulated
+	PageURL string
+
 	HasVimeo bool
 	Vimeo    vimeoapiClient
 	S3       *s3.Client
+
+	HasRemoteImages bool
+	RemoteImages    remoteimgsClient
 }
 
 type AssetManager interface {
+	All() []assetmanager.Asset
+	StaticDir() string
 	WithID(id string) map[assets.Type][]assetmanager.Asset
+	WithType(t assets.Type) []assetmanager.Asset
+	AddLocal(a *assetmanager.LocalAsset)
 }
 
 type vimeoapiClient interface {
 	Video(videoID string) (*vimeoapi.Video, error)
 }
 
+type remoteimgsClient interface {
+	Size(url string) (int, int, error)
+}
+
 func CSSNamespace(name string) string {
 	return fmt.Sprintf("n-ham-%v", name)
 }
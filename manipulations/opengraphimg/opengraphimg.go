@@ -19,6 +19,9 @@ package opengraphimg
 import (
 	"fmt"
 	"log"
+	"math"
+	"mime"
+	"path"
 	"sort"
 	"strings"
 
@@ -33,35 +36,57 @@ const (
 )
 
 var (
-	genimgsLookupSizes = genimgs.LookupSizes
+	genimgsLookupSizes  = genimgs.LookupSizes
+	genimgsLookupSocial = genimgs.LookupSocial
+	genimgsOpen         = genimgs.Open
+
+	// Meta tags that reference a social image
+	imageTags = []metaTag{
+		{"property", "og:image"},
+		{"name", "twitter:image"},
+		{"itemprop", "image"},
+	}
 )
 
+type metaTag struct {
+	attr string
+	val  string
+}
+
+// Manipulator points social image meta tags at a generated image of a
+// suitable size and describes the og:image with its dimensions, type and alt
+// text. Pages without an og:image get one from their other social image tags,
+// the first content image or the configured default image.
 func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if runtime.Config == nil {
+		return nil
+	}
+
+	hasOG := false
+	var other *imageSource
 	els := htmlparsing.FindNodesByTag("meta", doc)
 	for _, ele := range els {
 		// Create a map of the element attributes
-		attributes := map[string]html.Attribute{}
-		for _, a := range ele.Attr {
-			attributes[a.Key] = a
-		}
+		attributes := htmlparsing.Attributes(ele)
 
-		// Bail on elements that aren't property="og:image"
-		p, ok := attributes["property"]
+		// Bail on elements that aren't social images
+		tag, ok := imageTag(attributes)
 		if !ok {
 			continue
 		}
-		if !strings.EqualFold(p.Val, "og:image") {
-			continue
+		if tag.val == "og:image" {
+			hasOG = true
 		}
 
 		c, ok := attributes["content"]
 		if !ok {
 			continue
 		}
+		original := c.Val
 
-		img, err := getSuitableImg(runtime, c.Val)
+		img, err := getSuitableImg(runtime, original)
 		if err != nil {
-			log.Printf("Warning: Unable to find suitable image for %q: %v", c.Val, err)
+			log.Printf("Warning: Unable to find suitable image for %q: %v", original, err)
 			continue
 		}
 
@@ -72,11 +97,218 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 		c.Val = fmt.Sprintf("%v%v", runtime.Config.BaseURL, img.URL)
 		attributes["content"] = c
 		ele.Attr = htmlparsing.AttributesList(attributes)
+
+		if tag.val == "og:image" {
+			setImageDetails(runtime, doc, ele, original, img, contentImageAlt(doc, original))
+		} else if other == nil {
+			other = &imageSource{original: original, img: img}
+		}
+	}
+
+	if hasOG {
+		return nil
+	}
+	if other != nil {
+		headNode := htmlparsing.FindNodeByTag("head", doc)
+		if headNode != nil {
+			appendImageTags(runtime, doc, headNode, other.original, other.img, contentImageAlt(doc, other.original))
+		}
+		return nil
+	}
+	return addImageTags(runtime, doc)
+}
+
+type imageSource struct {
+	original string
+	img      *genimgs.GenImg
+}
+
+func imageTag(attributes map[string]html.Attribute) (metaTag, bool) {
+	for _, t := range imageTags {
+		if a, ok := attributes[t.attr]; ok && strings.EqualFold(a.Val, t.val) {
+			return t, true
+		}
+	}
+	return metaTag{}, false
+}
+
+// addImageTags adds og:image and twitter:image tags for the first content
+// image if it has a suitable generated size, falling back to the default image
+func addImageTags(runtime manipulations.Runtime, doc *html.Node) error {
+	headNode := htmlparsing.FindNodeByTag("head", doc)
+	if headNode == nil {
+		return nil
+	}
+	content := htmlparsing.FindNodeByTag("main", doc)
+	if content == nil {
+		content = htmlparsing.FindNodeByTag("body", doc)
 	}
+
+	if content != nil {
+		// Only the first local image is looked up to avoid a lookup for
+		// every image on the page
+		for _, ele := range htmlparsing.FindNodesByTag("img", content) {
+			attributes := htmlparsing.Attributes(ele)
+			src := attributes["src"].Val
+			if !strings.HasPrefix(src, "/") || strings.HasPrefix(src, "//") || genimgs.IsSVG(src) {
+				continue
+			}
+
+			img, err := getSuitableImg(runtime, src)
+			if err != nil {
+				log.Printf("Warning: Unable to find suitable image for %q: %v", src, err)
+			}
+			if img != nil {
+				appendImageTags(runtime, doc, headNode, src, img, attributes["alt"].Val)
+				return nil
+			}
+			break
+		}
+	}
+
+	social := runtime.Config.Social
+	if social == nil || social.DefaultImage == "" {
+		return nil
+	}
+
+	img, err := getSuitableImg(runtime, social.DefaultImage)
+	if err != nil {
+		log.Printf("Warning: Unable to find suitable image for %q: %v", social.DefaultImage, err)
+	}
+	if img == nil {
+		// Use the default image as is
+		img = &genimgs.GenImg{URL: social.DefaultImage}
+	}
+	appendImageTags(runtime, doc, headNode, social.DefaultImage, img, social.DefaultImageAlt)
 	return nil
 }
 
+// appendImageTags adds an og:image tag, and a twitter:image tag if the page
+// doesn't have one
+func appendImageTags(runtime manipulations.Runtime, doc, headNode *html.Node, original string, img *genimgs.GenImg, alt string) {
+	hasTwitter := false
+	for _, m := range htmlparsing.FindNodesByTag("meta", doc) {
+		if t, ok := imageTag(htmlparsing.Attributes(m)); ok && t.val == "twitter:image" {
+			hasTwitter = true
+		}
+	}
+
+	u := fmt.Sprintf("%v%v", runtime.Config.BaseURL, img.URL)
+	og := metaNode("property", "og:image", u)
+	headNode.AppendChild(og)
+	setImageDetails(runtime, doc, og, original, img, alt)
+	if !hasTwitter {
+		headNode.AppendChild(metaNode("name", "twitter:image", u))
+	}
+}
+
+// setImageDetails adds or updates the og:image:width, og:image:height,
+// og:image:type and og:image:alt tags for an og:image. An existing alt is
+// kept as it was written for the page.
+func setImageDetails(runtime manipulations.Runtime, doc, og *html.Node, original string, img *genimgs.GenImg, alt string) {
+	details := []html.Attribute{}
+	if w, h, ok := imageSize(runtime, original, img); ok {
+		details = append(details,
+			html.Attribute{Key: "og:image:width", Val: fmt.Sprintf("%v", w)},
+			html.Attribute{Key: "og:image:height", Val: fmt.Sprintf("%v", h)},
+		)
+	}
+	if t := imageType(img); t != "" {
+		details = append(details, html.Attribute{Key: "og:image:type", Val: t})
+	}
+
+	existing := map[string]*html.Node{}
+	for _, m := range htmlparsing.FindNodesByTag("meta", doc) {
+		if p := htmlparsing.Attributes(m)["property"].Val; strings.HasPrefix(p, "og:image:") {
+			existing[p] = m
+		}
+	}
+
+	if _, ok := existing["og:image:alt"]; !ok && alt != "" {
+		details = append(details, html.Attribute{Key: "og:image:alt", Val: alt})
+	}
+
+	after := og
+	for _, d := range details {
+		if m, ok := existing[d.Key]; ok {
+			attributes := htmlparsing.Attributes(m)
+			attributes["content"] = html.Attribute{Key: "content", Val: d.Val}
+			m.Attr = htmlparsing.AttributesList(attributes)
+			continue
+		}
+		m := metaNode("property", d.Key, d.Val)
+		after.Parent.InsertBefore(m, after.NextSibling)
+		after = m
+	}
+}
+
+// imageSize returns the size of the generated image, using the aspect ratio
+// of the original image for the height unless it was cropped. Images that
+// weren't generated have the size of the original.
+func imageSize(runtime manipulations.Runtime, original string, img *genimgs.GenImg) (int64, int64, bool) {
+	if img.Height > 0 {
+		return img.Size, img.Height, true
+	}
+	if runtime.Config.Assets == nil || runtime.Config.Assets.StaticDir == "" {
+		return 0, 0, false
+	}
+	i, err := genimgsOpen(runtime.Config, original)
+	if err != nil {
+		log.Printf("Warning: Unable to open %q for its size: %v", original, err)
+		return 0, 0, false
+	}
+	b := i.Bounds()
+	if b.Dx() == 0 {
+		return 0, 0, false
+	}
+	if img.Size == 0 {
+		return int64(b.Dx()), int64(b.Dy()), true
+	}
+	return img.Size, int64(math.Round(float64(img.Size) * float64(b.Dy()) / float64(b.Dx()))), true
+}
+
+func imageType(img *genimgs.GenImg) string {
+	if img.Type != "" {
+		return img.Type
+	}
+	return strings.Split(mime.TypeByExtension(strings.ToLower(path.Ext(img.URL))), ";")[0]
+}
+
+// contentImageAlt returns the alt text of an img on the page using the image
+func contentImageAlt(doc *html.Node, src string) string {
+	for _, ele := range htmlparsing.FindNodesByTag("img", doc) {
+		attributes := htmlparsing.Attributes(ele)
+		if attributes["src"].Val == src && attributes["alt"].Val != "" {
+			return attributes["alt"].Val
+		}
+	}
+	return ""
+}
+
+func metaNode(attr, val, content string) *html.Node {
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "meta",
+		Attr: []html.Attribute{
+			{Key: attr, Val: val},
+			{Key: "content", Val: content},
+		},
+	}
+}
+
+// getSuitableImg returns the generated social image if there is one, else the
+// largest generated size no wider than the recommended width
 func getSuitableImg(runtime manipulations.Runtime, imgPath string) (*genimgs.GenImg, error) {
+	if runtime.Config.Social != nil {
+		img, err := genimgsLookupSocial(runtime.S3, runtime.Config, imgPath)
+		if err != nil {
+			return nil, err
+		}
+		if img != nil {
+			return img, nil
+		}
+	}
+
 	imgs, err := genimgsLookupSizes(runtime.S3, runtime.Config, imgPath)
 	if err != nil {
 		return nil, err
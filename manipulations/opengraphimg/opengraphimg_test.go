@@ -3,6 +3,7 @@ package opengraphimg
 import (
 	"bytes"
 	"errors"
+	"image"
 	"os"
 	"strings"
 	"testing"
@@ -21,9 +22,13 @@ var reset func()
 
 func TestMain(m *testing.M) {
 	origGenimgsLookupSizes := genimgsLookupSizes
+	origGenimgsLookupSocial := genimgsLookupSocial
+	origGenimgsOpen := genimgsOpen
 
 	reset = func() {
 		genimgsLookupSizes = origGenimgsLookupSizes
+		genimgsLookupSocial = origGenimgsLookupSocial
+		genimgsOpen = origGenimgsOpen
 	}
 
 	os.Exit(m.Run())
@@ -31,12 +36,16 @@ func TestMain(m *testing.M) {
 
 func TestManipulator(t *testing.T) {
 	tests := []struct {
-		description        string
-		doc                *html.Node
-		findNodes          func(tag string, node *html.Node) []*html.Node
-		genimgsLookupSizes func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error)
-		wantError          error
-		wantHTML           string
+		description         string
+		doc                 *html.Node
+		findNodes           func(tag string, node *html.Node) []*html.Node
+		genimgsLookupSizes  func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error)
+		genimgsLookupSocial func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) (*genimgs.GenImg, error)
+		genimgsOpen         func(conf *config.Config, imgPath string) (image.Image, error)
+		staticDir           string
+		social              *config.SocialConfig
+		wantError           error
+		wantHTML            string
 	}{
 		{
 			description: "do nothing when no property",
@@ -112,7 +121,7 @@ func TestManipulator(t *testing.T) {
 					},
 				}, nil
 			},
-			wantHTML: `<html><head><meta content="http://base-url.com/images/default-social.1200xabc.png" property="og:image"/></head><body></body></html>`,
+			wantHTML: `<html><head><meta content="http://base-url.com/images/default-social.1200xabc.png" property="og:image"/><meta property="og:image:type" content="image/png"/></head><body></body></html>`,
 		},
 
 		{
@@ -137,7 +146,162 @@ func TestManipulator(t *testing.T) {
 					},
 				}, nil
 			},
-			wantHTML: `<html><head><meta content="http://base-url.com/images/default-social.1200xabc.png" property="og:image"/></head><body></body></html>`,
+			wantHTML: `<html><head><meta content="http://base-url.com/images/default-social.1200xabc.png" property="og:image"/><meta property="og:image:type" content="image/png"/></head><body></body></html>`,
+		},
+		{
+			description: "update social images and og:image details keeping the existing alt",
+			findNodes:   htmlparsing.FindNodesByTag,
+			doc: MustGetNode(t, `<head>
+<meta property="og:image" content="/images/social.png">
+<meta property="og:image:width" content="10">
+<meta property="og:image:alt" content="Existing">
+<meta name="twitter:image" content="/images/social.png">
+<meta itemprop="image" content="/images/social.png">
+</head><body><img src="/images/social.png" alt="Content"></body>`),
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{
+					{
+						Size: 800,
+						URL:  "/generated/social.abc/800.png",
+					},
+				}, nil
+			},
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				if imgPath != "/images/social.png" {
+					t.Errorf("Unexpected image opened: %v", imgPath)
+				}
+				return image.NewRGBA(image.Rect(0, 0, 300, 200)), nil
+			},
+			staticDir: "/static",
+			wantHTML: `<html><head>
+<meta content="http://base-url.com/generated/social.abc/800.png" property="og:image"/><meta property="og:image:height" content="533"/><meta property="og:image:type" content="image/png"/>
+<meta content="800" property="og:image:width"/>
+<meta property="og:image:alt" content="Existing"/>
+<meta content="http://base-url.com/generated/social.abc/800.png" name="twitter:image"/>
+<meta content="http://base-url.com/generated/social.abc/800.png" itemprop="image"/>
+</head><body><img src="/images/social.png" alt="Content"/></body></html>`,
+		},
+		{
+			description: "add social images from the first local content image",
+			findNodes:   htmlparsing.FindNodesByTag,
+			doc: MustGetNode(t, `<head></head><body><header><img src="/logo.png"></header><main>
+<img src="https://example.com/remote.png">
+<img src="/icon.svg">
+<img src="/images/photo.jpg" alt="A photo">
+</main></body>`),
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				if imgPath != "/images/photo.jpg" {
+					return []genimgs.GenImg{}, nil
+				}
+				return []genimgs.GenImg{
+					{
+						Size: 1200,
+						URL:  "/generated/photo.abc/1200.jpg",
+					},
+				}, nil
+			},
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				return nil, errInjected
+			},
+			staticDir: "/static",
+			wantHTML: `<html><head><meta property="og:image" content="http://base-url.com/generated/photo.abc/1200.jpg"/><meta property="og:image:type" content="image/jpeg"/><meta property="og:image:alt" content="A photo"/><meta name="twitter:image" content="http://base-url.com/generated/photo.abc/1200.jpg"/></head><body><header><img src="/logo.png"/></header><main>
+<img src="https://example.com/remote.png"/>
+<img src="/icon.svg"/>
+<img src="/images/photo.jpg" alt="A photo"/>
+</main></body></html>`,
+		},
+		{
+			description: "only look up the first local content image",
+			findNodes:   htmlparsing.FindNodesByTag,
+			doc:         MustGetNode(t, `<head></head><body><img src="/small.png"><img src="/images/photo.jpg"></body>`),
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				if imgPath != "/small.png" {
+					return nil, errors.New("unexpected lookup")
+				}
+				return []genimgs.GenImg{}, nil
+			},
+			wantHTML: `<html><head></head><body><img src="/small.png"/><img src="/images/photo.jpg"/></body></html>`,
+		},
+		{
+			description: "add og:image from a twitter:image without duplicating it",
+			findNodes:   htmlparsing.FindNodesByTag,
+			doc:         MustGetNode(t, `<head><meta name="twitter:image" content="/images/photo.jpg"></head><body><img src="/images/other.jpg"></body>`),
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				if imgPath != "/images/photo.jpg" {
+					return nil, errors.New("unexpected lookup")
+				}
+				return []genimgs.GenImg{{Size: 800, URL: "/generated/photo.abc/800.jpg"}}, nil
+			},
+			wantHTML: `<html><head><meta content="http://base-url.com/generated/photo.abc/800.jpg" name="twitter:image"/><meta property="og:image" content="http://base-url.com/generated/photo.abc/800.jpg"/><meta property="og:image:type" content="image/jpeg"/></head><body><img src="/images/other.jpg"/></body></html>`,
+		},
+		{
+			description: "add the default image when a twitter:image has no generated size",
+			findNodes:   htmlparsing.FindNodesByTag,
+			doc:         MustGetNode(t, `<head><meta name="twitter:image" content="https://example.com/photo.jpg"></head><body></body>`),
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return nil, nil
+			},
+			genimgsLookupSocial: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) (*genimgs.GenImg, error) {
+				return nil, nil
+			},
+			social: &config.SocialConfig{
+				DefaultImage: "/images/default.png",
+			},
+			wantHTML: `<html><head><meta name="twitter:image" content="https://example.com/photo.jpg"/><meta property="og:image" content="http://base-url.com/images/default.png"/><meta property="og:image:type" content="image/png"/></head><body></body></html>`,
+		},
+		{
+			description: "prefer the generated social image",
+			doc:         MustGetNode(t, `<meta property="og:image" content="/images/portrait.png" />`),
+			genimgsLookupSocial: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) (*genimgs.GenImg, error) {
+				return &genimgs.GenImg{URL: "/generated/portrait.abc/social-1200x630.png", Size: 1200, Height: 630}, nil
+			},
+			social:   &config.SocialConfig{},
+			wantHTML: `<html><head><meta content="http://base-url.com/generated/portrait.abc/social-1200x630.png" property="og:image"/><meta property="og:image:width" content="1200"/><meta property="og:image:height" content="630"/><meta property="og:image:type" content="image/png"/></head><body></body></html>`,
+		},
+		{
+			description: "use the generated sizes when there is no social image",
+			doc:         MustGetNode(t, `<meta property="og:image" content="/images/portrait.png" />`),
+			genimgsLookupSocial: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) (*genimgs.GenImg, error) {
+				return nil, nil
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return []genimgs.GenImg{{URL: "/generated/portrait.abc/800.png", Size: 800}}, nil
+			},
+			social:   &config.SocialConfig{},
+			wantHTML: `<html><head><meta content="http://base-url.com/generated/portrait.abc/800.png" property="og:image"/><meta property="og:image:type" content="image/png"/></head><body></body></html>`,
+		},
+		{
+			description: "add the default image when there is no content image",
+			doc:         MustGetNode(t, `<html><head></head><body><p>Text</p></body></html>`),
+			genimgsLookupSocial: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) (*genimgs.GenImg, error) {
+				if imgPath != "/images/default.png" {
+					return nil, errInjected
+				}
+				return &genimgs.GenImg{URL: "/generated/default.abc/social-1200x630.png", Size: 1200, Height: 630}, nil
+			},
+			social: &config.SocialConfig{
+				DefaultImage:    "/images/default.png",
+				DefaultImageAlt: "The site logo",
+			},
+			wantHTML: `<html><head><meta property="og:image" content="http://base-url.com/generated/default.abc/social-1200x630.png"/><meta property="og:image:width" content="1200"/><meta property="og:image:height" content="630"/><meta property="og:image:type" content="image/png"/><meta property="og:image:alt" content="The site logo"/><meta name="twitter:image" content="http://base-url.com/generated/default.abc/social-1200x630.png"/></head><body><p>Text</p></body></html>`,
+		},
+		{
+			description: "add the default image as is when it wasn't generated",
+			doc:         MustGetNode(t, `<html><head></head><body><p>Text</p></body></html>`),
+			genimgsLookupSocial: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) (*genimgs.GenImg, error) {
+				return nil, nil
+			},
+			genimgsLookupSizes: func(s3 genimgs.S3ClientInterface, conf *config.Config, imgPath string) ([]genimgs.GenImg, error) {
+				return nil, nil
+			},
+			genimgsOpen: func(conf *config.Config, imgPath string) (image.Image, error) {
+				return image.NewRGBA(image.Rect(0, 0, 600, 300)), nil
+			},
+			staticDir: "/static",
+			social: &config.SocialConfig{
+				DefaultImage: "/images/default.png",
+			},
+			wantHTML: `<html><head><meta property="og:image" content="http://base-url.com/images/default.png"/><meta property="og:image:width" content="600"/><meta property="og:image:height" content="300"/><meta property="og:image:type" content="image/png"/><meta name="twitter:image" content="http://base-url.com/images/default.png"/></head><body><p>Text</p></body></html>`,
 		},
 	}
 
@@ -146,12 +310,24 @@ func TestManipulator(t *testing.T) {
 			defer reset()
 
 			genimgsLookupSizes = tt.genimgsLookupSizes
+			if tt.genimgsLookupSocial != nil {
+				genimgsLookupSocial = tt.genimgsLookupSocial
+			}
+			if tt.genimgsOpen != nil {
+				genimgsOpen = tt.genimgsOpen
+			}
 
 			r := manipulations.Runtime{
 				Config: &config.Config{
 					BaseURL: "http://base-url.com",
+					Social:  tt.social,
 				},
 			}
+			if tt.staticDir != "" {
+				r.Config.Assets = &config.AssetsConfig{
+					StaticDir: tt.staticDir,
+				}
+			}
 
 			err := Manipulator(r, tt.doc)
 			if !errors.Is(err, tt.wantError) {
@@ -169,6 +345,17 @@ func TestManipulator(t *testing.T) {
 	}
 }
 
+func TestManipulator_NoConfig(t *testing.T) {
+	doc := MustGetNode(t, `<html><head></head><body><img src="/images/photo.jpg"></body></html>`)
+	if err := Manipulator(manipulations.Runtime{}, doc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `<html><head></head><body><img src="/images/photo.jpg"/></body></html>`
+	if diff := cmp.Diff(MustRenderNode(t, doc), want); diff != "" {
+		t.Fatalf("Unexpected HTML; diff %v", diff)
+	}
+}
+
 func MustGetNode(t *testing.T, input string) *html.Node {
 	t.Helper()
 
@@ -0,0 +1,285 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package resourcehints
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultMaxPreconnect = 4
+)
+
+var (
+	// Attributes that cause the browser to fetch a URL for each element
+	fetchAttributes = map[string][]string{
+		"link":   {"href"},
+		"script": {"src"},
+		"img":    {"src", "srcset"},
+		"source": {"src", "srcset"},
+		"iframe": {"src", "data-src"},
+		"video":  {"src", "poster"},
+		"audio":  {"src"},
+	}
+
+	// Only links with these rel values fetch resources
+	fetchingRels = map[string]bool{
+		"stylesheet":    true,
+		"preload":       true,
+		"modulepreload": true,
+		"icon":          true,
+		"manifest":      true,
+	}
+)
+
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if runtime.Config == nil || runtime.Config.ResourceHints == nil {
+		return nil
+	}
+
+	headNode := htmlparsing.FindNodeByTag("head", doc)
+	if headNode == nil {
+		return nil
+	}
+
+	conf := runtime.Config.ResourceHints
+	siteOrigin := ""
+	if o, ok := origin(runtime.Config.BaseURL); ok {
+		siteOrigin = o.name
+	}
+
+	// Browsers only reuse a connection for requests with the same
+	// credentials mode, so each mode used for an origin gets its own hint
+	existing := existingHints(headNode)
+	origins := []*originUsage{}
+	seen := map[string]bool{}
+	for _, u := range fetchedURLs(doc) {
+		o, ok := origin(u.url)
+		if !ok || o.name == siteOrigin || !allowed(conf.Allow, o) {
+			continue
+		}
+
+		o.crossorigin = u.crossorigin
+		if existing[o.name] || existing[o.key()] || seen[o.key()] {
+			continue
+		}
+		seen[o.key()] = true
+		origins = append(origins, o)
+	}
+
+	if len(origins) == 0 {
+		return nil
+	}
+
+	max := conf.MaxPreconnect
+	if max <= 0 {
+		max = defaultMaxPreconnect
+	}
+
+	hints := []*html.Node{}
+	dnsPrefetched := map[string]bool{}
+	for i, o := range origins {
+		rel := "preconnect"
+		if i >= max {
+			// DNS lookups don't depend on the credentials mode
+			if dnsPrefetched[o.name] {
+				continue
+			}
+			dnsPrefetched[o.name] = true
+			rel = "dns-prefetch"
+		}
+		if runtime.Debug {
+			fmt.Printf("Adding %v hint for %q\n", rel, o.name)
+		}
+		hints = append(hints, hintTag(rel, o))
+	}
+
+	// Hints should be discovered before any other resources in the head
+	before := htmlparsing.FirstResourceNode(headNode)
+	for _, h := range hints {
+		headNode.InsertBefore(h, before)
+	}
+	return nil
+}
+
+func fetchedURLs(doc *html.Node) []fetchedURL {
+	urls := []fetchedURL{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			urls = append(urls, elementURLs(n)...)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return urls
+}
+
+func elementURLs(n *html.Node) []fetchedURL {
+	keys, ok := fetchAttributes[n.Data]
+	if !ok {
+		return nil
+	}
+
+	attrs := htmlparsing.Attributes(n)
+	if n.Data == "link" && !fetchesResource(attrs["rel"].Val) {
+		return nil
+	}
+
+	crossorigin := ""
+	if a, ok := attrs["crossorigin"]; ok {
+		crossorigin = corsMode(a.Val)
+	} else if (n.Data == "link" && attrs["as"].Val == "font") || (n.Data == "script" && attrs["type"].Val == "module") {
+		// Fonts and module scripts are always fetched in CORS mode
+		crossorigin = "anonymous"
+	}
+
+	urls := []fetchedURL{}
+	for _, k := range keys {
+		a, ok := attrs[k]
+		if !ok || a.Val == "" {
+			continue
+		}
+
+		vals := []string{a.Val}
+		if k == "srcset" {
+			vals = htmlparsing.SrcsetURLs(a.Val)
+		}
+		for _, v := range vals {
+			urls = append(urls, fetchedURL{url: v, crossorigin: crossorigin})
+		}
+	}
+	return urls
+}
+
+// corsMode returns the credentials mode of a crossorigin attribute value,
+// invalid values are treated as anonymous
+func corsMode(val string) string {
+	if strings.EqualFold(strings.TrimSpace(val), "use-credentials") {
+		return "use-credentials"
+	}
+	return "anonymous"
+}
+
+func fetchesResource(rel string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		if fetchingRels[r] {
+			return true
+		}
+	}
+	return false
+}
+
+func origin(raw string) (*originUsage, bool) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, false
+	}
+
+	return &originUsage{
+		name: fmt.Sprintf("%v://%v", u.Scheme, u.Host),
+		host: u.Host,
+	}, true
+}
+
+func allowed(allow []string, o *originUsage) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	for _, a := range allow {
+		a = strings.TrimSuffix(a, "/")
+		if a == o.name || a == o.host {
+			return true
+		}
+	}
+	return false
+}
+
+func existingHints(headNode *html.Node) map[string]bool {
+	existing := map[string]bool{}
+	for _, l := range htmlparsing.FindNodesByTag("link", headNode) {
+		attrs := htmlparsing.Attributes(l)
+		rel := strings.ToLower(attrs["rel"].Val)
+		if rel != "preconnect" && rel != "dns-prefetch" {
+			continue
+		}
+		o, ok := origin(attrs["href"].Val)
+		if !ok {
+			continue
+		}
+		if rel == "dns-prefetch" {
+			existing[o.name] = true
+			continue
+		}
+		if a, ok := attrs["crossorigin"]; ok {
+			o.crossorigin = corsMode(a.Val)
+		}
+		existing[o.key()] = true
+	}
+	return existing
+}
+
+func hintTag(rel string, o *originUsage) *html.Node {
+	attr := []html.Attribute{
+		{Key: "rel", Val: rel},
+		{Key: "href", Val: o.name},
+	}
+	switch {
+	case rel != "preconnect":
+	case o.crossorigin == "anonymous":
+		attr = append(attr, html.Attribute{Key: "crossorigin"})
+	case o.crossorigin == "use-credentials":
+		attr = append(attr, html.Attribute{Key: "crossorigin", Val: o.crossorigin})
+	}
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "link",
+		Attr: attr,
+	}
+}
+
+type fetchedURL struct {
+	url string
+	// The credentials mode, empty for no-cors requests
+	crossorigin string
+}
+
+type originUsage struct {
+	name        string
+	host        string
+	crossorigin string
+}
+
+func (o *originUsage) key() string {
+	return o.name + "|" + o.crossorigin
+}
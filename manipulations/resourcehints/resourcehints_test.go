@@ -0,0 +1,161 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package resourcehints
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_Manipulator(t *testing.T) {
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without config",
+			doc:         MustGetNode(t, `<img src="https://i.ytimg.com/vi/1/hqdefault.jpg"/>`),
+			want:        `<html><head></head><body><img src="https://i.ytimg.com/vi/1/hqdefault.jpg"/></body></html>`,
+		},
+		{
+			description: "do nothing for same origin and relative URLs",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					BaseURL:       "https://www.example.com",
+					ResourceHints: &config.ResourceHintsConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="/example.jpg"/><img src="https://www.example.com/example.jpg"/><img src="data:image/png;base64,AAAA"/>`),
+			want: `<html><head></head><body><img src="/example.jpg"/><img src="https://www.example.com/example.jpg"/><img src="data:image/png;base64,AAAA"/></body></html>`,
+		},
+		{
+			description: "add deduplicated preconnect hints before other resources",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					ResourceHints: &config.ResourceHintsConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<head><title>Example</title><link rel="stylesheet" href="https://fonts.googleapis.com/css2"/></head><body><img src="//i.ytimg.com/vi/1/hqdefault.jpg"/><img srcset="https://i.vimeocdn.com/1.jpg 100w, https://i.vimeocdn.com/2.jpg 200w"/><img src="https://i.ytimg.com/vi/2/hqdefault.jpg"/><a href="https://www.youtube.com/watch?v=1">Link</a></body>`),
+			want: `<html><head><title>Example</title><link rel="preconnect" href="https://fonts.googleapis.com"/><link rel="preconnect" href="https://i.ytimg.com"/><link rel="preconnect" href="https://i.vimeocdn.com"/><link rel="stylesheet" href="https://fonts.googleapis.com/css2"/></head><body><img src="//i.ytimg.com/vi/1/hqdefault.jpg"/><img srcset="https://i.vimeocdn.com/1.jpg 100w, https://i.vimeocdn.com/2.jpg 200w"/><img src="https://i.ytimg.com/vi/2/hqdefault.jpg"/><a href="https://www.youtube.com/watch?v=1">Link</a></body></html>`,
+		},
+		{
+			description: "use dns-prefetch once the preconnect limit is reached",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					ResourceHints: &config.ResourceHintsConfig{
+						MaxPreconnect: 1,
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="https://a.example.com/1.jpg"/><script src="https://b.example.com/1.js"></script>`),
+			want: `<html><head><link rel="preconnect" href="https://a.example.com"/><link rel="dns-prefetch" href="https://b.example.com"/></head><body><img src="https://a.example.com/1.jpg"/><script src="https://b.example.com/1.js"></script></body></html>`,
+		},
+		{
+			description: "only add hints for allowed origins",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					ResourceHints: &config.ResourceHintsConfig{
+						Allow: []string{"b.example.com", "https://c.example.com/"},
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="https://a.example.com/1.jpg"/><img src="https://b.example.com/1.jpg"/><img src="https://c.example.com/1.jpg"/>`),
+			want: `<html><head><link rel="preconnect" href="https://b.example.com"/><link rel="preconnect" href="https://c.example.com"/></head><body><img src="https://a.example.com/1.jpg"/><img src="https://b.example.com/1.jpg"/><img src="https://c.example.com/1.jpg"/></body></html>`,
+		},
+		{
+			description: "add crossorigin for font preloads and skip existing hints",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					ResourceHints: &config.ResourceHintsConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<head><link rel="dns-prefetch" href="https://a.example.com"/></head><body><img src="https://a.example.com/1.jpg"/><link rel="preload" as="font" href="https://fonts.example.com/font.woff2"/><link rel="canonical" href="https://other.example.com/"/></body>`),
+			want: `<html><head><link rel="preconnect" href="https://fonts.example.com" crossorigin=""/><link rel="dns-prefetch" href="https://a.example.com"/></head><body><img src="https://a.example.com/1.jpg"/><link rel="preload" as="font" href="https://fonts.example.com/font.woff2"/><link rel="canonical" href="https://other.example.com/"/></body></html>`,
+		},
+		{
+			description: "add a preconnect hint for each credentials mode of an origin",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					ResourceHints: &config.ResourceHintsConfig{},
+				},
+			},
+			doc:  MustGetNode(t, `<head><link rel="preconnect" href="https://c.example.com" crossorigin="anonymous"/></head><body><img src="https://a.example.com/1.jpg"/><img src="https://a.example.com/2.jpg" crossorigin/><script type="module" src="https://a.example.com/1.js"></script><script src="https://b.example.com/1.js" crossorigin="use-credentials"></script><link rel="preload" as="font" href="https://c.example.com/font.woff2"/><img src="https://c.example.com/1.jpg"/></body>`),
+			want: `<html><head><link rel="preconnect" href="https://a.example.com"/><link rel="preconnect" href="https://a.example.com" crossorigin=""/><link rel="preconnect" href="https://b.example.com" crossorigin="use-credentials"/><link rel="preconnect" href="https://c.example.com"/><link rel="preconnect" href="https://c.example.com" crossorigin="anonymous"/></head><body><img src="https://a.example.com/1.jpg"/><img src="https://a.example.com/2.jpg" crossorigin=""/><script type="module" src="https://a.example.com/1.js"></script><script src="https://b.example.com/1.js" crossorigin="use-credentials"></script><link rel="preload" as="font" href="https://c.example.com/font.woff2"/><img src="https://c.example.com/1.jpg"/></body></html>`,
+		},
+		{
+			description: "add one dns-prefetch hint per origin once the preconnect limit is reached",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					ResourceHints: &config.ResourceHintsConfig{
+						MaxPreconnect: 1,
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="https://a.example.com/1.jpg"/><img src="https://b.example.com/1.jpg"/><img src="https://b.example.com/2.jpg" crossorigin/>`),
+			want: `<html><head><link rel="preconnect" href="https://a.example.com"/><link rel="dns-prefetch" href="https://b.example.com"/></head><body><img src="https://a.example.com/1.jpg"/><img src="https://b.example.com/1.jpg"/><img src="https://b.example.com/2.jpg" crossorigin=""/></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,201 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package revisionurls
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+var (
+	urlAttributes = []string{
+		"src",
+		"href",
+		"poster",
+	}
+)
+
+// Manipulator rewrites references to local files that have been revisioned
+// by revisionassets. It runs last so other manipulations can read the
+// original files.
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	revisions, err := revisionedURLs(runtime.Assets)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	baseURL := ""
+	if runtime.Config != nil {
+		baseURL = strings.TrimSuffix(runtime.Config.BaseURL, "/")
+	}
+
+	rewrite := func(u string) string {
+		updated := rewriteURL(revisions, baseURL, runtime.PageURL, u)
+		if runtime.Debug && updated != u {
+			fmt.Printf("Rewriting %q to %q\n", u, updated)
+		}
+		return updated
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			rewriteAttributes(n, rewrite)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return nil
+}
+
+func rewriteAttributes(n *html.Node, rewrite func(string) string) {
+	isSocialImage := false
+	if n.Data == "meta" {
+		attrs := htmlparsing.Attributes(n)
+		isSocialImage = attrs["property"].Val == "og:image" || attrs["name"].Val == "twitter:image" || attrs["itemprop"].Val == "image"
+	}
+
+	for i, a := range n.Attr {
+		switch {
+		case a.Key == "srcset":
+			n.Attr[i].Val = rewriteSrcset(a.Val, rewrite)
+		case a.Key == "content" && isSocialImage:
+			n.Attr[i].Val = rewrite(a.Val)
+		case isURLAttribute(a.Key):
+			n.Attr[i].Val = rewrite(a.Val)
+		}
+	}
+}
+
+func rewriteSrcset(srcset string, rewrite func(string) string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		updated := rewrite(fields[0])
+		if updated != fields[0] {
+			candidates[i] = strings.Replace(c, fields[0], updated, 1)
+		}
+	}
+	return strings.Join(candidates, ",")
+}
+
+// rewriteURL swaps a root relative URL, an absolute URL on the sites base URL
+// or a URL relative to the page for its revisioned URL, keeping any query and
+// fragment
+func rewriteURL(revisions map[string]string, baseURL, pageURL, u string) string {
+	prefix := ""
+	p := u
+	if baseURL != "" && strings.HasPrefix(p, baseURL+"/") {
+		prefix = baseURL
+		p = strings.TrimPrefix(p, baseURL)
+	}
+
+	suffix := ""
+	if i := strings.IndexAny(p, "?#"); i != -1 {
+		p, suffix = p[:i], p[i:]
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		return rewriteRelativeURL(revisions, pageURL, p, suffix, u)
+	}
+
+	n, ok := revisions[p]
+	if !ok {
+		return u
+	}
+	return prefix + n + suffix
+}
+
+// rewriteRelativeURL resolves a document relative path against the page URL
+// to look up its revision, keeping the reference relative when the
+// revisioned file is in the same directory
+func rewriteRelativeURL(revisions map[string]string, pageURL, p, suffix, u string) string {
+	if pageURL == "" || p == "" {
+		return u
+	}
+	ref, err := url.Parse(p)
+	if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Opaque != "" {
+		return u
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return u
+	}
+
+	resolved := base.ResolveReference(ref).Path
+	n, ok := revisions[resolved]
+	if !ok {
+		return u
+	}
+	if path.Dir(n) != path.Dir(resolved) {
+		return n + suffix
+	}
+	return strings.TrimSuffix(p, path.Base(p)) + path.Base(n) + suffix
+}
+
+func isURLAttribute(key string) bool {
+	for _, k := range urlAttributes {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func revisionedURLs(manager manipulations.AssetManager) (map[string]string, error) {
+	revisions := map[string]string{}
+	for _, a := range manager.All() {
+		if !a.IsLocal() {
+			continue
+		}
+		oa, ok := a.(originalURLAsset)
+		if !ok {
+			continue
+		}
+
+		u, err := a.URL()
+		if err != nil {
+			return nil, err
+		}
+		ou, err := oa.OriginalURL()
+		if err != nil {
+			return nil, err
+		}
+		if u != ou {
+			revisions[ou] = u
+		}
+	}
+	return revisions, nil
+}
+
+type originalURLAsset interface {
+	OriginalURL() (string, error)
+}
@@ -0,0 +1,169 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package revisionurls
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetstubs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+func Test_Manipulator(t *testing.T) {
+	revisioned := &assetstubs.Manager{
+		AllReturn: []assetmanager.Asset{
+			&assetstubs.Asset{
+				URLReturn:         "/img/hero.1234.jpg",
+				OriginalURLReturn: "/img/hero.jpg",
+				IsLocalReturn:     true,
+			},
+			&assetstubs.Asset{
+				URLReturn:         "/img/hero-2x.5678.jpg",
+				OriginalURLReturn: "/img/hero-2x.jpg",
+				IsLocalReturn:     true,
+			},
+			&assetstubs.Asset{
+				URLReturn:         "/video/intro.9012.mp4",
+				OriginalURLReturn: "/video/intro.mp4",
+				IsLocalReturn:     true,
+			},
+			&assetstubs.Asset{
+				URLReturn:         "/unchanged.png",
+				OriginalURLReturn: "/unchanged.png",
+				IsLocalReturn:     true,
+			},
+			&assetstubs.Asset{
+				URLReturn: "https://example.com/remote.css",
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without revisioned assets",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{},
+			},
+			doc:  MustGetNode(t, `<img src="/img/hero.jpg"/>`),
+			want: `<html><head></head><body><img src="/img/hero.jpg"/></body></html>`,
+		},
+		{
+			description: "return error if asset url fails",
+			runtime: manipulations.Runtime{
+				Assets: &assetstubs.Manager{
+					AllReturn: []assetmanager.Asset{
+						&assetstubs.Asset{URLError: errInjected, IsLocalReturn: true},
+					},
+				},
+			},
+			doc:       MustGetNode(t, `<img src="/img/hero.jpg"/>`),
+			want:      `<html><head></head><body><img src="/img/hero.jpg"/></body></html>`,
+			wantError: errInjected,
+		},
+		{
+			description: "rewrite src, srcset, href and poster",
+			runtime: manipulations.Runtime{
+				Assets: revisioned,
+			},
+			doc:  MustGetNode(t, `<img src="/img/hero.jpg?w=1" srcset="/img/hero.jpg 1x, /img/hero-2x.jpg 2x"/><a href="/img/hero.jpg#top">Hero</a><video poster="/img/hero.jpg"><source src="/video/intro.mp4"/></video><img src="/unchanged.png"/><img src="img/hero.jpg"/>`),
+			want: `<html><head></head><body><img src="/img/hero.1234.jpg?w=1" srcset="/img/hero.1234.jpg 1x, /img/hero-2x.5678.jpg 2x"/><a href="/img/hero.1234.jpg#top">Hero</a><video poster="/img/hero.1234.jpg"><source src="/video/intro.9012.mp4"/></video><img src="/unchanged.png"/><img src="img/hero.jpg"/></body></html>`,
+		},
+		{
+			description: "rewrite urls relative to the page",
+			runtime: manipulations.Runtime{
+				Assets:  revisioned,
+				PageURL: "/blog/post.html",
+			},
+			doc:  MustGetNode(t, `<img src="../img/hero.jpg?w=1" srcset="../img/hero.jpg 1x, ../img/hero-2x.jpg 2x"/><img src="img/hero.jpg"/><a href="https://example.com/img/hero.jpg">Remote</a><a href="#top">Top</a><a href="mailto:a@example.com">Mail</a>`),
+			want: `<html><head></head><body><img src="../img/hero.1234.jpg?w=1" srcset="../img/hero.1234.jpg 1x, ../img/hero-2x.5678.jpg 2x"/><img src="img/hero.jpg"/><a href="https://example.com/img/hero.jpg">Remote</a><a href="#top">Top</a><a href="mailto:a@example.com">Mail</a></body></html>`,
+		},
+		{
+			description: "rewrite urls relative to the root page",
+			runtime: manipulations.Runtime{
+				Assets:  revisioned,
+				PageURL: "/index.html",
+			},
+			doc:  MustGetNode(t, `<img src="img/hero.jpg"/><video src="./video/intro.mp4"></video>`),
+			want: `<html><head></head><body><img src="img/hero.1234.jpg"/><video src="./video/intro.9012.mp4"></video></body></html>`,
+		},
+		{
+			description: "rewrite social images on the base url",
+			runtime: manipulations.Runtime{
+				Assets: revisioned,
+				Config: &config.Config{
+					BaseURL: "https://www.example.com/",
+				},
+			},
+			doc:  MustGetNode(t, `<head><meta property="og:image" content="https://www.example.com/img/hero.jpg"/><meta name="twitter:image" content="https://www.example.com/img/hero.jpg"/><meta itemprop="image" content="/img/hero.jpg"/><meta name="description" content="/img/hero.jpg"/></head>`),
+			want: `<html><head><meta property="og:image" content="https://www.example.com/img/hero.1234.jpg"/><meta name="twitter:image" content="https://www.example.com/img/hero.1234.jpg"/><meta itemprop="image" content="/img/hero.1234.jpg"/><meta name="description" content="/img/hero.jpg"/></head><body></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,215 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package svginline
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/genimgs"
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/svg"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	errNoSVGElement = errors.New("no svg element found")
+
+	ioutilReadFile = ioutil.ReadFile
+
+	// Attributes that only make sense on an img element
+	imgOnlyAttributes = map[string]bool{
+		"src":      true,
+		"srcset":   true,
+		"sizes":    true,
+		"alt":      true,
+		"loading":  true,
+		"decoding": true,
+	}
+
+	// Matches local references such as fill="url(#gradient)"
+	urlRefRegex = regexp.MustCompile(`url\(\s*['"]?#([^'")\s]+)['"]?\s*\)`)
+)
+
+func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
+	if !shouldRun(runtime.Config) {
+		return nil
+	}
+
+	inlined := 0
+	imgs := htmlparsing.FindNodesByTag("img", doc)
+	for _, ele := range imgs {
+		attributes := htmlparsing.Attributes(ele)
+
+		srcAttr, ok := attributes["src"]
+		if !ok || !genimgs.IsSVG(srcAttr.Val) {
+			continue
+		}
+
+		if strings.HasPrefix(srcAttr.Val, "http") || strings.HasPrefix(srcAttr.Val, "//") {
+			continue
+		}
+
+		b, err := ioutilReadFile(filepath.Join(runtime.Config.Assets.StaticDir, srcAttr.Val))
+		if err != nil {
+			fmt.Printf("Failed to read svg %q\n", srcAttr.Val)
+			continue
+		}
+
+		if int64(len(b)) > runtime.Config.SVG.InlineMaxBytes {
+			if runtime.Debug {
+				fmt.Printf("Skipping svg %q with size %v bytes\n", srcAttr.Val, len(b))
+			}
+			continue
+		}
+
+		svgNode, err := parseSVG(string(b), runtime.Config.SVG.Optimize)
+		if err != nil {
+			fmt.Printf("Failed to parse svg %q: %v\n", srcAttr.Val, err)
+			continue
+		}
+
+		// A <style> element applies to the whole page once inlined
+		if htmlparsing.FindNodeByTag("style", svgNode) != nil {
+			if runtime.Debug {
+				fmt.Printf("Skipping svg %q with a style element\n", srcAttr.Val)
+			}
+			continue
+		}
+
+		// Each inlined copy needs unique ids so references such as
+		// gradients and clip paths don't resolve to another copy.
+		inlined++
+		prefixIDs(svgNode, fmt.Sprintf("svg%v-", inlined))
+
+		svgNode.Attr = mergeAttributes(svgNode, attributes)
+		htmlparsing.SwapNodes(ele, svgNode)
+	}
+	return nil
+}
+
+func shouldRun(conf *config.Config) bool {
+	if conf == nil {
+		return false
+	}
+
+	if conf.Assets == nil || conf.Assets.StaticDir == "" {
+		return false
+	}
+
+	if conf.SVG == nil || conf.SVG.InlineMaxBytes <= 0 {
+		return false
+	}
+
+	return true
+}
+
+func parseSVG(contents string, optimize bool) (*html.Node, error) {
+	if optimize {
+		contents = svg.Optimize(contents)
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(contents), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && n.Data == "svg" {
+			return n, nil
+		}
+	}
+	return nil, errNoSVGElement
+}
+
+func prefixIDs(svgNode *html.Node, prefix string) {
+	ids := map[string]bool{}
+	walkElements(svgNode, func(n *html.Node) {
+		for i, a := range n.Attr {
+			if a.Namespace == "" && a.Key == "id" && a.Val != "" {
+				ids[a.Val] = true
+				n.Attr[i].Val = prefix + a.Val
+			}
+		}
+	})
+	if len(ids) == 0 {
+		return
+	}
+
+	walkElements(svgNode, func(n *html.Node) {
+		for i, a := range n.Attr {
+			if a.Key == "id" {
+				continue
+			}
+
+			if a.Key == "href" && strings.HasPrefix(a.Val, "#") {
+				if ids[a.Val[1:]] {
+					n.Attr[i].Val = "#" + prefix + a.Val[1:]
+				}
+				continue
+			}
+
+			n.Attr[i].Val = urlRefRegex.ReplaceAllStringFunc(a.Val, func(m string) string {
+				id := urlRefRegex.FindStringSubmatch(m)[1]
+				if !ids[id] {
+					return m
+				}
+				return fmt.Sprintf("url(#%v%v)", prefix, id)
+			})
+		}
+	})
+}
+
+func walkElements(n *html.Node, fn func(n *html.Node)) {
+	if n.Type == html.ElementNode {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, fn)
+	}
+}
+
+func mergeAttributes(svgNode *html.Node, imgAttributes map[string]html.Attribute) []html.Attribute {
+	attributes := htmlparsing.Attributes(svgNode)
+	for k, a := range imgAttributes {
+		if imgOnlyAttributes[k] {
+			continue
+		}
+		attributes[k] = a
+	}
+
+	// Keep the img alt text available to assistive technology
+	if alt, ok := imgAttributes["alt"]; ok && alt.Val != "" {
+		attributes["role"] = html.Attribute{Key: "role", Val: "img"}
+		attributes["aria-label"] = html.Attribute{Key: "aria-label", Val: alt.Val}
+	} else {
+		attributes["aria-hidden"] = html.Attribute{Key: "aria-hidden", Val: "true"}
+	}
+
+	return htmlparsing.AttributesList(attributes)
+}
@@ -0,0 +1,225 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package svginline
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+var reset func()
+
+func TestMain(m *testing.M) {
+	origReadFile := ioutilReadFile
+
+	reset = func() {
+		ioutilReadFile = origReadFile
+	}
+
+	os.Exit(m.Run())
+}
+
+func Test_Manipulator(t *testing.T) {
+	conf := func(max int64, optimize bool) *config.Config {
+		return &config.Config{
+			Assets: &config.AssetsConfig{
+				StaticDir: "/static",
+			},
+			SVG: &config.SVGConfig{
+				InlineMaxBytes: max,
+				Optimize:       optimize,
+			},
+		}
+	}
+
+	tests := []struct {
+		description string
+		runtime     manipulations.Runtime
+		doc         *html.Node
+		readFile    func(filename string) ([]byte, error)
+		want        string
+		wantError   error
+	}{
+		{
+			description: "do nothing without svg config",
+			runtime: manipulations.Runtime{
+				Config: &config.Config{
+					Assets: &config.AssetsConfig{
+						StaticDir: "/static",
+					},
+				},
+			},
+			doc:  MustGetNode(t, `<img src="/icon.svg"/>`),
+			want: `<html><head></head><body><img src="/icon.svg"/></body></html>`,
+		},
+		{
+			description: "do nothing for non-svg images",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, false),
+			},
+			doc: MustGetNode(t, `<img src="/photo.png"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				t.Errorf("unexpected read of %v", filename)
+				return nil, errInjected
+			},
+			want: `<html><head></head><body><img src="/photo.png"/></body></html>`,
+		},
+		{
+			description: "do nothing for remote svgs",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, false),
+			},
+			doc: MustGetNode(t, `<img src="https://example.com/icon.svg"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				t.Errorf("unexpected read of %v", filename)
+				return nil, errInjected
+			},
+			want: `<html><head></head><body><img src="https://example.com/icon.svg"/></body></html>`,
+		},
+		{
+			description: "do nothing if reading the svg fails",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, false),
+			},
+			doc: MustGetNode(t, `<img src="/icon.svg"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				return nil, errInjected
+			},
+			want: `<html><head></head><body><img src="/icon.svg"/></body></html>`,
+		},
+		{
+			description: "do nothing if svg is larger than the threshold",
+			runtime: manipulations.Runtime{
+				Config: conf(10, false),
+			},
+			doc: MustGetNode(t, `<img src="/icon.svg"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				return []byte(`<svg viewBox="0 0 10 10"><path d="M0 0h10v10H0z"></path></svg>`), nil
+			},
+			want: `<html><head></head><body><img src="/icon.svg"/></body></html>`,
+		},
+		{
+			description: "inline decorative svg",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, false),
+			},
+			doc: MustGetNode(t, `<img src="/icon.svg" alt="" class="icon" width="10" height="10" loading="lazy"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				wantFile := "/static/icon.svg"
+				if filename != wantFile {
+					t.Errorf("unexpected filename; got %v, want %v", filename, wantFile)
+				}
+				return []byte(`<svg viewBox="0 0 10 10"><path d="M0 0h10v10H0z"></path></svg>`), nil
+			},
+			want: `<html><head></head><body><svg aria-hidden="true" class="icon" height="10" viewBox="0 0 10 10" width="10"><path d="M0 0h10v10H0z"></path></svg></body></html>`,
+		},
+		{
+			description: "inline optimized svg with alt text",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, true),
+			},
+			doc: MustGetNode(t, `<img src="/icon.svg" alt="Example"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				return []byte(`<?xml version="1.0"?>
+<!-- Generator -->
+<svg viewBox="0 0 10 10">
+	<metadata>Example</metadata>
+	<path d="M0 0h10v10H0z"></path>
+</svg>`), nil
+			},
+			want: `<html><head></head><body><svg aria-label="Example" role="img" viewBox="0 0 10 10"><path d="M0 0h10v10H0z"></path></svg></body></html>`,
+		},
+		{
+			description: "do nothing for svgs with a style element",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, false),
+			},
+			doc: MustGetNode(t, `<img src="/icon.svg"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				return []byte(`<svg viewBox="0 0 10 10"><style>path { fill: red; }</style><path d="M0 0h10v10H0z"></path></svg>`), nil
+			},
+			want: `<html><head></head><body><img src="/icon.svg"/></body></html>`,
+		},
+		{
+			description: "prefix ids for each inlined svg",
+			runtime: manipulations.Runtime{
+				Config: conf(1024, false),
+			},
+			doc: MustGetNode(t, `<img src="/icon.svg"/><img src="/icon.svg" id="second"/>`),
+			readFile: func(filename string) ([]byte, error) {
+				return []byte(`<svg viewBox="0 0 10 10"><defs><linearGradient id="g"></linearGradient><clipPath id="c"></clipPath></defs><path d="M0 0h10v10H0z" fill="url(#g)" clip-path="url('#c')" stroke="url(#other)"></path><use xlink:href="#g"></use><use href="#other"></use></svg>`), nil
+			},
+			want: `<html><head></head><body>` +
+				`<svg aria-hidden="true" viewBox="0 0 10 10"><defs><linearGradient id="svg1-g"></linearGradient><clipPath id="svg1-c"></clipPath></defs><path d="M0 0h10v10H0z" fill="url(#svg1-g)" clip-path="url(#svg1-c)" stroke="url(#other)"></path><use xlink:href="#svg1-g"></use><use href="#other"></use></svg>` +
+				`<svg aria-hidden="true" id="second" viewBox="0 0 10 10"><defs><linearGradient id="svg2-g"></linearGradient><clipPath id="svg2-c"></clipPath></defs><path d="M0 0h10v10H0z" fill="url(#svg2-g)" clip-path="url(#svg2-c)" stroke="url(#other)"></path><use xlink:href="#svg2-g"></use><use href="#other"></use></svg>` +
+				`</body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Cleanup(reset)
+
+			ioutilReadFile = tt.readFile
+
+			err := Manipulator(tt.runtime, tt.doc)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if diff := cmp.Diff(MustRenderNode(t, tt.doc), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func MustRenderNode(t *testing.T, n *html.Node) string {
+	t.Helper()
+
+	if n == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		t.Fatalf("failed to render html node to string: %v", err)
+	}
+
+	return buf.String()
+}
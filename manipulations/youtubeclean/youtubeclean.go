@@ -20,7 +20,10 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gauntface/go-html-asset-manager/v5/manipulations"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
@@ -32,13 +35,32 @@ import (
 const (
 	defaultWidth  int64 = 4
 	defaultHeight int64 = 3
+
+	shortsWidth  int64 = 9
+	shortsHeight int64 = 16
+
+	shortURLHost = "youtu.be"
 )
 
 var (
-	embedRegex = regexp.MustCompile(`/embed/(.*).*`)
+	embedRegex    = regexp.MustCompile(`^/(embed|shorts)/([^/]+)`)
+	shortURLRegex = regexp.MustCompile(`^/([^/]+)$`)
+
+	youtubeHosts = map[string]bool{
+		"youtube.com":              true,
+		"www.youtube.com":          true,
+		"m.youtube.com":            true,
+		"youtube-nocookie.com":     true,
+		"www.youtube-nocookie.com": true,
+	}
 
 	supportedParams = map[string]bool{
-		"list": true,
+		"list":           true,
+		"start":          true,
+		"end":            true,
+		"loop":           true,
+		"playlist":       true,
+		"cc_load_policy": true,
 	}
 )
 
@@ -62,27 +84,56 @@ func Manipulator(runtime manipulations.Runtime, doc *html.Node) error {
 			continue
 		}
 
-		if u.Host == "" {
-			if !strings.Contains(u.Path, "www.youtube.com") {
-				continue
-			}
-		} else if u.Host != "www.youtube.com" {
+		videoID, shorts, ok := parseVideo(u)
+		if !ok {
 			continue
 		}
 
-		matches := embedRegex.FindStringSubmatch(u.Path)
-		if len(matches) == 0 {
-			continue
+		w, h := defaultWidth, defaultHeight
+		if shorts {
+			w, h = shortsWidth, shortsHeight
 		}
 
-		ytElement := ytElement(matches[1], queryParams(u.Query()))
+		ytElement := ytElement(videoID, queryParams(u.Query()), w, h)
 		htmlparsing.SwapNodes(ele, ytElement)
 	}
 	return nil
 }
 
+// parseVideo returns the video ID for embed, shorts and youtu.be URLs and
+// whether the video is a Short.
+func parseVideo(u *url.URL) (string, bool, bool) {
+	host, p := strings.ToLower(u.Host), u.Path
+	if host == "" {
+		// URLs without a protocol have the host as part of the path
+		parts := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+		if len(parts) != 2 {
+			return "", false, false
+		}
+		host, p = strings.ToLower(parts[0]), "/"+parts[1]
+	}
+
+	if host == shortURLHost {
+		matches := shortURLRegex.FindStringSubmatch(p)
+		if len(matches) == 0 {
+			return "", false, false
+		}
+		return matches[1], false, true
+	}
+
+	if !youtubeHosts[host] {
+		return "", false, false
+	}
+
+	matches := embedRegex.FindStringSubmatch(p)
+	if len(matches) == 0 {
+		return "", false, false
+	}
+	return matches[2], matches[1] == "shorts", true
+}
+
 // H/T to @paulirish for the idea via https://github.com/paulirish/lite-youtube-embed
-func ytElement(videoID string, params url.Values) *html.Node {
+func ytElement(videoID string, params url.Values, width, height int64) *html.Node {
 	posterImg := &html.Node{
 		Type: html.ElementNode,
 		Data: "img",
@@ -148,7 +199,7 @@ func ytElement(videoID string, params url.Values) *html.Node {
 	}
 	container.AppendChild(anchor)
 
-	ratiostyles.AddAspectRatio(container, defaultWidth, defaultHeight)
+	ratiostyles.AddAspectRatio(container, width, height)
 
 	return container
 }
@@ -162,13 +213,41 @@ func queryParams(params url.Values) url.Values {
 		// Use the last value
 		n[k] = v
 	}
+
+	// The embed player only understands start, so convert share links
+	// timestamps such as t=1m30s to seconds
+	if _, ok := n["start"]; !ok {
+		if s, ok := timestampSeconds(params.Get("t")); ok {
+			n.Set("start", s)
+		}
+	}
 	return n
 }
 
+func timestampSeconds(t string) (string, bool) {
+	if t == "" {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(t, 10, 64); err == nil {
+		return t, true
+	}
+	d, err := time.ParseDuration(t)
+	if err != nil || d < 0 {
+		return "", false
+	}
+	return strconv.FormatInt(int64(d.Seconds()), 10), true
+}
+
 func paramsString(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	pairs := []string{}
-	for k, vs := range params {
-		for _, v := range vs {
+	for _, k := range keys {
+		for _, v := range params[k] {
 			pairs = append(pairs, fmt.Sprintf("%v=%v", k, v))
 		}
 	}
@@ -80,6 +80,66 @@ func Test_Manipulator(t *testing.T) {
 			doc:         MustGetNode(t, `<iframe src="http://www.youtube.com/embed/1234-abcd?list=xyz-5678&random=searchparam" iframeborder="0" other="test"></iframe>`),
 			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" videoparams="list=xyz-5678" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd&amp;list=xyz-5678" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
 		},
+		{
+			description: "clean youtube-nocookie embed",
+			doc:         MustGetNode(t, `<iframe src="https://www.youtube-nocookie.com/embed/1234-abcd" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "clean bare youtube.com embed",
+			doc:         MustGetNode(t, `<iframe src="https://youtube.com/embed/1234-abcd" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "clean youtu.be URL without protocol",
+			doc:         MustGetNode(t, `<iframe src="youtu.be/1234-abcd?si=share" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "clean youtu.be URL with protocol",
+			doc:         MustGetNode(t, `<iframe src="https://youtu.be/1234-abcd" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "use a vertical ratio for shorts",
+			doc:         MustGetNode(t, `<iframe src="https://www.youtube.com/shorts/1234-abcd" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" style="aspect-ratio: auto 9 / 16"><a href="https://www.youtube.com/watch?v=1234-abcd" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "preserve timing, loop and caption params",
+			doc:         MustGetNode(t, `<iframe src="https://www.youtube.com/embed/1234-abcd?start=30&end=60&loop=1&playlist=1234-abcd&cc_load_policy=1&random=searchparam" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" videoparams="cc_load_policy=1&amp;end=60&amp;loop=1&amp;playlist=1234-abcd&amp;start=30" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd&amp;cc_load_policy=1&amp;end=60&amp;loop=1&amp;playlist=1234-abcd&amp;start=30" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "convert t timestamp to start",
+			doc:         MustGetNode(t, `<iframe src="https://youtu.be/1234-abcd?t=1m30s" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" videoparams="start=90" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd&amp;start=90" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "convert t seconds to start",
+			doc:         MustGetNode(t, `<iframe src="https://youtu.be/1234-abcd?t=45" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" videoparams="start=45" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd&amp;start=45" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "prefer start over t",
+			doc:         MustGetNode(t, `<iframe src="https://www.youtube.com/embed/1234-abcd?t=45&start=10" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" videoparams="start=10" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd&amp;start=10" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "drop invalid t timestamp",
+			doc:         MustGetNode(t, `<iframe src="https://youtu.be/1234-abcd?t=soon" iframeborder="0"></iframe>`),
+			want:        `<html><head></head><body><div class="n-ham-c-lite-yt" videoid="1234-abcd" style="aspect-ratio: auto 4 / 3"><a href="https://www.youtube.com/watch?v=1234-abcd" class="n-ham-c-lite-yt__link" target="_blank"><img src="https://i.ytimg.com/vi/1234-abcd/hqdefault.jpg" style="width: 100%; height: 100%; object-fit: contain;"/></a></div></body></html>`,
+		},
+		{
+			description: "do nothing for youtu.be URL with nested path",
+			doc:         MustGetNode(t, `<iframe src="https://youtu.be/1234-abcd/other"></iframe>`),
+			want:        `<html><head></head><body><iframe src="https://youtu.be/1234-abcd/other"></iframe></body></html>`,
+		},
+		{
+			description: "do nothing for lookalike youtube host",
+			doc:         MustGetNode(t, `<iframe src="https://notyoutube.com/embed/1234-abcd"></iframe>`),
+			want:        `<html><head></head><body><iframe src="https://notyoutube.com/embed/1234-abcd"></iframe></body></html>`,
+		},
 		{
 			// Regression test: a non-matching/malformed iframe used to abort
 			// the loop entirely (via an early `return`), preventing any later
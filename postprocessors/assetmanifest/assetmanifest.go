@@ -0,0 +1,120 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package assetmanifest
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+)
+
+var (
+	errWriteFailed = errors.New("unable to write file")
+
+	// HTML and JSON files are inputs to htmlassets rather than served assets
+	skipTypes = map[assets.Type]bool{
+		assets.HTML: true,
+		assets.JSON: true,
+	}
+
+	ioutilWriteFile = ioutil.WriteFile
+)
+
+// Postprocessor writes a Vite style manifest.json mapping the original path of
+// each local asset to the file it is served from.
+func Postprocessor(runtime postprocessors.Runtime) error {
+	if runtime.Config == nil || runtime.Config.AssetManifest == nil || runtime.Config.AssetManifest.Output == "" {
+		return nil
+	}
+
+	m, err := Build(runtime.Assets)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	output := runtime.Config.AssetManifest.Output
+	if err := ioutilWriteFile(output, b, 0644); err != nil {
+		return fmt.Errorf("%w %q; %v", errWriteFailed, output, err)
+	}
+	return nil
+}
+
+// Build returns the manifest entries for every local asset keyed by the
+// original path of the asset relative to the static directory.
+func Build(manager postprocessors.AssetManager) (map[string]Entry, error) {
+	m := map[string]Entry{}
+	for _, a := range manager.All() {
+		if !a.IsLocal() || skipTypes[a.Type()] {
+			continue
+		}
+
+		la := a.(*assetmanager.LocalAsset)
+		u, err := la.URL()
+		if err != nil {
+			return nil, err
+		}
+		ou, err := la.OriginalURL()
+		if err != nil {
+			return nil, err
+		}
+		c, err := la.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		src := strings.TrimPrefix(ou, "/")
+		m[src] = Entry{
+			File:      strings.TrimPrefix(u, "/"),
+			Src:       src,
+			Type:      string(la.Type()),
+			Media:     la.Media(),
+			Size:      len(c),
+			Integrity: Integrity([]byte(c)),
+		}
+	}
+	return m, nil
+}
+
+// Integrity returns a subresource integrity value for the contents of a file
+func Integrity(b []byte) string {
+	h := sha512.Sum384(b)
+	return "sha384-" + base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Entry describes a single asset in the manifest. File and Src match the
+// fields of a Vite manifest chunk.
+type Entry struct {
+	File      string `json:"file"`
+	Src       string `json:"src"`
+	Type      string `json:"type"`
+	Media     string `json:"media,omitempty"`
+	Size      int    `json:"size"`
+	Integrity string `json:"integrity"`
+}
@@ -0,0 +1,164 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package assetmanifest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+func TestPostprocessor(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"css/main-sync.print.css": `body{color:red}`,
+		"js/main-async.js":        `console.log("hello")`,
+		"img/logo.svg":            `<svg></svg>`,
+	}
+	for p, c := range files {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0777); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manager, err := assetmanager.NewManager(dir, dir, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// Simulate revisionassets renaming a file
+	revisionedPath := filepath.Join(dir, "js", "main-async.1234567.js")
+	if err := os.Rename(filepath.Join(dir, "js", "main-async.js"), revisionedPath); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	for _, a := range manager.All() {
+		la := a.(*assetmanager.LocalAsset)
+		if filepath.Base(la.Path()) == "main-async.js" {
+			la.UpdatePath(revisionedPath)
+		}
+	}
+
+	tests := []struct {
+		description     string
+		config          *config.Config
+		writeFile       func(filename string, data []byte, perm os.FileMode) error
+		want            map[string]Entry
+		wantWriteCalled bool
+		wantError       error
+	}{
+		{
+			description: "do nothing without config",
+			config:      &config.Config{},
+		},
+		{
+			description: "return error if writing fails",
+			config: &config.Config{
+				AssetManifest: &config.AssetManifestConfig{Output: "/manifest.json"},
+			},
+			writeFile: func(filename string, data []byte, perm os.FileMode) error {
+				return errInjected
+			},
+			wantWriteCalled: true,
+			wantError:       errWriteFailed,
+		},
+		{
+			description: "write manifest for local assets",
+			config: &config.Config{
+				AssetManifest: &config.AssetManifestConfig{Output: "/manifest.json"},
+			},
+			wantWriteCalled: true,
+			want: map[string]Entry{
+				"css/main-sync.print.css": {
+					File:      "css/main-sync.print.css",
+					Src:       "css/main-sync.print.css",
+					Type:      "sync-css",
+					Media:     "print",
+					Size:      15,
+					Integrity: Integrity([]byte(`body{color:red}`)),
+				},
+				"js/main-async.js": {
+					File:      "js/main-async.1234567.js",
+					Src:       "js/main-async.js",
+					Type:      "async-js",
+					Size:      20,
+					Integrity: Integrity([]byte(`console.log("hello")`)),
+				},
+				"img/logo.svg": {
+					File:      "img/logo.svg",
+					Src:       "img/logo.svg",
+					Type:      "svg",
+					Size:      11,
+					Integrity: Integrity([]byte(`<svg></svg>`)),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Cleanup(func() {
+				ioutilWriteFile = ioutil.WriteFile
+			})
+
+			writeCalled := false
+			var got map[string]Entry
+			ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+				writeCalled = true
+				if tt.writeFile != nil {
+					return tt.writeFile(filename, data, perm)
+				}
+				return json.Unmarshal(data, &got)
+			}
+
+			err := Postprocessor(postprocessors.Runtime{
+				Assets: manager,
+				Config: tt.config,
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+			if writeCalled != tt.wantWriteCalled {
+				t.Fatalf("Unexpected write; got %v, want %v", writeCalled, tt.wantWriteCalled)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected manifest; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	got := Integrity([]byte("alert('Hello, world.');"))
+	want := "sha384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO"
+	if got != want {
+		t.Fatalf("Unexpected integrity; got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package postprocessors defines the interfaces for steps that run once every
+// HTML file has been manipulated
+package postprocessors
+
+import (
+	"time"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+)
+
+type Postprocessor func(runtime Runtime) error
+
+type Runtime struct {
+	Debug  bool
+	Assets AssetManager
+	Config *config.Config
+
+	// ModTimes holds the modification times of the HTML files, keyed by
+	// path, from before they were rewritten by the manipulations
+	ModTimes map[string]time.Time
+}
+
+type AssetManager interface {
+	All() []assetmanager.Asset
+	StaticDir() string
+	WithType(t assets.Type) []assetmanager.Asset
+}
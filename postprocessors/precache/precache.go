@@ -0,0 +1,192 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package precache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/files"
+)
+
+const (
+	formatJSON   = "json"
+	formatModule = "module"
+
+	// bootstrapURL is the script injected on every page by embedassets
+	bootstrapURL = "/__ham/assets/js/bootstrap/always-async.js"
+)
+
+var (
+	errWriteFailed    = errors.New("unable to write file")
+	errStatFailed     = errors.New("unable to get file size")
+	errInvalidFormat  = errors.New("invalid precache format")
+	errInvalidPattern = errors.New("invalid precache pattern")
+	errBudgetExceeded = errors.New("precache size budget exceeded")
+
+	ioutilWriteFile = ioutil.WriteFile
+	osStat          = os.Stat
+)
+
+// Postprocessor writes a list of URLs and revisions for a service worker to
+// precache, covering sync CSS and JS, the ham bootstrap script and optionally
+// every HTML page.
+func Postprocessor(runtime postprocessors.Runtime) error {
+	if runtime.Config == nil || runtime.Config.Precache == nil || runtime.Config.Precache.Output == "" {
+		return nil
+	}
+
+	conf := runtime.Config.Precache
+	if conf.Format != "" && conf.Format != formatJSON && conf.Format != formatModule {
+		return fmt.Errorf("%w %q; expected %q or %q", errInvalidFormat, conf.Format, formatJSON, formatModule)
+	}
+
+	entries, err := Build(runtime.Assets, conf)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if conf.Format == formatModule {
+		b = []byte(fmt.Sprintf("export default %s;\n", b))
+	}
+
+	if runtime.Debug {
+		fmt.Printf("Writing %v precache entries to %q\n", len(entries), conf.Output)
+	}
+	if err := ioutilWriteFile(conf.Output, b, 0644); err != nil {
+		return fmt.Errorf("%w %q; %v", errWriteFailed, conf.Output, err)
+	}
+	return nil
+}
+
+// Build returns the precache entries for the final state of the assets,
+// filtered by the include and exclude patterns of the config.
+func Build(manager postprocessors.AssetManager, conf *config.PrecacheConfig) ([]Entry, error) {
+	candidates := []assetmanager.Asset{}
+	candidates = append(candidates, manager.WithType(assets.SyncCSS)...)
+	candidates = append(candidates, manager.WithType(assets.SyncJS)...)
+	for _, a := range manager.WithType(assets.AsyncJS) {
+		oa, ok := a.(originalURLAsset)
+		if !ok {
+			continue
+		}
+		ou, err := oa.OriginalURL()
+		if err != nil {
+			return nil, err
+		}
+		if ou == bootstrapURL {
+			candidates = append(candidates, a)
+		}
+	}
+	if conf.HTML {
+		candidates = append(candidates, manager.WithType(assets.HTML)...)
+	}
+
+	entries := []Entry{}
+	seen := map[string]bool{}
+	var total int64
+	for _, a := range candidates {
+		if !a.IsLocal() {
+			continue
+		}
+
+		la := a.(*assetmanager.LocalAsset)
+		u, err := la.URL()
+		if err != nil {
+			return nil, err
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+
+		ok, err := included(u, conf)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		info, err := osStat(la.Path())
+		if err != nil {
+			return nil, fmt.Errorf("%w %q; %v", errStatFailed, la.Path(), err)
+		}
+		total += info.Size()
+
+		rev, err := files.Hash(la.Path())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			URL:      u,
+			Revision: rev,
+		})
+	}
+
+	if conf.MaxSize > 0 && total > conf.MaxSize {
+		return nil, fmt.Errorf("%w; %v bytes in %v files, budget is %v bytes", errBudgetExceeded, total, len(entries), conf.MaxSize)
+	}
+	return entries, nil
+}
+
+func included(u string, conf *config.PrecacheConfig) (bool, error) {
+	for _, p := range conf.Exclude {
+		m, err := files.MatchPath(p, u)
+		if err != nil {
+			return false, fmt.Errorf("%w %q; %v", errInvalidPattern, p, err)
+		}
+		if m {
+			return false, nil
+		}
+	}
+
+	if len(conf.Include) == 0 {
+		return true, nil
+	}
+	for _, p := range conf.Include {
+		m, err := files.MatchPath(p, u)
+		if err != nil {
+			return false, fmt.Errorf("%w %q; %v", errInvalidPattern, p, err)
+		}
+		if m {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Entry matches the precache entries used by Workbox
+type Entry struct {
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+}
+
+type originalURLAsset interface {
+	OriginalURL() (string, error)
+}
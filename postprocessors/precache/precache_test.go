@@ -0,0 +1,228 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package precache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/files"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+func TestPostprocessor(t *testing.T) {
+	htmlDir := t.TempDir()
+	staticDir := t.TempDir()
+	mustWriteFiles(t, htmlDir, map[string]string{
+		"index.html":      `<html></html>`,
+		"blog/index.html": `<html><body>Blog</body></html>`,
+	})
+	mustWriteFiles(t, staticDir, map[string]string{
+		"css/main-sync.css":                         `body{color:red}`,
+		"css/main-async.css":                        `body{color:blue}`,
+		"js/main-sync.js":                           `console.log("sync")`,
+		"js/other-async.js":                         `console.log("async")`,
+		"__ham/assets/js/bootstrap/always-async.js": `console.log("bootstrap")`,
+	})
+
+	manager, err := assetmanager.NewManager(htmlDir, staticDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	rev := func(dir, p string) string {
+		h, err := files.Hash(filepath.Join(dir, p))
+		if err != nil {
+			t.Fatalf("Failed to hash file: %v", err)
+		}
+		return h
+	}
+
+	tests := []struct {
+		description string
+		config      *config.PrecacheConfig
+		writeError  error
+		want        string
+		wantError   error
+	}{
+		{
+			description: "return error for an unknown format",
+			config: &config.PrecacheConfig{
+				Output: "/sw-manifest.json",
+				Format: "yaml",
+			},
+			wantError: errInvalidFormat,
+		},
+		{
+			description: "return error for an invalid pattern",
+			config: &config.PrecacheConfig{
+				Output:  "/sw-manifest.json",
+				Exclude: []string{"["},
+			},
+			wantError: errInvalidPattern,
+		},
+		{
+			description: "return error if the size budget is exceeded",
+			config: &config.PrecacheConfig{
+				Output:  "/sw-manifest.json",
+				MaxSize: 10,
+			},
+			wantError: errBudgetExceeded,
+		},
+		{
+			description: "return error if writing fails",
+			config: &config.PrecacheConfig{
+				Output: "/sw-manifest.json",
+			},
+			writeError: errInjected,
+			wantError:  errWriteFailed,
+		},
+		{
+			description: "write sync assets and bootstrap script as JSON",
+			config: &config.PrecacheConfig{
+				Output: "/sw-manifest.json",
+			},
+			want: `[
+  {
+    "url": "/css/main-sync.css",
+    "revision": "` + rev(staticDir, "css/main-sync.css") + `"
+  },
+  {
+    "url": "/js/main-sync.js",
+    "revision": "` + rev(staticDir, "js/main-sync.js") + `"
+  },
+  {
+    "url": "/__ham/assets/js/bootstrap/always-async.js",
+    "revision": "` + rev(staticDir, "__ham/assets/js/bootstrap/always-async.js") + `"
+  }
+]`,
+		},
+		{
+			description: "write filtered entries with HTML as a module",
+			config: &config.PrecacheConfig{
+				Output:  "/sw-manifest.js",
+				Format:  "module",
+				HTML:    true,
+				Include: []string{"/css/*", "/*.html", "/blog/*"},
+				Exclude: []string{"/blog/*"},
+			},
+			want: `export default [
+  {
+    "url": "/css/main-sync.css",
+    "revision": "` + rev(staticDir, "css/main-sync.css") + `"
+  },
+  {
+    "url": "/index.html",
+    "revision": "` + rev(htmlDir, "index.html") + `"
+  }
+];
+`,
+		},
+		{
+			description: "write entries matching ** patterns",
+			config: &config.PrecacheConfig{
+				Output:  "/sw-manifest.json",
+				HTML:    true,
+				Include: []string{"/**/index.html", "/__ham/**"},
+			},
+			want: `[
+  {
+    "url": "/__ham/assets/js/bootstrap/always-async.js",
+    "revision": "` + rev(staticDir, "__ham/assets/js/bootstrap/always-async.js") + `"
+  },
+  {
+    "url": "/blog/index.html",
+    "revision": "` + rev(htmlDir, "blog/index.html") + `"
+  },
+  {
+    "url": "/index.html",
+    "revision": "` + rev(htmlDir, "index.html") + `"
+  }
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Cleanup(func() {
+				ioutilWriteFile = ioutil.WriteFile
+			})
+
+			got := ""
+			ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+				if filename != tt.config.Output {
+					t.Fatalf("Unexpected output file; got %v, want %v", filename, tt.config.Output)
+				}
+				got = string(data)
+				return tt.writeError
+			}
+
+			err := Postprocessor(postprocessors.Runtime{
+				Assets: manager,
+				Config: &config.Config{
+					Precache: tt.config,
+				},
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected precache manifest; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestPostprocessor_NoConfig(t *testing.T) {
+	t.Cleanup(func() {
+		ioutilWriteFile = ioutil.WriteFile
+	})
+	ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+		t.Fatalf("Unexpected write to %v", filename)
+		return nil
+	}
+
+	if err := Postprocessor(postprocessors.Runtime{Config: &config.Config{}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func mustWriteFiles(t *testing.T, dir string, contents map[string]string) {
+	t.Helper()
+
+	for p, c := range contents {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0777); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
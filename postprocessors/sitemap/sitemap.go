@@ -0,0 +1,361 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package sitemap
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+const (
+	sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	imageNS   = "http://www.google.com/schemas/sitemap-image/1.1"
+
+	// The maximum number of images per URL in an image sitemap
+	maxImages = 1000
+)
+
+var (
+	errNoBaseURL     = errors.New("base-url is required for a sitemap")
+	errWriteFailed   = errors.New("unable to write file")
+	errStatFailed    = errors.New("unable to get file modification time")
+	errParseFailed   = errors.New("unable to parse HTML file")
+	errOutsideHTML   = errors.New("sitemap output must be in html-dir to be split")
+	errInvalidOutput = errors.New("invalid sitemap output")
+
+	ioutilWriteFile = ioutil.WriteFile
+	osStat          = os.Stat
+
+	// The maximum number of URLs in one sitemap before it is split and
+	// written with a sitemap index
+	maxURLs = 50000
+
+	// Meta tags that hold when a page was last modified, in order of
+	// preference
+	lastModMetas = []struct {
+		attr string
+		val  string
+	}{
+		{"property", "article:modified_time"},
+		{"property", "og:updated_time"},
+		{"name", "lastmod"},
+	}
+)
+
+// Postprocessor writes a sitemap of every indexable HTML file using the
+// base-url of the config, splitting it with a sitemap index when there are too
+// many URLs for one sitemap.
+func Postprocessor(runtime postprocessors.Runtime) error {
+	if runtime.Config == nil || runtime.Config.Sitemap == nil || runtime.Config.Sitemap.Output == "" {
+		return nil
+	}
+
+	urls, err := Build(runtime.Assets, runtime.Config, runtime.ModTimes)
+	if err != nil {
+		return err
+	}
+
+	conf := runtime.Config
+	if len(urls) <= maxURLs {
+		if runtime.Debug {
+			fmt.Printf("Writing %v URLs to sitemap %q\n", len(urls), conf.Sitemap.Output)
+		}
+		return writeXML(conf.Sitemap.Output, newURLSet(urls, conf.Sitemap.Images))
+	}
+
+	rel, err := filepath.Rel(conf.HTMLDir, conf.Sitemap.Output)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%w; %q is not in %q", errOutsideHTML, conf.Sitemap.Output, conf.HTMLDir)
+	}
+	ext := filepath.Ext(conf.Sitemap.Output)
+	if ext == "" {
+		return fmt.Errorf("%w %q; expected a file extension", errInvalidOutput, conf.Sitemap.Output)
+	}
+
+	index := sitemapIndex{
+		XMLNS: sitemapNS,
+	}
+	baseURL := strings.TrimSuffix(conf.BaseURL, "/")
+	for i := 0; i*maxURLs < len(urls); i++ {
+		end := (i + 1) * maxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		file := fmt.Sprintf("%v-%v%v", strings.TrimSuffix(conf.Sitemap.Output, ext), i+1, ext)
+		if err := writeXML(file, newURLSet(urls[i*maxURLs:end], conf.Sitemap.Images)); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(conf.HTMLDir, file)
+		if err != nil {
+			return fmt.Errorf("%w; %q is not in %q", errOutsideHTML, file, conf.HTMLDir)
+		}
+		index.Sitemaps = append(index.Sitemaps, Sitemap{
+			Loc: baseURL + "/" + filepath.ToSlash(rel),
+		})
+	}
+
+	if runtime.Debug {
+		fmt.Printf("Writing %v URLs to %v sitemaps in index %q\n", len(urls), len(index.Sitemaps), conf.Sitemap.Output)
+	}
+	return writeXML(conf.Sitemap.Output, index)
+}
+
+// Build returns the sitemap URLs for the HTML files sorted by URL. Pages with
+// a robots noindex meta tag or a canonical link to another URL are left out.
+// Pages without a last modified meta tag use their time in modTimes, or the
+// modification time of the file if it has none.
+func Build(manager postprocessors.AssetManager, conf *config.Config, modTimes map[string]time.Time) ([]URL, error) {
+	if conf.BaseURL == "" {
+		return nil, errNoBaseURL
+	}
+	baseURL := strings.TrimSuffix(conf.BaseURL, "/")
+	images := conf.Sitemap != nil && conf.Sitemap.Images
+
+	las := []*assetmanager.LocalAsset{}
+	for _, a := range manager.WithType(assets.HTML) {
+		if !a.IsLocal() {
+			continue
+		}
+		las = append(las, a.(*assetmanager.LocalAsset))
+	}
+
+	urls := []URL{}
+	for _, la := range las {
+		u, err := la.URL()
+		if err != nil {
+			return nil, err
+		}
+		pageURL, err := url.Parse(baseURL + pagePath(u))
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := la.Contents()
+		if err != nil {
+			return nil, err
+		}
+		doc, err := html.Parse(strings.NewReader(contents))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q; %v", errParseFailed, la.Path(), err)
+		}
+
+		if noindex(doc) || !isCanonical(doc, pageURL) {
+			continue
+		}
+
+		lastMod := metaLastMod(doc)
+		if lastMod == "" {
+			mt, ok := modTimes[la.Path()]
+			if !ok {
+				info, err := osStat(la.Path())
+				if err != nil {
+					return nil, fmt.Errorf("%w %q; %v", errStatFailed, la.Path(), err)
+				}
+				mt = info.ModTime()
+			}
+			lastMod = mt.UTC().Format(time.RFC3339)
+		}
+
+		entry := URL{
+			Loc:     pageURL.String(),
+			LastMod: lastMod,
+		}
+		if images {
+			entry.Images = pictureImages(doc, pageURL)
+		}
+		urls = append(urls, entry)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Loc < urls[j].Loc
+	})
+	return urls, nil
+}
+
+// pagePath returns the URL path a page is served from, index.html files are
+// served from their directory
+func pagePath(u string) string {
+	if strings.HasSuffix(u, "/index.html") {
+		return strings.TrimSuffix(u, "index.html")
+	}
+	return u
+}
+
+func noindex(doc *html.Node) bool {
+	for _, m := range htmlparsing.FindNodesByTag("meta", doc) {
+		attrs := htmlparsing.Attributes(m)
+		if strings.ToLower(attrs["name"].Val) != "robots" {
+			continue
+		}
+		for _, d := range strings.Split(attrs["content"].Val, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "noindex" || d == "none" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isCanonical returns false if the page has a canonical link to another URL
+func isCanonical(doc *html.Node, pageURL *url.URL) bool {
+	for _, l := range htmlparsing.FindNodesByTag("link", doc) {
+		attrs := htmlparsing.Attributes(l)
+		if strings.ToLower(attrs["rel"].Val) != "canonical" {
+			continue
+		}
+		ref, err := url.Parse(attrs["href"].Val)
+		if err != nil {
+			return true
+		}
+		canonical := pageURL.ResolveReference(ref)
+		canonical.Fragment = ""
+		return canonical.String() == pageURL.String()
+	}
+	return true
+}
+
+func metaLastMod(doc *html.Node) string {
+	metas := htmlparsing.FindNodesByTag("meta", doc)
+	for _, lm := range lastModMetas {
+		for _, m := range metas {
+			attrs := htmlparsing.Attributes(m)
+			if attrs[lm.attr].Val != lm.val {
+				continue
+			}
+			c := strings.TrimSpace(attrs["content"].Val)
+			if _, err := time.Parse(time.RFC3339, c); err == nil {
+				return c
+			}
+			if _, err := time.Parse("2006-01-02", c); err == nil {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+// pictureImages returns the absolute URL of the img in every picture element
+func pictureImages(doc *html.Node, pageURL *url.URL) []Image {
+	imgs := []Image{}
+	seen := map[string]bool{}
+	for _, p := range htmlparsing.FindNodesByTag("picture", doc) {
+		img := htmlparsing.FindNodeByTag("img", p)
+		if img == nil {
+			continue
+		}
+		attrs := htmlparsing.Attributes(img)
+		src := attrs["src"].Val
+		if src == "" {
+			// Candidates are listed smallest first by imgtopicture
+			candidates := htmlparsing.SrcsetURLs(attrs["srcset"].Val)
+			if len(candidates) == 0 {
+				continue
+			}
+			src = candidates[len(candidates)-1]
+		}
+		if strings.HasPrefix(src, "data:") {
+			continue
+		}
+
+		ref, err := url.Parse(src)
+		if err != nil {
+			continue
+		}
+		loc := pageURL.ResolveReference(ref).String()
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		imgs = append(imgs, Image{Loc: loc})
+		if len(imgs) == maxImages {
+			break
+		}
+	}
+	return imgs
+}
+
+func newURLSet(urls []URL, images bool) urlSet {
+	s := urlSet{
+		XMLNS: sitemapNS,
+		URLs:  urls,
+	}
+	if images {
+		s.ImageNS = imageNS
+	}
+	return s
+}
+
+func writeXML(file string, v interface{}) error {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append([]byte(xml.Header), b...)
+	b = append(b, '\n')
+	if err := ioutilWriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("%w %q; %v", errWriteFailed, file, err)
+	}
+	return nil
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	ImageNS string   `xml:"xmlns:image,attr,omitempty"`
+	URLs    []URL    `xml:"url"`
+}
+
+// URL is a page in a sitemap
+type URL struct {
+	Loc     string  `xml:"loc"`
+	LastMod string  `xml:"lastmod,omitempty"`
+	Images  []Image `xml:"image:image,omitempty"`
+}
+
+// Image is an image on a page in an image sitemap
+type Image struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name  `xml:"sitemapindex"`
+	XMLNS    string    `xml:"xmlns,attr"`
+	Sitemaps []Sitemap `xml:"sitemap"`
+}
+
+// Sitemap is a sitemap listed in a sitemap index
+type Sitemap struct {
+	Loc string `xml:"loc"`
+}
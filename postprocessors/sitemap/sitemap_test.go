@@ -0,0 +1,265 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package sitemap
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/postprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+func TestPostprocessor(t *testing.T) {
+	htmlDir := t.TempDir()
+	mustWriteFiles(t, htmlDir, map[string]string{
+		"index.html": `<html><head><link rel="canonical" href="https://example.com/"></head><body>
+<picture><source srcset="/generated/a.123/100.webp 100w"><img src="/generated/a.123/200.jpg"></picture>
+<picture><img srcset="/b/100.jpg 100w, /b/200.jpg 200w"></picture>
+<picture><img src="/generated/a.123/200.jpg"></picture>
+<img src="/c.png">
+</body></html>`,
+		"blog/index.html":     `<html><head><meta property="article:modified_time" content="2020-05-01T10:00:00Z"></head></html>`,
+		"blog/post.html":      `<html><head><meta name="lastmod" content="2020-06-01"><link rel="canonical" href="post.html#top"></head></html>`,
+		"blog/duplicate.html": `<html><head><link rel="canonical" href="/blog/post.html"></head></html>`,
+		"private.html":        `<html><head><meta name="robots" content="nofollow, NOINDEX"></head></html>`,
+		"404.html":            `<html><head><meta name="lastmod" content="yesterday"></head></html>`,
+	})
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, p := range []string{"index.html", "404.html"} {
+		if err := os.Chtimes(filepath.Join(htmlDir, p), mtime, mtime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	// Times recorded before the pages were rewritten take precedence
+	modTimes := map[string]time.Time{
+		filepath.Join(htmlDir, "404.html"): time.Date(2019, 7, 8, 9, 10, 11, 0, time.FixedZone("", 3600)),
+	}
+
+	manager, err := assetmanager.NewManager(htmlDir, "", "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	output := filepath.Join(htmlDir, "sitemap.xml")
+
+	tests := []struct {
+		description string
+		baseURL     string
+		config      *config.SitemapConfig
+		maxURLs     int
+		writeError  error
+		want        map[string]string
+		wantError   error
+	}{
+		{
+			description: "return error without base url",
+			config: &config.SitemapConfig{
+				Output: output,
+			},
+			wantError: errNoBaseURL,
+		},
+		{
+			description: "return error if write fails",
+			baseURL:     "https://example.com",
+			config: &config.SitemapConfig{
+				Output: output,
+			},
+			writeError: errInjected,
+			wantError:  errWriteFailed,
+		},
+		{
+			description: "write sitemap of indexable canonical pages",
+			baseURL:     "https://example.com/",
+			config: &config.SitemapConfig{
+				Output: output,
+			},
+			want: map[string]string{
+				output: `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2021-01-02T03:04:05Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/404.html</loc>
+    <lastmod>2019-07-08T08:10:11Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/blog/</loc>
+    <lastmod>2020-05-01T10:00:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/blog/post.html</loc>
+    <lastmod>2020-06-01</lastmod>
+  </url>
+</urlset>
+`,
+			},
+		},
+		{
+			description: "write image entries for picture elements",
+			baseURL:     "https://example.com",
+			config: &config.SitemapConfig{
+				Output: output,
+				Images: true,
+			},
+			maxURLs: 1,
+			want: map[string]string{
+				filepath.Join(htmlDir, "sitemap-1.xml"): `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2021-01-02T03:04:05Z</lastmod>
+    <image:image>
+      <image:loc>https://example.com/generated/a.123/200.jpg</image:loc>
+    </image:image>
+    <image:image>
+      <image:loc>https://example.com/b/200.jpg</image:loc>
+    </image:image>
+  </url>
+</urlset>
+`,
+				filepath.Join(htmlDir, "sitemap-2.xml"): `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://example.com/404.html</loc>
+    <lastmod>2019-07-08T08:10:11Z</lastmod>
+  </url>
+</urlset>
+`,
+				filepath.Join(htmlDir, "sitemap-3.xml"): `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://example.com/blog/</loc>
+    <lastmod>2020-05-01T10:00:00Z</lastmod>
+  </url>
+</urlset>
+`,
+				filepath.Join(htmlDir, "sitemap-4.xml"): `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://example.com/blog/post.html</loc>
+    <lastmod>2020-06-01</lastmod>
+  </url>
+</urlset>
+`,
+				output: `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://example.com/sitemap-1.xml</loc>
+  </sitemap>
+  <sitemap>
+    <loc>https://example.com/sitemap-2.xml</loc>
+  </sitemap>
+  <sitemap>
+    <loc>https://example.com/sitemap-3.xml</loc>
+  </sitemap>
+  <sitemap>
+    <loc>https://example.com/sitemap-4.xml</loc>
+  </sitemap>
+</sitemapindex>
+`,
+			},
+		},
+		{
+			description: "return error if split sitemaps are outside html-dir",
+			baseURL:     "https://example.com",
+			config: &config.SitemapConfig{
+				Output: "/sitemap.xml",
+			},
+			maxURLs:   1,
+			wantError: errOutsideHTML,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			origMaxURLs := maxURLs
+			t.Cleanup(func() {
+				ioutilWriteFile = ioutil.WriteFile
+				maxURLs = origMaxURLs
+			})
+			if tt.maxURLs > 0 {
+				maxURLs = tt.maxURLs
+			}
+
+			got := map[string]string{}
+			ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+				got[filename] = string(data)
+				return tt.writeError
+			}
+
+			err := Postprocessor(postprocessors.Runtime{
+				Assets:   manager,
+				ModTimes: modTimes,
+				Config: &config.Config{
+					HTMLDir: htmlDir,
+					BaseURL: tt.baseURL,
+					Sitemap: tt.config,
+				},
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected sitemap; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestPostprocessor_NoConfig(t *testing.T) {
+	t.Cleanup(func() {
+		ioutilWriteFile = ioutil.WriteFile
+	})
+	ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+		t.Fatalf("Unexpected write to %v", filename)
+		return nil
+	}
+
+	if err := Postprocessor(postprocessors.Runtime{Config: &config.Config{}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func mustWriteFiles(t *testing.T, dir string, contents map[string]string) {
+	t.Helper()
+
+	for p, c := range contents {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0777); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontassets
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/fontusage"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+var (
+	errWriteFailed  = errors.New("unable to write file")
+	errSubsetFailed = errors.New("unable to subset font")
+
+	cssTypes = []assets.Type{
+		assets.InlineCSS,
+		assets.SyncCSS,
+		assets.AsyncCSS,
+		assets.PreloadCSS,
+	}
+
+	// Attributes whose text can be rendered with the page fonts
+	textAttributes = map[string]bool{
+		"alt":         true,
+		"title":       true,
+		"placeholder": true,
+	}
+
+	ioutilWriteFile = ioutil.WriteFile
+	execCommand     = exec.Command
+)
+
+const subsetSuffix = ".subset"
+
+// Preprocessor sets font-display in local CSS and optionally subsets local
+// fonts to the characters used by the HTML pages that load them.
+func Preprocessor(runtime preprocessors.Runtime) error {
+	if runtime.Config == nil || runtime.Config.Fonts == nil {
+		return nil
+	}
+
+	conf := runtime.Config.Fonts
+	if conf.FontDisplay != "" {
+		if err := setFontDisplay(runtime.Assets, conf.FontDisplay); err != nil {
+			return err
+		}
+	}
+
+	if conf.Subset {
+		return subsetFonts(runtime.Assets)
+	}
+	return nil
+}
+
+func setFontDisplay(manager preprocessors.AssetManager, display string) error {
+	for _, t := range cssTypes {
+		for _, a := range manager.WithType(t) {
+			if !a.IsLocal() {
+				continue
+			}
+
+			la := a.(*assetmanager.LocalAsset)
+			c, err := la.Contents()
+			if err != nil {
+				return err
+			}
+
+			updated := css.SetFontDisplay(c, display)
+			if updated == c {
+				continue
+			}
+
+			if err := ioutilWriteFile(la.Path(), []byte(updated), 0644); err != nil {
+				return fmt.Errorf("%w %q; %v", errWriteFailed, la.Path(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func subsetFonts(manager preprocessors.AssetManager) error {
+	// Fonts are shared between pages so each font needs the characters of
+	// every page that uses it
+	chars := map[*assetmanager.LocalAsset]map[rune]bool{}
+	for _, a := range manager.WithType(assets.HTML) {
+		if !a.IsLocal() {
+			continue
+		}
+
+		c, err := a.Contents()
+		if err != nil {
+			return err
+		}
+		doc, err := html.Parse(strings.NewReader(c))
+		if err != nil {
+			return err
+		}
+
+		keys := htmlparsing.GetKeys(doc).Sorted()
+		fonts, err := fontusage.Fonts(manager, keys, cssTypes...)
+		if err != nil {
+			return err
+		}
+		if len(fonts) == 0 {
+			continue
+		}
+
+		text, err := pageText(manager, keys, doc)
+		if err != nil {
+			return err
+		}
+		for _, f := range fonts {
+			for _, fa := range f.Assets {
+				la := fa.(*assetmanager.LocalAsset)
+				if isSubset(la.Path()) {
+					continue
+				}
+				if _, ok := chars[la]; !ok {
+					chars[la] = map[rune]bool{}
+				}
+				for _, r := range text {
+					chars[la][r] = true
+				}
+			}
+		}
+	}
+
+	fonts := []*assetmanager.LocalAsset{}
+	for la := range chars {
+		fonts = append(fonts, la)
+	}
+	sort.Slice(fonts, func(i, j int) bool {
+		return fonts[i].Path() < fonts[j].Path()
+	})
+
+	// Subsets are written next to the original font and the stylesheets are
+	// pointed at them, leaving the original file untouched
+	renames := map[string]string{}
+	for _, la := range fonts {
+		u, err := la.URL()
+		if err != nil {
+			return err
+		}
+
+		p := subsetPath(la.Path())
+		if err := subset(la.Path(), p, chars[la]); err != nil {
+			return err
+		}
+		la.UpdatePath(p)
+		renames[u] = path.Base(filepath.ToSlash(p))
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+	return rewriteFontURLs(manager, renames)
+}
+
+// pageText returns the text of a page that could be rendered with a font:
+// the text nodes, user visible attributes and CSS generated content
+func pageText(manager preprocessors.AssetManager, keys []string, doc *html.Node) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(textContent(doc))
+	for _, n := range htmlparsing.FindNodesByTag("style", doc) {
+		sb.WriteString(css.ContentText(textContent(n.FirstChild)))
+	}
+
+	for _, t := range cssTypes {
+		for _, k := range keys {
+			for _, a := range manager.WithID(k)[t] {
+				if !a.IsLocal() {
+					continue
+				}
+				c, err := a.Contents()
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(css.ContentText(c))
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+func rewriteFontURLs(manager preprocessors.AssetManager, renames map[string]string) error {
+	for _, t := range cssTypes {
+		for _, a := range manager.WithType(t) {
+			if !a.IsLocal() {
+				continue
+			}
+
+			la := a.(*assetmanager.LocalAsset)
+			cssURL, err := la.URL()
+			if err != nil {
+				return err
+			}
+			c, err := la.Contents()
+			if err != nil {
+				return err
+			}
+
+			updated := css.ReplaceReferences(c, func(ref string) string {
+				filename, ok := renames[stripQuery(css.ResolveURL(cssURL, ref))]
+				if !ok {
+					return ref
+				}
+				return renameRef(ref, filename)
+			})
+			if updated == c {
+				continue
+			}
+
+			if err := ioutilWriteFile(la.Path(), []byte(updated), 0644); err != nil {
+				return fmt.Errorf("%w %q; %v", errWriteFailed, la.Path(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func subset(fontPath, outputPath string, chars map[rune]bool) error {
+	runes := []rune{}
+	for r := range chars {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool {
+		return runes[i] < runes[j]
+	})
+
+	flavor := "woff2"
+	if strings.HasSuffix(fontPath, ".woff") {
+		flavor = "woff"
+	}
+
+	cmd := execCommand(
+		"pyftsubset", fontPath,
+		fmt.Sprintf("--text=%v", string(runes)),
+		fmt.Sprintf("--flavor=%v", flavor),
+		fmt.Sprintf("--output-file=%v", outputPath),
+		"--layout-features=*",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w %q; %v: %v", errSubsetFailed, fontPath, err, string(output))
+	}
+	return nil
+}
+
+// subsetPath returns the path a subset of a font is written to, for example
+// fonts/sans.woff2 becomes fonts/sans.subset.woff2
+func subsetPath(fontPath string) string {
+	ext := filepath.Ext(fontPath)
+	return strings.TrimSuffix(fontPath, ext) + subsetSuffix + ext
+}
+
+func isSubset(fontPath string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(fontPath, filepath.Ext(fontPath)), subsetSuffix)
+}
+
+// renameRef swaps the filename in a reference, keeping its directory, query
+// and fragment
+func renameRef(ref, filename string) string {
+	suffix := ""
+	if i := strings.IndexAny(ref, "?#"); i != -1 {
+		ref, suffix = ref[:i], ref[i:]
+	}
+	return ref[:strings.LastIndex(ref, "/")+1] + filename + suffix
+}
+
+func stripQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i != -1 {
+		return u[:i]
+	}
+	return u
+}
+
+func textContent(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+		return ""
+	}
+
+	var sb strings.Builder
+	if n.Type == html.ElementNode {
+		for _, a := range n.Attr {
+			if textAttributes[a.Key] {
+				sb.WriteString(a.Val)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
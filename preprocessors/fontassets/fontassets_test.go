@@ -0,0 +1,207 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package fontassets
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+// fakeSubset returns a command that writes the subset text to the output file
+// instead of running pyftsubset, recording the arguments it was called with
+func fakeSubset(code int, gotArgs *[][]string) func(string, ...string) *exec.Cmd {
+	return func(name string, args ...string) *exec.Cmd {
+		*gotArgs = append(*gotArgs, append([]string{name}, args...))
+
+		text, output := "", ""
+		for _, a := range args {
+			if strings.HasPrefix(a, "--text=") {
+				text = strings.TrimPrefix(a, "--text=")
+			}
+			if strings.HasPrefix(a, "--output-file=") {
+				output = strings.TrimPrefix(a, "--output-file=")
+			}
+		}
+		return exec.Command("sh", "-c", `printf 'subset:%s' "$0" > "$1"; exit $2`, text, output, strconv.Itoa(code))
+	}
+}
+
+func TestPreprocessor(t *testing.T) {
+	files := map[string]string{
+		"html/index.html":                `<html><head><title>Hi</title></head><body class="quote"><img alt="Cat" src="/cat.png"><input placeholder="Go"></body></html>`,
+		"html/other.html":                `<html><head><style>b::after{content:"!"}</style></head><body><b title="x">b</b></body></html>`,
+		"html/plain.html":                `<html><body>Plain</body></html>`,
+		"static/always.css":              `@font-face{font-family:Sans;src:url(fonts/sans.woff2?v=1) format("woff2"),url("/fonts/sans.woff")}`,
+		"static/quote.css":               `.quote::before{content:"\201C"}@font-face{font-family:Mono;src:url(fonts/mono.subset.woff2)}`,
+		"static/fonts/sans.woff2":        "sans-woff2",
+		"static/fonts/sans.woff":         "sans-woff",
+		"static/fonts/mono.subset.woff2": "mono",
+	}
+
+	tests := []struct {
+		description string
+		config      *config.Config
+		exitCode    int
+		writeError  error
+		want        map[string]string
+		wantArgs    [][]string
+		wantError   error
+	}{
+		{
+			description: "do nothing without subset config",
+			config: &config.Config{
+				Fonts: &config.FontsConfig{},
+			},
+			want: files,
+		},
+		{
+			description: "return error if subsetting fails",
+			config: &config.Config{
+				Fonts: &config.FontsConfig{Subset: true},
+			},
+			exitCode: 1,
+			wantArgs: [][]string{
+				{"pyftsubset", "static/fonts/sans.woff", "--text=!CGHPabilnotx“", "--flavor=woff", "--output-file=static/fonts/sans.subset.woff", "--layout-features=*"},
+			},
+			wantError: errSubsetFailed,
+		},
+		{
+			description: "return error if writing css fails",
+			config: &config.Config{
+				Fonts: &config.FontsConfig{Subset: true},
+			},
+			writeError: errInjected,
+			wantArgs: [][]string{
+				{"pyftsubset", "static/fonts/sans.woff", "--text=!CGHPabilnotx“", "--flavor=woff", "--output-file=static/fonts/sans.subset.woff", "--layout-features=*"},
+				{"pyftsubset", "static/fonts/sans.woff2", "--text=!CGHPabilnotx“", "--flavor=woff2", "--output-file=static/fonts/sans.subset.woff2", "--layout-features=*"},
+			},
+			wantError: errWriteFailed,
+		},
+		{
+			description: "write subsets of fonts and point css at them",
+			config: &config.Config{
+				Fonts: &config.FontsConfig{Subset: true},
+			},
+			want: map[string]string{
+				"html/index.html":                files["html/index.html"],
+				"html/other.html":                files["html/other.html"],
+				"html/plain.html":                files["html/plain.html"],
+				"static/always.css":              `@font-face{font-family:Sans;src:url(fonts/sans.subset.woff2?v=1) format("woff2"),url("/fonts/sans.subset.woff")}`,
+				"static/quote.css":               files["static/quote.css"],
+				"static/fonts/sans.woff2":        "sans-woff2",
+				"static/fonts/sans.woff":         "sans-woff",
+				"static/fonts/sans.subset.woff2": "subset:!CGHPabilnotx“",
+				"static/fonts/sans.subset.woff":  "subset:!CGHPabilnotx“",
+				"static/fonts/mono.subset.woff2": "mono",
+			},
+			wantArgs: [][]string{
+				{"pyftsubset", "static/fonts/sans.woff", "--text=!CGHPabilnotx“", "--flavor=woff", "--output-file=static/fonts/sans.subset.woff", "--layout-features=*"},
+				{"pyftsubset", "static/fonts/sans.woff2", "--text=!CGHPabilnotx“", "--flavor=woff2", "--output-file=static/fonts/sans.subset.woff2", "--layout-features=*"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Cleanup(func() {
+				ioutilWriteFile = ioutil.WriteFile
+				execCommand = exec.Command
+			})
+
+			dir := t.TempDir()
+			for p, c := range files {
+				fp := filepath.Join(dir, p)
+				if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+					t.Fatalf("Failed to create dir: %v", err)
+				}
+				if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+					t.Fatalf("Failed to write file: %v", err)
+				}
+			}
+
+			manager, err := assetmanager.NewManager(filepath.Join(dir, "html"), filepath.Join(dir, "static"), "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			var args [][]string
+			execCommand = fakeSubset(tt.exitCode, &args)
+			ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+				if tt.writeError != nil {
+					return tt.writeError
+				}
+				return ioutil.WriteFile(filename, data, perm)
+			}
+
+			err = Preprocessor(preprocessors.Runtime{
+				Assets: manager,
+				Config: tt.config,
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			for _, a := range args {
+				for i, arg := range a {
+					a[i] = strings.Replace(arg, dir+string(filepath.Separator), "", 1)
+				}
+			}
+			if diff := cmp.Diff(args, tt.wantArgs); diff != "" {
+				t.Fatalf("Unexpected commands; diff %v", diff)
+			}
+
+			if tt.want == nil {
+				return
+			}
+			got := map[string]string{}
+			err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				b, err := ioutil.ReadFile(p)
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					return err
+				}
+				got[filepath.ToSlash(rel)] = string(b)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to read dir: %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected files; diff %v", diff)
+			}
+		})
+	}
+}
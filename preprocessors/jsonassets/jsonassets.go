@@ -47,6 +47,7 @@ func Preprocessor(runtime preprocessors.Runtime) error {
 			assets.SyncJS:    remoteURLs.JS.Sync,
 			assets.AsyncJS:   remoteURLs.JS.Async,
 			assets.PreloadJS: remoteURLs.JS.Preload,
+			assets.ModuleJS:  remoteURLs.JS.Module,
 		}
 		for t, htmlAssets := range types {
 			for _, ha := range htmlAssets {
@@ -89,6 +90,7 @@ type jsonAssetGroup struct {
 	Sync    []htmlAsset
 	Async   []htmlAsset
 	Preload []htmlAsset
+	Module  []htmlAsset
 }
 
 type htmlAsset struct {
@@ -0,0 +1,125 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minifyassets
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/minify"
+)
+
+var (
+	errWriteFailed = errors.New("unable to write file")
+
+	cssTypes = []assets.Type{
+		assets.InlineCSS,
+		assets.SyncCSS,
+		assets.AsyncCSS,
+		assets.PreloadCSS,
+	}
+
+	jsTypes = []assets.Type{
+		assets.InlineJS,
+		assets.SyncJS,
+		assets.AsyncJS,
+		assets.PreloadJS,
+		assets.ModuleJS,
+	}
+
+	// Inlined assets have no file for a source map to refer to
+	inlineTypes = map[assets.Type]bool{
+		assets.InlineCSS: true,
+		assets.InlineJS:  true,
+	}
+
+	ioutilWriteFile = ioutil.WriteFile
+)
+
+// Preprocessor minifies local CSS and JS files in place. It must run before
+// revisionassets so revision hashes are based on the minified files.
+func Preprocessor(runtime preprocessors.Runtime) error {
+	if runtime.Config == nil || runtime.Config.Minify == nil {
+		return nil
+	}
+
+	conf := runtime.Config.Minify
+	if conf.CSS {
+		err := minifyTypes(runtime.Assets, cssTypes, minify.CSS, conf.SourceMaps, "/*# sourceMappingURL=%v */")
+		if err != nil {
+			return err
+		}
+	}
+	if conf.JS {
+		err := minifyTypes(runtime.Assets, jsTypes, minify.JS, conf.SourceMaps, "//# sourceMappingURL=%v")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func minifyTypes(manager preprocessors.AssetManager, types []assets.Type, fn minifyFunc, sourceMaps bool, mapComment string) error {
+	for _, t := range types {
+		for _, a := range manager.WithType(t) {
+			if !a.IsLocal() {
+				continue
+			}
+
+			la := a.(*assetmanager.LocalAsset)
+			c, err := la.Contents()
+			if err != nil {
+				return err
+			}
+
+			minified, mappings := fn(c)
+			if sourceMaps && !inlineTypes[t] {
+				mapPath := la.Path() + ".map"
+				if err := writeSourceMap(mapPath, la.Path(), c, mappings); err != nil {
+					return err
+				}
+				minified += "\n" + fmt.Sprintf(mapComment, filepath.Base(mapPath))
+			}
+
+			if err := ioutilWriteFile(la.Path(), []byte(minified), 0644); err != nil {
+				return fmt.Errorf("%w %q; %v", errWriteFailed, la.Path(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeSourceMap(mapPath, filePath, source string, mappings []minify.Mapping) error {
+	// The original file is overwritten so the source is only available
+	// through the sourcesContent of the map
+	name := filepath.Base(filePath)
+	b, err := minify.SourceMap(name, name, source, mappings)
+	if err != nil {
+		return err
+	}
+	if err := ioutilWriteFile(mapPath, b, 0644); err != nil {
+		return fmt.Errorf("%w %q; %v", errWriteFailed, mapPath, err)
+	}
+	return nil
+}
+
+type minifyFunc func(contents string) (string, []minify.Mapping)
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minifyassets
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+func TestPreprocessor(t *testing.T) {
+	files := map[string]string{
+		"main.css":      "body {\n  color: red;\n}\n",
+		"main-sync.css": "a {\n  color: blue;\n}\n",
+		"main-async.js": "// Comment\nconsole.log( 'hello' );\n",
+	}
+
+	tests := []struct {
+		description string
+		config      *config.Config
+		writeError  error
+		want        map[string]string
+		wantError   error
+	}{
+		{
+			description: "do nothing without config",
+			config:      &config.Config{},
+			want:        files,
+		},
+		{
+			description: "return error if writing fails",
+			config: &config.Config{
+				Minify: &config.MinifyConfig{CSS: true},
+			},
+			writeError: errInjected,
+			want:       files,
+			wantError:  errWriteFailed,
+		},
+		{
+			description: "minify CSS only",
+			config: &config.Config{
+				Minify: &config.MinifyConfig{CSS: true},
+			},
+			want: map[string]string{
+				"main.css":      "body{color:red}",
+				"main-sync.css": "a{color:blue}",
+				"main-async.js": "// Comment\nconsole.log( 'hello' );\n",
+			},
+		},
+		{
+			description: "minify CSS and JS with source maps for external files",
+			config: &config.Config{
+				Minify: &config.MinifyConfig{CSS: true, JS: true, SourceMaps: true},
+			},
+			want: map[string]string{
+				"main.css":          "body{color:red}",
+				"main-sync.css":     "a{color:blue}\n/*# sourceMappingURL=main-sync.css.map */",
+				"main-sync.css.map": `{"version":3,"file":"main-sync.css","sources":["main-sync.css"],"sourcesContent":["a {\n  color: blue;\n}\n"],"names":[],"mappings":"AAAA,EACE"}`,
+				"main-async.js":     "console.log('hello');\n//# sourceMappingURL=main-async.js.map",
+				"main-async.js.map": `{"version":3,"file":"main-async.js","sources":["main-async.js"],"sourcesContent":["// Comment\nconsole.log( 'hello' );\n"],"names":[],"mappings":"AACA,OAAO,CAAC,GAAG,CAAE,OAAQ,CAAC"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Cleanup(func() {
+				ioutilWriteFile = ioutil.WriteFile
+			})
+
+			dir := t.TempDir()
+			for p, c := range files {
+				if err := ioutil.WriteFile(filepath.Join(dir, p), []byte(c), 0644); err != nil {
+					t.Fatalf("Failed to write file: %v", err)
+				}
+			}
+
+			manager, err := assetmanager.NewManager("", dir, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			if tt.writeError != nil {
+				ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+					return tt.writeError
+				}
+			}
+
+			err = Preprocessor(preprocessors.Runtime{
+				Assets: manager,
+				Config: tt.config,
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			got := map[string]string{}
+			fs, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("Failed to read dir: %v", err)
+			}
+			for _, f := range fs {
+				b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+				if err != nil {
+					t.Fatalf("Failed to read file: %v", err)
+				}
+				got[f.Name()] = string(b)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected files; diff %v", diff)
+			}
+		})
+	}
+}
@@ -19,18 +19,21 @@ package preprocessors
 import (
 	"github.com/gauntface/go-html-asset-manager/v5/assets"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
 )
 
 type Preprocessor func(runtime Runtime) error
 
 type Runtime struct {
 	Assets AssetManager
+	Config *config.Config
 }
 
 type AssetManager interface {
 	All() []assetmanager.Asset
 	StaticDir() string
 	WithType(t assets.Type) []assetmanager.Asset
+	WithID(id string) map[assets.Type][]assetmanager.Asset
 	AddRemote(a *assetmanager.RemoteAsset)
 	AddLocal(a *assetmanager.LocalAsset)
 }
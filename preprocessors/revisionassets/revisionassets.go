@@ -17,19 +17,27 @@
 package revisionassets
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/gauntface/go-html-asset-manager/v5/assets"
 	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
 	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/css"
 	"github.com/gauntface/go-html-asset-manager/v5/utils/files"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/jsmodules"
 )
 
 var (
 	errRenameFailed = errors.New("unable to rename file")
+	errWriteFailed  = errors.New("unable to write file")
+	errCopyFailed   = errors.New("unable to copy file")
 
 	assetsToRevision = []assets.Type{
 		assets.SyncCSS,
@@ -38,35 +46,312 @@ var (
 		assets.SyncJS,
 		assets.AsyncJS,
 		assets.PreloadJS,
+		assets.ModuleJS,
 	}
 
-	osRename = os.Rename
+	// Inline assets are never renamed but can reference revisioned files
+	cssTypes = []assets.Type{
+		assets.InlineCSS,
+		assets.SyncCSS,
+		assets.AsyncCSS,
+		assets.PreloadCSS,
+	}
+	jsTypes = []assets.Type{
+		assets.InlineJS,
+		assets.SyncJS,
+		assets.AsyncJS,
+		assets.PreloadJS,
+		assets.ModuleJS,
+	}
+
+	osRename        = os.Rename
+	ioutilReadFile  = ioutil.ReadFile
+	ioutilWriteFile = ioutil.WriteFile
 )
 
+// Preprocessor revisions assets so they can be cached long term. Files are
+// revisioned after the files they reference so that references can be
+// rewritten to the new names before the referencing file is hashed.
 func Preprocessor(runtime preprocessors.Runtime) error {
-	allAssets := runtime.Assets.All()
-	for _, a := range allAssets {
+	r := &revisioner{
+		byURL:     map[string]*assetmanager.LocalAsset{},
+		state:     map[*assetmanager.LocalAsset]revisionState{},
+		refs:      map[*assetmanager.LocalAsset][]reference{},
+		copyTypes: map[assets.Type]bool{},
+	}
+	if runtime.Config != nil && runtime.Config.Revision != nil {
+		for _, t := range runtime.Config.Revision.Types {
+			r.copyTypes[assets.Type(t)] = true
+		}
+	}
+
+	las := []*assetmanager.LocalAsset{}
+	remotes := []*assetmanager.RemoteAsset{}
+	for _, a := range runtime.Assets.All() {
 		if !a.IsLocal() {
+			if ra, ok := a.(*assetmanager.RemoteAsset); ok {
+				remotes = append(remotes, ra)
+			}
 			continue
 		}
 
 		la := a.(*assetmanager.LocalAsset)
-		if !shouldRevision(la) {
+		u, err := la.URL()
+		if err != nil {
+			return err
+		}
+		r.byURL[u] = la
+		las = append(las, la)
+	}
+
+	for _, la := range las {
+		if err := r.findReferences(la); err != nil {
+			return err
+		}
+	}
+	r.inCycle = cycles(las, r.refs)
+
+	for _, la := range las {
+		if err := r.revision(la); err != nil {
+			return err
+		}
+	}
+
+	revisions, err := revisionedURLs(las)
+	if err != nil {
+		return err
+	}
+
+	// JSON assets can refer to local files by URL
+	for _, ra := range remotes {
+		u, _ := ra.URL()
+		if n, ok := revisions[u]; ok {
+			ra.UpdateURL(n)
+		}
+	}
+
+	if runtime.Config != nil && runtime.Config.Revision != nil && runtime.Config.Revision.Manifest != "" {
+		return writeManifest(runtime.Config.Revision.Manifest, revisions)
+	}
+	return nil
+}
+
+// reference is a reference from a CSS or JS file to another local asset
+type reference struct {
+	ref string
+	dep *assetmanager.LocalAsset
+}
+
+type revisionState int
+
+const (
+	unvisited revisionState = iota
+	visiting
+	done
+)
+
+type revisioner struct {
+	byURL map[string]*assetmanager.LocalAsset
+	state map[*assetmanager.LocalAsset]revisionState
+	refs  map[*assetmanager.LocalAsset][]reference
+
+	// Files that reference each other can't be named after their contents
+	// so every file in a cycle keeps its original name
+	inCycle map[*assetmanager.LocalAsset]bool
+
+	// Opted in types are copied rather than renamed so the original files
+	// can still be read by manipulations and linked to from other sites
+	copyTypes map[assets.Type]bool
+}
+
+func (r *revisioner) revision(la *assetmanager.LocalAsset) error {
+	if r.state[la] != unvisited {
+		// Either already revisioned or part of a reference cycle, in which
+		// case the file keeps its original name
+		return nil
+	}
+	r.state[la] = visiting
+	defer func() {
+		r.state[la] = done
+	}()
+
+	if err := r.rewriteReferences(la); err != nil {
+		return err
+	}
+
+	var newPath string
+	var err error
+	switch {
+	case r.inCycle[la]:
+		log.Printf("Warning: Not revisioning %q as it is part of a reference cycle", la.Path())
+		return nil
+	case shouldRevision(la):
+		newPath, err = revisionFile(la.Path())
+	case r.copyTypes[la.Type()]:
+		newPath, err = revisionCopy(la.Path())
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	la.UpdatePath(newPath)
+	return nil
+}
+
+// findReferences records the local assets referenced by a CSS or JS file
+func (r *revisioner) findReferences(la *assetmanager.LocalAsset) error {
+	isCSS := isType(la, cssTypes)
+	if !isCSS && !isType(la, jsTypes) {
+		return nil
+	}
+
+	u, err := la.URL()
+	if err != nil {
+		return err
+	}
+	c, err := la.Contents()
+	if err != nil {
+		return err
+	}
+
+	refs := jsmodules.Imports(c)
+	if isCSS {
+		refs = css.References(c)
+	}
+	for _, ref := range refs {
+		dep, ok := r.byURL[resolve(la, u, ref)]
+		if !ok || dep == la {
 			continue
 		}
+		r.refs[la] = append(r.refs[la], reference{ref: ref, dep: dep})
+	}
+	return nil
+}
+
+// rewriteReferences revisions the assets referenced by a CSS or JS file and
+// updates the file to use their new names
+func (r *revisioner) rewriteReferences(la *assetmanager.LocalAsset) error {
+	renames := map[string]string{}
+	for _, ref := range r.refs[la] {
+		if err := r.revision(ref.dep); err != nil {
+			return err
+		}
 
-		newPath, err := revisionFile(la.Path())
+		depURL, err := ref.dep.URL()
 		if err != nil {
 			return err
 		}
-		la.UpdatePath(newPath)
+		if updated := renameRef(ref.ref, path.Base(depURL)); updated != ref.ref {
+			renames[ref.ref] = updated
+		}
+	}
+	if len(renames) == 0 {
+		return nil
 	}
 
+	c, err := la.Contents()
+	if err != nil {
+		return err
+	}
+	replace := jsmodules.ReplaceImports
+	if isType(la, cssTypes) {
+		replace = css.ReplaceReferences
+	}
+	updated := replace(c, func(ref string) string {
+		if n, ok := renames[ref]; ok {
+			return n
+		}
+		return ref
+	})
+	if err := ioutilWriteFile(la.Path(), []byte(updated), 0644); err != nil {
+		return fmt.Errorf("%w %q; %v", errWriteFailed, la.Path(), err)
+	}
 	return nil
 }
 
-func shouldRevision(asset assetmanager.Asset) bool {
-	for _, t := range assetsToRevision {
+// cycles returns the assets that are part of a reference cycle, found as the
+// strongly connected components of the references with Tarjan's algorithm
+func cycles(las []*assetmanager.LocalAsset, refs map[*assetmanager.LocalAsset][]reference) map[*assetmanager.LocalAsset]bool {
+	inCycle := map[*assetmanager.LocalAsset]bool{}
+	index := map[*assetmanager.LocalAsset]int{}
+	lowLink := map[*assetmanager.LocalAsset]int{}
+	onStack := map[*assetmanager.LocalAsset]bool{}
+	stack := []*assetmanager.LocalAsset{}
+
+	var visit func(la *assetmanager.LocalAsset)
+	visit = func(la *assetmanager.LocalAsset) {
+		index[la] = len(index)
+		lowLink[la] = index[la]
+		stack = append(stack, la)
+		onStack[la] = true
+
+		for _, ref := range refs[la] {
+			if _, ok := index[ref.dep]; !ok {
+				visit(ref.dep)
+				if lowLink[ref.dep] < lowLink[la] {
+					lowLink[la] = lowLink[ref.dep]
+				}
+			} else if onStack[ref.dep] && index[ref.dep] < lowLink[la] {
+				lowLink[la] = index[ref.dep]
+			}
+		}
+
+		if lowLink[la] != index[la] {
+			return
+		}
+		component := []*assetmanager.LocalAsset{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == la {
+				break
+			}
+		}
+		if len(component) > 1 {
+			for _, c := range component {
+				inCycle[c] = true
+			}
+		}
+	}
+
+	for _, la := range las {
+		if _, ok := index[la]; !ok {
+			visit(la)
+		}
+	}
+	return inCycle
+}
+
+func resolve(la *assetmanager.LocalAsset, assetURL, ref string) string {
+	if isType(la, cssTypes) {
+		return stripQuery(css.ResolveURL(assetURL, ref))
+	}
+	u, _ := jsmodules.Resolve(assetURL, ref)
+	return stripQuery(u)
+}
+
+// renameRef swaps the filename in a reference, keeping its directory, query
+// and fragment
+func renameRef(ref, filename string) string {
+	suffix := ""
+	if i := strings.IndexAny(ref, "?#"); i != -1 {
+		ref, suffix = ref[:i], ref[i:]
+	}
+	return ref[:strings.LastIndex(ref, "/")+1] + filename + suffix
+}
+
+func stripQuery(u string) string {
+	if i := strings.IndexAny(u, "?#"); i != -1 {
+		return u[:i]
+	}
+	return u
+}
+
+func isType(asset assetmanager.Asset, types []assets.Type) bool {
+	for _, t := range types {
 		if t == asset.Type() {
 			return true
 		}
@@ -74,18 +359,74 @@ func shouldRevision(asset assetmanager.Asset) bool {
 	return false
 }
 
+func shouldRevision(asset assetmanager.Asset) bool {
+	return isType(asset, assetsToRevision)
+}
+
 func revisionFile(filepath string) (string, error) {
-	hash, err := files.Hash(filepath)
+	newFilepath, err := revisionedPath(filepath)
 	if err != nil {
 		return "", err
 	}
 
-	ext := path.Ext(filepath)
-	newFilepath := fmt.Sprintf("%v.%v%v", filepath[0:len(filepath)-len(ext)], hash, ext)
-
 	err = osRename(filepath, newFilepath)
 	if err != nil {
 		return "", fmt.Errorf("%w %q; %v", errRenameFailed, filepath, err)
 	}
 	return newFilepath, nil
 }
+
+func revisionCopy(filepath string) (string, error) {
+	newFilepath, err := revisionedPath(filepath)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := ioutilReadFile(filepath)
+	if err != nil {
+		return "", fmt.Errorf("%w %q; %v", errCopyFailed, filepath, err)
+	}
+	if err := ioutilWriteFile(newFilepath, b, 0644); err != nil {
+		return "", fmt.Errorf("%w %q; %v", errCopyFailed, filepath, err)
+	}
+	return newFilepath, nil
+}
+
+func revisionedPath(filepath string) (string, error) {
+	hash, err := files.Hash(filepath)
+	if err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(filepath)
+	return fmt.Sprintf("%v.%v%v", filepath[0:len(filepath)-len(ext)], hash, ext), nil
+}
+
+func revisionedURLs(las []*assetmanager.LocalAsset) (map[string]string, error) {
+	revisions := map[string]string{}
+	for _, la := range las {
+		u, err := la.URL()
+		if err != nil {
+			return nil, err
+		}
+		ou, err := la.OriginalURL()
+		if err != nil {
+			return nil, err
+		}
+		if u != ou {
+			revisions[ou] = u
+		}
+	}
+	return revisions, nil
+}
+
+func writeManifest(manifestPath string, revisions map[string]string) error {
+	b, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutilWriteFile(manifestPath, b, 0644); err != nil {
+		return fmt.Errorf("%w %q; %v", errWriteFailed, manifestPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package revisionassets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+var hashRegex = regexp.MustCompile(`\.[0-9a-f]{7}\.`)
+
+func TestPreprocessor(t *testing.T) {
+	tests := []struct {
+		description  string
+		files        map[string]string
+		wantContents map[string]string
+	}{
+		{
+			description: "revision css and js without references",
+			files: map[string]string{
+				"main-sync.css":   `body{color:red}`,
+				"main-async.js":   `console.log("hello")`,
+				"inline.css":      `body{background:url(/img/bg.png)}`,
+				"img/bg.png":      `png`,
+				"main-module.mjs": `export const a = 1;`,
+			},
+			wantContents: map[string]string{
+				"main-sync.HASH.css":   `body{color:red}`,
+				"main-async.HASH.js":   `console.log("hello")`,
+				"inline.css":           `body{background:url(/img/bg.png)}`,
+				"img/bg.png":           `png`,
+				"main-module.HASH.mjs": `export const a = 1;`,
+			},
+		},
+		{
+			description: "leave every file in a reference cycle unrevisioned",
+			files: map[string]string{
+				"css/a-sync.css":    `@import "b-sync.css";a{background:url(/img/bg.png)}`,
+				"css/b-sync.css":    `@import "c-sync.css";`,
+				"css/c-sync.css":    `@import "a-sync.css";@import "d-sync.css";`,
+				"css/d-sync.css":    `d{}`,
+				"css/main-sync.css": `@import "c-sync.css";`,
+				"js/loop-module.js": `import "./back-module.js";import("./leaf-module.js")`,
+				"js/back-module.js": `import "./loop-module.js";`,
+				"js/leaf-module.js": `export const a = 1;`,
+				"js/entry-async.js": `import("/js/loop-module.js")`,
+				"img/bg.png":        `png`,
+			},
+			wantContents: map[string]string{
+				"css/a-sync.css":         `@import "b-sync.css";a{background:url(/img/bg.png)}`,
+				"css/b-sync.css":         `@import "c-sync.css";`,
+				"css/c-sync.css":         `@import "a-sync.css";@import "d-sync.HASH.css";`,
+				"css/d-sync.HASH.css":    `d{}`,
+				"css/main-sync.HASH.css": `@import "c-sync.css";`,
+				"js/loop-module.js":      `import "./back-module.js";import("./leaf-module.HASH.js")`,
+				"js/back-module.js":      `import "./loop-module.js";`,
+				"js/leaf-module.HASH.js": `export const a = 1;`,
+				"js/entry-async.HASH.js": `import("/js/loop-module.js")`,
+				"img/bg.png":             `png`,
+			},
+		},
+		{
+			description: "rewrite references to revisioned files before revisioning parents",
+			files: map[string]string{
+				"css/base-sync.css":   `body{color:red}`,
+				"css/main-sync.css":   `@import "base-sync.css";@import url("/css/base-sync.css?v=1");`,
+				"inline.css":          `@import url(css/main-sync.css);`,
+				"js/util-module.js":   `export const a = 1;`,
+				"js/lazy-module.js":   `import {a} from "./util-module.js";`,
+				"js/main-module.js":   `import {a} from './util-module.js';const l = import("./lazy-module.js");import "lodash";`,
+				"js/cycle-a-sync.js":  `import("./cycle-b-sync.js")`,
+				"js/cycle-b-sync.js":  `import("./cycle-a-sync.js")`,
+				"js/classic-async.js": `import("/js/main-module.js")`,
+			},
+			wantContents: map[string]string{
+				"css/base-sync.HASH.css":   `body{color:red}`,
+				"css/main-sync.HASH.css":   `@import "base-sync.HASH.css";@import url("/css/base-sync.HASH.css?v=1");`,
+				"inline.css":               `@import url(css/main-sync.HASH.css);`,
+				"js/util-module.HASH.js":   `export const a = 1;`,
+				"js/lazy-module.HASH.js":   `import {a} from "./util-module.HASH.js";`,
+				"js/main-module.HASH.js":   `import {a} from './util-module.HASH.js';const l = import("./lazy-module.HASH.js");import "lodash";`,
+				"js/cycle-a-sync.js":       `import("./cycle-b-sync.js")`,
+				"js/cycle-b-sync.js":       `import("./cycle-a-sync.js")`,
+				"js/classic-async.HASH.js": `import("/js/main-module.HASH.js")`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			dir := t.TempDir()
+			for p, c := range tt.files {
+				fp := filepath.Join(dir, p)
+				if err := os.MkdirAll(filepath.Dir(fp), 0777); err != nil {
+					t.Fatalf("Failed to create dir: %v", err)
+				}
+				if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+					t.Fatalf("Failed to write file: %v", err)
+				}
+			}
+
+			manager, err := assetmanager.NewManager("", dir, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = Preprocessor(preprocessors.Runtime{
+				Assets: manager,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := map[string]string{}
+			err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				b, err := ioutil.ReadFile(p)
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					return err
+				}
+				got[hashRegex.ReplaceAllString(filepath.ToSlash(rel), ".HASH.")] = hashRegex.ReplaceAllString(string(b), ".HASH.")
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to read files: %v", err)
+			}
+
+			if diff := cmp.Diff(got, tt.wantContents); diff != "" {
+				t.Fatalf("Unexpected files; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestPreprocessor_Config(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"img/bg.png":    `png`,
+		"img/logo.svg":  `<svg></svg>`,
+		"main-sync.css": `body{background:url(img/bg.png)}`,
+	}
+	for p, c := range files {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0777); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manager, err := assetmanager.NewManager("", dir, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	remote := assetmanager.NewRemoteAsset("example", "/img/logo.svg", nil, assets.PreloadCSS)
+	manager.AddRemote(remote)
+
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	err = Preprocessor(preprocessors.Runtime{
+		Assets: manager,
+		Config: &config.Config{
+			Revision: &config.RevisionConfig{
+				Types:    []string{"png", "svg"},
+				Manifest: manifestPath,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	for orig, revisioned := range manifest {
+		manifest[orig] = hashRegex.ReplaceAllString(revisioned, ".HASH.")
+	}
+	wantManifest := map[string]string{
+		"/img/bg.png":    "/img/bg.HASH.png",
+		"/img/logo.svg":  "/img/logo.HASH.svg",
+		"/main-sync.css": "/main-sync.HASH.css",
+	}
+	if diff := cmp.Diff(manifest, wantManifest); diff != "" {
+		t.Fatalf("Unexpected manifest; diff %v", diff)
+	}
+
+	// Opted in types are copied so the originals still exist
+	if _, err := os.Stat(filepath.Join(dir, "img/bg.png")); err != nil {
+		t.Fatalf("Expected original image to be kept: %v", err)
+	}
+
+	u, _ := remote.URL()
+	if diff := cmp.Diff(hashRegex.ReplaceAllString(u, ".HASH."), "/img/logo.HASH.svg"); diff != "" {
+		t.Fatalf("Unexpected remote asset URL; diff %v", diff)
+	}
+}
@@ -0,0 +1,63 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package svgoptimize
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets"
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/svg"
+)
+
+var (
+	errWriteFailed = errors.New("unable to write file")
+
+	ioutilWriteFile = ioutil.WriteFile
+)
+
+func Preprocessor(runtime preprocessors.Runtime) error {
+	if runtime.Config == nil || runtime.Config.SVG == nil || !runtime.Config.SVG.Optimize {
+		return nil
+	}
+
+	for _, a := range runtime.Assets.WithType(assets.SVG) {
+		if !a.IsLocal() {
+			continue
+		}
+
+		la := a.(*assetmanager.LocalAsset)
+		c, err := la.Contents()
+		if err != nil {
+			return err
+		}
+
+		optimized := svg.Optimize(c)
+		if optimized == c {
+			continue
+		}
+
+		if err := ioutilWriteFile(la.Path(), []byte(optimized), 0644); err != nil {
+			return fmt.Errorf("%w %q; %v", errWriteFailed, la.Path(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package svgoptimize
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/assets/assetmanager"
+	"github.com/gauntface/go-html-asset-manager/v5/preprocessors"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+func TestPreprocessor(t *testing.T) {
+	files := map[string]string{
+		"icon.svg":      "<?xml version=\"1.0\"?>\n<!-- Icon -->\n<svg viewBox=\"0 0 10 10\">\n  <path d=\"M0 0h10\"/>\n</svg>\n",
+		"optimized.svg": `<svg viewBox="0 0 10 10"><path d="M0 0h10"/></svg>`,
+		"main-sync.css": "a {\n  color: blue;\n}\n",
+	}
+
+	tests := []struct {
+		description string
+		config      *config.Config
+		writeError  error
+		want        map[string]string
+		wantWrites  int
+		wantError   error
+	}{
+		{
+			description: "do nothing without config",
+			config:      &config.Config{},
+			want:        files,
+		},
+		{
+			description: "do nothing when optimize is off",
+			config: &config.Config{
+				SVG: &config.SVGConfig{InlineMaxBytes: 100},
+			},
+			want: files,
+		},
+		{
+			description: "return error if writing fails",
+			config: &config.Config{
+				SVG: &config.SVGConfig{Optimize: true},
+			},
+			writeError: errInjected,
+			want:       files,
+			wantWrites: 1,
+			wantError:  errWriteFailed,
+		},
+		{
+			description: "optimize svg files and only write changed files",
+			config: &config.Config{
+				SVG: &config.SVGConfig{Optimize: true},
+			},
+			want: map[string]string{
+				"icon.svg":      `<svg viewBox="0 0 10 10"><path d="M0 0h10"/></svg>`,
+				"optimized.svg": `<svg viewBox="0 0 10 10"><path d="M0 0h10"/></svg>`,
+				"main-sync.css": "a {\n  color: blue;\n}\n",
+			},
+			wantWrites: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Cleanup(func() {
+				ioutilWriteFile = ioutil.WriteFile
+			})
+
+			dir := t.TempDir()
+			for p, c := range files {
+				if err := ioutil.WriteFile(filepath.Join(dir, p), []byte(c), 0644); err != nil {
+					t.Fatalf("Failed to write file: %v", err)
+				}
+			}
+
+			manager, err := assetmanager.NewManager("", dir, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			writes := 0
+			ioutilWriteFile = func(filename string, data []byte, perm os.FileMode) error {
+				writes++
+				if tt.writeError != nil {
+					return tt.writeError
+				}
+				return ioutil.WriteFile(filename, data, perm)
+			}
+
+			err = Preprocessor(preprocessors.Runtime{
+				Assets: manager,
+				Config: tt.config,
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+			if writes != tt.wantWrites {
+				t.Fatalf("Unexpected number of writes; got %v, want %v", writes, tt.wantWrites)
+			}
+
+			got := map[string]string{}
+			fs, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("Failed to read dir: %v", err)
+			}
+			for _, f := range fs {
+				b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+				if err != nil {
+					t.Fatalf("Failed to read file: %v", err)
+				}
+				got[f.Name()] = string(b)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected files; diff %v", diff)
+			}
+		})
+	}
+}
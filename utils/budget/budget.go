@@ -0,0 +1,327 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package budget measures pages after manipulation and checks them against
+// the size and request budgets in the config
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/files"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/stringui"
+	"golang.org/x/net/html"
+)
+
+var (
+	osStat = os.Stat
+
+	ErrInvalidPattern = errors.New("invalid budget path pattern")
+
+	// Script types that are run by the browser
+	scriptTypes = map[string]bool{
+		"":                       true,
+		"module":                 true,
+		"text/javascript":        true,
+		"application/javascript": true,
+	}
+)
+
+// The names of the budgets as used in the config
+const (
+	InlineCSSBytes   = "inline-css-bytes"
+	InlineJSBytes    = "inline-js-bytes"
+	BlockingRequests = "blocking-requests"
+	ImageBytes       = "image-bytes"
+	HTMLBytes        = "html-bytes"
+)
+
+// Usage is what a page uses of each budget
+type Usage struct {
+	InlineCSSBytes   int64
+	InlineJSBytes    int64
+	BlockingRequests int64
+	ImageBytes       int64
+	HTMLBytes        int64
+}
+
+// Violation is a budget a page has exceeded
+type Violation struct {
+	Page   string
+	Metric string
+	Value  int64
+	Limit  int64
+}
+
+// ForPage returns the budget for a page path relative to the HTML directory.
+// Budget paths are globs where `*` matches within a directory and `**`
+// matches any number of directories. Budgets are applied in order so later matching budgets override the limits
+// they set. Nil is returned if no budget applies.
+func ForPage(budgets []*config.BudgetConfig, page string) (*config.BudgetConfig, error) {
+	var merged *config.BudgetConfig
+	for _, b := range budgets {
+		if b.Path != "" {
+			m, err := files.MatchPath(b.Path, page)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q; %v", ErrInvalidPattern, b.Path, err)
+			}
+			if !m {
+				continue
+			}
+		}
+
+		if merged == nil {
+			merged = &config.BudgetConfig{Path: page}
+		}
+		if b.InlineCSSBytes > 0 {
+			merged.InlineCSSBytes = b.InlineCSSBytes
+		}
+		if b.InlineJSBytes > 0 {
+			merged.InlineJSBytes = b.InlineJSBytes
+		}
+		if b.BlockingRequests > 0 {
+			merged.BlockingRequests = b.BlockingRequests
+		}
+		if b.ImageBytes > 0 {
+			merged.ImageBytes = b.ImageBytes
+		}
+		if b.HTMLBytes > 0 {
+			merged.HTMLBytes = b.HTMLBytes
+		}
+	}
+	return merged, nil
+}
+
+// Measure returns the usage of a manipulated page. Each image is counted
+// once, using the file a browser is most likely to download: the largest
+// candidate of the first source of a picture, otherwise the src or the
+// largest srcset candidate of the img. The size of local images is read from
+// the first static directory that has them. Remote images are not counted
+// and a warning is logged for local images that can't be found.
+func Measure(doc *html.Node, htmlBytes int64, staticDirs ...string) Usage {
+	u := Usage{
+		HTMLBytes: htmlBytes,
+	}
+
+	images := map[string]bool{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attrs := htmlparsing.Attributes(n)
+			switch n.Data {
+			case "style":
+				u.InlineCSSBytes += textBytes(n)
+			case "script":
+				if !scriptTypes[strings.ToLower(attrs["type"].Val)] {
+					break
+				}
+				if _, ok := attrs["src"]; !ok {
+					u.InlineJSBytes += textBytes(n)
+				} else if isBlockingScript(attrs) {
+					u.BlockingRequests++
+				}
+			case "link":
+				if isBlockingStylesheet(attrs) {
+					u.BlockingRequests++
+				}
+			case "img":
+				if i := imageURL(n, attrs); i != "" {
+					images[i] = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for i := range images {
+		u.ImageBytes += imageBytes(staticDirs, i)
+	}
+	return u
+}
+
+// imageURL returns the image an img element is expected to load
+func imageURL(img *html.Node, attrs map[string]html.Attribute) string {
+	if img.Parent != nil && img.Parent.Data == "picture" {
+		for c := img.Parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data != "source" {
+				continue
+			}
+			if l := largestCandidate(htmlparsing.Attributes(c)["srcset"].Val); l != "" {
+				return l
+			}
+			break
+		}
+	}
+	if s, ok := attrs["src"]; ok && s.Val != "" {
+		return s.Val
+	}
+	return largestCandidate(attrs["srcset"].Val)
+}
+
+// largestCandidate returns the URL of the srcset candidate with the largest
+// width or density descriptor
+func largestCandidate(srcset string) string {
+	largest := ""
+	largestSize := -1.0
+	for _, c := range strings.Split(srcset, ",") {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		size := 1.0
+		if len(fields) > 1 {
+			d := fields[1]
+			if v, err := strconv.ParseFloat(d[:len(d)-1], 64); err == nil {
+				size = v
+			}
+		}
+		if size > largestSize {
+			largest = fields[0]
+			largestSize = size
+		}
+	}
+	return largest
+}
+
+// Check returns the budgets the usage exceeds sorted by metric
+func Check(page string, u Usage, b *config.BudgetConfig) []Violation {
+	if b == nil {
+		return nil
+	}
+
+	checks := []struct {
+		metric string
+		value  int64
+		limit  int64
+	}{
+		{BlockingRequests, u.BlockingRequests, b.BlockingRequests},
+		{HTMLBytes, u.HTMLBytes, b.HTMLBytes},
+		{ImageBytes, u.ImageBytes, b.ImageBytes},
+		{InlineCSSBytes, u.InlineCSSBytes, b.InlineCSSBytes},
+		{InlineJSBytes, u.InlineJSBytes, b.InlineJSBytes},
+	}
+
+	violations := []Violation{}
+	for _, c := range checks {
+		if c.limit <= 0 || c.value <= c.limit {
+			continue
+		}
+		violations = append(violations, Violation{
+			Page:   page,
+			Metric: c.metric,
+			Value:  c.value,
+			Limit:  c.limit,
+		})
+	}
+	return violations
+}
+
+// Table returns the violations as a table sorted by page and metric
+func Table(violations []Violation) string {
+	sorted := make([]Violation, len(violations))
+	copy(sorted, violations)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Page != sorted[j].Page {
+			return sorted[i].Page < sorted[j].Page
+		}
+		return sorted[i].Metric < sorted[j].Metric
+	})
+
+	headings := []string{
+		"Page",
+		"Budget",
+		"Value",
+		"Limit",
+	}
+
+	rows := [][]string{}
+	for _, v := range sorted {
+		rows = append(rows, []string{
+			v.Page,
+			v.Metric,
+			fmt.Sprintf("%v", v.Value),
+			fmt.Sprintf("%v", v.Limit),
+		})
+	}
+	return stringui.Table(headings, rows)
+}
+
+func textBytes(n *html.Node) int64 {
+	var size int64
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode || c.Type == html.RawNode {
+			size += int64(len(c.Data))
+		}
+	}
+	return size
+}
+
+func isBlockingScript(attrs map[string]html.Attribute) bool {
+	for _, k := range []string{"async", "defer"} {
+		if _, ok := attrs[k]; ok {
+			return false
+		}
+	}
+	return strings.ToLower(attrs["type"].Val) != "module"
+}
+
+func isBlockingStylesheet(attrs map[string]html.Attribute) bool {
+	if strings.ToLower(attrs["rel"].Val) != "stylesheet" {
+		return false
+	}
+	if _, ok := attrs["href"]; !ok {
+		return false
+	}
+	// Async stylesheets load with media="print" and switch once loaded
+	if _, ok := attrs["onload"]; ok {
+		return false
+	}
+	return attrs["media"].Val != "print"
+}
+
+func imageBytes(staticDirs []string, src string) int64 {
+	if !strings.HasPrefix(src, "/") || strings.HasPrefix(src, "//") {
+		return 0
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return 0
+	}
+	for _, d := range staticDirs {
+		if d == "" {
+			continue
+		}
+		fi, err := osStat(filepath.Join(d, u.Path))
+		if err == nil {
+			return fi.Size()
+		}
+	}
+	log.Printf("Warning: Unable to find image %q to measure its size in %q", src, staticDirs)
+	return 0
+}
@@ -0,0 +1,270 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package budget
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/config"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+var errInjected = errors.New("injected error")
+
+func Test_ForPage(t *testing.T) {
+	tests := []struct {
+		description string
+		budgets     []*config.BudgetConfig
+		page        string
+		want        *config.BudgetConfig
+		wantError   error
+	}{
+		{
+			description: "return nil without budgets",
+			page:        "index.html",
+		},
+		{
+			description: "return nil if no budget matches",
+			budgets: []*config.BudgetConfig{
+				{Path: "blog/*", HTMLBytes: 10},
+			},
+			page: "index.html",
+		},
+		{
+			description: "return error for invalid pattern",
+			budgets: []*config.BudgetConfig{
+				{Path: "[", HTMLBytes: 10},
+			},
+			page:      "index.html",
+			wantError: ErrInvalidPattern,
+		},
+		{
+			description: "match nested pages with ** patterns",
+			budgets: []*config.BudgetConfig{
+				{Path: "blog/*", HTMLBytes: 10},
+				{Path: "blog/**", InlineCSSBytes: 20},
+				{Path: "**/post.html", InlineJSBytes: 30},
+				{Path: "**/index.html", ImageBytes: 40},
+			},
+			page: "blog/2020/post.html",
+			want: &config.BudgetConfig{
+				Path:           "blog/2020/post.html",
+				InlineCSSBytes: 20,
+				InlineJSBytes:  30,
+			},
+		},
+		{
+			description: "match top level pages with leading ** patterns",
+			budgets: []*config.BudgetConfig{
+				{Path: "**/index.html", ImageBytes: 40},
+			},
+			page: "index.html",
+			want: &config.BudgetConfig{
+				Path:       "index.html",
+				ImageBytes: 40,
+			},
+		},
+		{
+			description: "return error for invalid pattern after a ** segment",
+			budgets: []*config.BudgetConfig{
+				{Path: "**/[", HTMLBytes: 10},
+			},
+			page:      "index.html",
+			wantError: ErrInvalidPattern,
+		},
+		{
+			description: "override global limits with matching path budgets",
+			budgets: []*config.BudgetConfig{
+				{HTMLBytes: 10, InlineCSSBytes: 20, BlockingRequests: 2},
+				{Path: "blog/*", InlineCSSBytes: 40},
+				{Path: "docs/*", InlineJSBytes: 5},
+				{Path: "*/index.html", ImageBytes: 1000},
+			},
+			page: "blog/index.html",
+			want: &config.BudgetConfig{
+				Path:             "blog/index.html",
+				HTMLBytes:        10,
+				InlineCSSBytes:   40,
+				BlockingRequests: 2,
+				ImageBytes:       1000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := ForPage(tt.budgets, tt.page)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected budget; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_Measure(t *testing.T) {
+	origStat := osStat
+	t.Cleanup(func() {
+		osStat = origStat
+	})
+	osStat = func(name string) (os.FileInfo, error) {
+		switch name {
+		case "/static/a.png":
+			return fileInfo{size: 100}, nil
+		case "/static/b.webp":
+			return fileInfo{size: 20}, nil
+		case "/static/b-small.webp":
+			return fileInfo{size: 5}, nil
+		case "/gen/d.png":
+			return fileInfo{size: 50}, nil
+		}
+		return nil, errInjected
+	}
+
+	doc := MustGetNode(t, `<html><head>
+<style>.a{}</style>
+<link rel="stylesheet" href="/main.css">
+<link rel="stylesheet" href="/print.css" media="print">
+<link rel="stylesheet" href="/async.css" media="print" onload="this.media='all'">
+<script src="/sync.js"></script>
+<script src="/async.js" async defer></script>
+<script type="module" src="/module.js"></script>
+<script type="application/ld+json">{"a": 1}</script>
+</head><body>
+<script>a();</script>
+<picture><source srcset="/b-small.webp 100w, /b.webp 200w"><source srcset="/a.png 200w"><img src="/a.png?v=1"></picture>
+<img src="/a.png">
+<img srcset="/c.png 1x, /d.png 2x">
+<img src="/missing.png">
+<img src="https://example.com/c.png">
+<svg><style>.b{}</style></svg>
+</body></html>`)
+
+	got := Measure(doc, 500, "/static", "/gen")
+	want := Usage{
+		InlineCSSBytes:   8,
+		InlineJSBytes:    4,
+		BlockingRequests: 2,
+		ImageBytes:       170,
+		HTMLBytes:        500,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Unexpected usage; diff %v", diff)
+	}
+}
+
+func Test_Check(t *testing.T) {
+	tests := []struct {
+		description string
+		usage       Usage
+		budget      *config.BudgetConfig
+		want        []Violation
+	}{
+		{
+			description: "return nothing without a budget",
+			usage:       Usage{HTMLBytes: 100},
+		},
+		{
+			description: "ignore limits of 0 and usage at the limit",
+			usage: Usage{
+				HTMLBytes:      100,
+				InlineCSSBytes: 10,
+			},
+			budget: &config.BudgetConfig{
+				InlineCSSBytes: 10,
+			},
+			want: []Violation{},
+		},
+		{
+			description: "return every exceeded budget",
+			usage: Usage{
+				InlineCSSBytes:   11,
+				InlineJSBytes:    12,
+				BlockingRequests: 3,
+				ImageBytes:       14,
+				HTMLBytes:        15,
+			},
+			budget: &config.BudgetConfig{
+				InlineCSSBytes:   10,
+				InlineJSBytes:    10,
+				BlockingRequests: 2,
+				ImageBytes:       20,
+				HTMLBytes:        10,
+			},
+			want: []Violation{
+				{Page: "index.html", Metric: BlockingRequests, Value: 3, Limit: 2},
+				{Page: "index.html", Metric: HTMLBytes, Value: 15, Limit: 10},
+				{Page: "index.html", Metric: InlineCSSBytes, Value: 11, Limit: 10},
+				{Page: "index.html", Metric: InlineJSBytes, Value: 12, Limit: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := Check("index.html", tt.usage, tt.budget)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected violations; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_Table(t *testing.T) {
+	got := Table([]Violation{
+		{Page: "z.html", Metric: HTMLBytes, Value: 15, Limit: 10},
+		{Page: "a.html", Metric: InlineJSBytes, Value: 12, Limit: 10},
+		{Page: "a.html", Metric: BlockingRequests, Value: 3, Limit: 2},
+	})
+	want := strings.Join([]string{
+		"----------------------------------------------",
+		"| Page   | Budget            | Value | Limit |",
+		"----------------------------------------------",
+		"| a.html | blocking-requests | 3     | 2     |",
+		"| a.html | inline-js-bytes   | 12    | 10    |",
+		"| z.html | html-bytes        | 15    | 10    |",
+		"----------------------------------------------",
+	}, "\n")
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Unexpected table; diff %v", diff)
+	}
+}
+
+type fileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (f fileInfo) Size() int64 {
+	return f.size
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
@@ -30,8 +30,59 @@ type Config struct {
 	// The img-to-picture manipulation config
 	ImgToPicture []*ImgToPicConfig `json:"img-to-picture"`
 
+	// The lazyload manipulation config
+	LazyLoad *LazyLoadConfig `json:"lazy-load"`
+
 	// The ratio-wrapper manipulation config
 	RatioWrapper []string `json:"ratio-wrapper"`
+
+	// The svg handling config
+	SVG *SVGConfig `json:"svg"`
+
+	// The remote image sizing config
+	RemoteImages *RemoteImagesConfig `json:"remote-images"`
+
+	// The lcp-image manipulation config
+	LCPImage *LCPImageConfig `json:"lcp-image"`
+
+	// The resource-hints manipulation config
+	ResourceHints *ResourceHintsConfig `json:"resource-hints"`
+
+	// The web font config
+	Fonts *FontsConfig `json:"fonts"`
+
+	// The asset revisioning config
+	Revision *RevisionConfig `json:"revision"`
+
+	// The asset manifest output config
+	AssetManifest *AssetManifestConfig `json:"asset-manifest"`
+
+	// The service worker precache manifest config
+	Precache *PrecacheConfig `json:"precache"`
+
+	// The asset bundling config
+	Bundle *BundleConfig `json:"bundle"`
+
+	// The CSS and JS minification config
+	Minify *MinifyConfig `json:"minify"`
+
+	// The automatic inlining config for small local assets
+	AutoInline *AutoInlineConfig `json:"auto-inline"`
+
+	// The page size and request budgets
+	Budgets []*BudgetConfig `json:"budgets"`
+
+	// The sitemap generation config
+	Sitemap *SitemapConfig `json:"sitemap"`
+
+	// The social image config
+	Social *SocialConfig `json:"social"`
+
+	// The favicon and web app manifest config
+	Icons *IconsConfig `json:"icons"`
+
+	// The local video config
+	Video *VideoConfig `json:"video"`
 }
 
 // AssetsConfig defines config options for assets
@@ -66,8 +117,223 @@ type ImgToPicConfig struct {
 	MaxWidth int64 `json:"max-width"`
 	// The source sizes the picture should have
 	SourceSizes []string `json:"source-sizes"`
+	// The layout to compute the source sizes from instead of source-sizes
+	Layout *LayoutConfig `json:"layout"`
 	// Class to apply to the picture element
 	Class string `json:"class"`
+	// How sizes are added, "picture" (the default) wraps the img in a picture
+	// with a source per format and "img" adds srcset and sizes to the img for
+	// its original format only
+	Mode string `json:"mode"`
+}
+
+// LayoutConfig describes the container of images to compute their sizes
+type LayoutConfig struct {
+	// The maximum width of the container in CSS pixels
+	MaxWidth int64 `json:"max-width"`
+	// The space in CSS pixels between columns and at either edge of the
+	// container
+	Gutter int64 `json:"gutter"`
+	// The number of columns from a viewport width, one column when empty
+	Columns []*LayoutColumnsConfig `json:"columns"`
+}
+
+// LayoutColumnsConfig defines the number of columns from a viewport width
+type LayoutColumnsConfig struct {
+	// The minimum viewport width in CSS pixels
+	MinWidth int64 `json:"min-width"`
+	// The number of columns
+	Count int64 `json:"count"`
+}
+
+// LazyLoadConfig defines config options for the lazyload manipulation
+type LazyLoadConfig struct {
+	// Add "auto" to the sizes of lazy loaded images so browsers use their
+	// layout width
+	AutoSizes bool `json:"auto-sizes"`
+}
+
+// SVGConfig defines config options for svg images
+type SVGConfig struct {
+	// Inline svg images with a file size at or below this many bytes
+	InlineMaxBytes int64 `json:"inline-max-bytes"`
+	// Strip comments and metadata from svg files
+	Optimize bool `json:"optimize"`
+}
+
+// RemoteImagesConfig defines config options for sizing remote images
+type RemoteImagesConfig struct {
+	// Path to a JSON file caching the sizes of remote images by URL
+	CacheFile string `json:"cache-file"`
+	// Only use sizes from the cache file and never fetch remote images
+	Offline bool `json:"offline"`
+}
+
+// LCPImageConfig defines config options for the lcp-image manipulation
+type LCPImageConfig struct {
+	// Classnames or tags of elements whose first img is the likely LCP image
+	Selectors []string `json:"selectors"`
+	// The number of images in <main> to eagerly load when no selector matches
+	MainImageCount int `json:"main-image-count"`
+}
+
+// ResourceHintsConfig defines config options for the resource-hints manipulation
+type ResourceHintsConfig struct {
+	// The maximum number of preconnect hints per page, other origins get dns-prefetch
+	MaxPreconnect int `json:"max-preconnect"`
+	// Origins or hosts to add hints for, all third-party origins are used when empty
+	Allow []string `json:"allow"`
+}
+
+// FontsConfig defines config options for web fonts
+type FontsConfig struct {
+	// The font-display value to set on every @font-face rule in local CSS
+	FontDisplay string `json:"font-display"`
+	// The maximum number of fonts to preload per page, 2 when 0
+	MaxPreload int `json:"max-preload"`
+	// Subset local fonts to the characters used across all HTML pages
+	Subset bool `json:"subset"`
+}
+
+// RevisionConfig defines config options for revisioning assets
+type RevisionConfig struct {
+	// Additional asset types to revision, i.e. "png", "jpeg", "svg" or "woff2"
+	Types []string `json:"types"`
+	// Path to write a JSON manifest of original to revisioned URLs
+	Manifest string `json:"manifest"`
+}
+
+// AssetManifestConfig defines config options for the asset manifest output
+type AssetManifestConfig struct {
+	// Path to write the manifest.json file to
+	Output string `json:"output"`
+}
+
+// PrecacheConfig defines config options for the service worker precache manifest
+type PrecacheConfig struct {
+	// Path to write the precache manifest to
+	Output string `json:"output"`
+	// The output format, either "json" (the default) or "module"
+	Format string `json:"format"`
+	// Include every HTML page in the manifest
+	HTML bool `json:"html"`
+	// URL patterns an entry must match to be included, all entries when empty
+	Include []string `json:"include"`
+	// URL patterns of entries to leave out
+	Exclude []string `json:"exclude"`
+	// The maximum total size in bytes of precached files, unlimited when 0
+	MaxSize int64 `json:"max-size"`
+}
+
+// BundleConfig defines config options for bundling assets injected on a page
+type BundleConfig struct {
+	// Asset types to bundle, one of "sync-css", "async-css", "sync-js" or
+	// "async-js", all of them are bundled when empty
+	Types []string `json:"types"`
+}
+
+// MinifyConfig defines config options for minifying local CSS and JS
+type MinifyConfig struct {
+	// Minify local CSS files
+	CSS bool `json:"css"`
+	// Minify local JS files
+	JS bool `json:"js"`
+	// Write a source map next to every minified file that isn't inlined
+	SourceMaps bool `json:"source-maps"`
+	// Minify HTML files once every manipulation has run
+	HTML bool `json:"html"`
+}
+
+// AutoInlineConfig defines config options for inlining small local assets
+type AutoInlineConfig struct {
+	// Inline sync CSS and JS files at or below this many bytes
+	AssetMaxBytes int64 `json:"asset-max-bytes"`
+	// Inline images at or below this many bytes as data URIs
+	ImageMaxBytes int64 `json:"image-max-bytes"`
+	// The maximum number of bytes to inline per page, there is no limit when 0
+	PageMaxBytes int64 `json:"page-max-bytes"`
+}
+
+// BudgetConfig defines the limits for pages after manipulation, a limit of 0
+// is not checked
+type BudgetConfig struct {
+	// Glob of HTML file paths relative to html-dir the budget applies to,
+	// every page when empty
+	Path string `json:"path"`
+	// The maximum bytes of inline CSS
+	InlineCSSBytes int64 `json:"inline-css-bytes"`
+	// The maximum bytes of inline JS
+	InlineJSBytes int64 `json:"inline-js-bytes"`
+	// The maximum number of render or parser blocking CSS and JS requests
+	BlockingRequests int64 `json:"blocking-requests"`
+	// The maximum bytes of local images referenced by img and source elements
+	ImageBytes int64 `json:"image-bytes"`
+	// The maximum bytes of the HTML file
+	HTMLBytes int64 `json:"html-bytes"`
+}
+
+// SitemapConfig defines config options for generating a sitemap of the HTML
+// files
+type SitemapConfig struct {
+	// Path to write the sitemap.xml file to, when there are too many URLs for
+	// one sitemap a sitemap index is written here instead
+	Output string `json:"output"`
+	// Add image sitemap entries for images in picture elements
+	Images bool `json:"images"`
+}
+
+// SocialConfig defines config options for social images
+type SocialConfig struct {
+	// The width of generated social images, 1200 when 0
+	Width int `json:"width"`
+	// The height of generated social images, 630 when 0
+	Height int `json:"height"`
+	// Focal points by image path relative to the gen-assets static-dir, i.e.
+	// "/images/example.jpg", the most detailed area is kept for other images
+	FocalPoints map[string]*FocalPoint `json:"focal-points"`
+	// Image path relative to the static-dir to use for pages without a social
+	// image or a suitable content image
+	DefaultImage string `json:"default-image"`
+	// The alt text for the default image
+	DefaultImageAlt string `json:"default-image-alt"`
+}
+
+// FocalPoint defines the point of an image to keep in a crop as fractions of
+// its width and height, where 0,0 is the top left and 1,1 the bottom right
+type FocalPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// IconsConfig defines config options for generating favicons and a web app
+// manifest
+type IconsConfig struct {
+	// Path to a square, high resolution PNG or JPEG to generate the icons from
+	Source string `json:"source"`
+	// Path to a directory in the static-dir to write the icons and manifest to
+	OutputDir string `json:"output-dir"`
+	// The name of the app in the manifest
+	Name string `json:"name"`
+	// The short name of the app in the manifest
+	ShortName string `json:"short-name"`
+	// The start URL in the manifest, "/" when empty
+	StartURL string `json:"start-url"`
+	// The display mode in the manifest, "standalone" when empty
+	Display string `json:"display"`
+	// The theme color in the manifest
+	ThemeColor string `json:"theme-color"`
+	// The background color in the manifest and behind the apple-touch-icon and
+	// maskable icons, "#ffffff" when empty
+	BackgroundColor string `json:"background-color"`
+}
+
+// VideoConfig defines config options for local videos
+type VideoConfig struct {
+	// The time in seconds of the frame genimgs extracts as a poster, 1 second
+	// or half way through shorter videos when 0
+	PosterTime float64 `json:"poster-time"`
+	// Add the poster as an img inside videos for browsers that can't play them
+	Fallback bool `json:"fallback"`
 }
 
 // Get reads and parses a Config file
@@ -99,6 +365,29 @@ func Get(inputPath string) (*Config, error) {
 			conf.Assets.JSONDir = abs(dir, conf.Assets.JSONDir)
 		}
 	}
+	if conf.RemoteImages != nil && conf.RemoteImages.CacheFile != "" {
+		conf.RemoteImages.CacheFile = abs(dir, conf.RemoteImages.CacheFile)
+	}
+	if conf.Revision != nil && conf.Revision.Manifest != "" {
+		conf.Revision.Manifest = abs(dir, conf.Revision.Manifest)
+	}
+	if conf.AssetManifest != nil && conf.AssetManifest.Output != "" {
+		conf.AssetManifest.Output = abs(dir, conf.AssetManifest.Output)
+	}
+	if conf.Precache != nil && conf.Precache.Output != "" {
+		conf.Precache.Output = abs(dir, conf.Precache.Output)
+	}
+	if conf.Sitemap != nil && conf.Sitemap.Output != "" {
+		conf.Sitemap.Output = abs(dir, conf.Sitemap.Output)
+	}
+	if conf.Icons != nil {
+		if conf.Icons.Source != "" {
+			conf.Icons.Source = abs(dir, conf.Icons.Source)
+		}
+		if conf.Icons.OutputDir != "" {
+			conf.Icons.OutputDir = abs(dir, conf.Icons.OutputDir)
+		}
+	}
 	if conf.GenAssets != nil {
 		conf.GenAssets.StaticDir = abs(dir, conf.GenAssets.StaticDir)
 		conf.GenAssets.OutputDir = abs(dir, conf.GenAssets.OutputDir)
@@ -0,0 +1,55 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	contentRegex       = regexp.MustCompile(`(?i)(?:^|[;{\s])content\s*:\s*([^;}]+)`)
+	contentStringRegex = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)'`)
+	cssEscapeRegex     = regexp.MustCompile(`\\(?:([0-9a-fA-F]{1,6})\s?|(.))`)
+)
+
+// ContentText returns the text of the strings in the content properties of a
+// stylesheet, with CSS escapes decoded
+func ContentText(contents string) string {
+	var sb strings.Builder
+	for _, m := range contentRegex.FindAllStringSubmatch(contents, -1) {
+		for _, s := range contentStringRegex.FindAllStringSubmatch(m[1], -1) {
+			sb.WriteString(unescape(s[1] + s[2]))
+		}
+	}
+	return sb.String()
+}
+
+func unescape(s string) string {
+	return cssEscapeRegex.ReplaceAllStringFunc(s, func(e string) string {
+		m := cssEscapeRegex.FindStringSubmatch(e)
+		if m[2] != "" {
+			return m[2]
+		}
+		r, err := strconv.ParseInt(m[1], 16, 32)
+		if err != nil {
+			return ""
+		}
+		return string(rune(r))
+	})
+}
@@ -0,0 +1,61 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ContentText(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        string
+	}{
+		{
+			description: "return nothing without content properties",
+			contents:    `body { font-family: "Example Sans"; }`,
+			want:        "",
+		},
+		{
+			description: "return content strings",
+			contents:    `q::before{content:"“"}.note::after { content: 'Note: ' attr(title) "!"; }`,
+			want:        "“Note: !",
+		},
+		{
+			description: "decode escapes",
+			contents:    `q::before{content:"\201C"}q::after{content:"\201D \"ok\""}`,
+			want:        "“”\"ok\"",
+		},
+		{
+			description: "ignore non-content properties",
+			contents:    `a{align-content:"x";content:none}`,
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := ContentText(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	fontFaceRegex     = regexp.MustCompile(`(?is)@font-face\s*{([^}]*)}`)
+	fontFamilyRegex   = regexp.MustCompile(`(?i)font-family\s*:\s*([^;}]+)`)
+	fontWeightRegex   = regexp.MustCompile(`(?i)font-weight\s*:\s*([^;}]+)`)
+	fontStyleRegex    = regexp.MustCompile(`(?i)font-style\s*:\s*([^;}]+)`)
+	fontStretchRegex  = regexp.MustCompile(`(?i)font-stretch\s*:\s*([^;}]+)`)
+	unicodeRangeRegex = regexp.MustCompile(`(?i)unicode-range\s*:\s*([^;}]+)`)
+	fontDisplayRegex  = regexp.MustCompile(`(?i)font-display\s*:\s*[^;}]+`)
+	fontSrcRegex      = regexp.MustCompile(`(?is)(?:^|[;\s{])src\s*:\s*([^;}]+)`)
+	urlRegex          = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"]*?))\s*\)`)
+	formatRegex       = regexp.MustCompile(`(?i)format\(\s*["']?([^"')]+)["']?\s*\)`)
+)
+
+// FontFace describes a single @font-face rule
+type FontFace struct {
+	Family       string
+	Weight       string
+	Style        string
+	Stretch      string
+	UnicodeRange string
+	Srcs         []FontSrc
+}
+
+// FontSrc is a single url() entry from an @font-face src descriptor
+type FontSrc struct {
+	URL    string
+	Format string
+}
+
+// FontFaces returns the @font-face rules defined in a stylesheet
+func FontFaces(contents string) []FontFace {
+	faces := []FontFace{}
+	for _, m := range fontFaceRegex.FindAllStringSubmatch(contents, -1) {
+		block := m[1]
+
+		ff := FontFace{}
+		if fm := fontFamilyRegex.FindStringSubmatch(block); fm != nil {
+			ff.Family = strings.Trim(strings.TrimSpace(fm[1]), `"'`)
+		}
+		ff.Weight = descriptor(fontWeightRegex, block)
+		ff.Style = descriptor(fontStyleRegex, block)
+		ff.Stretch = descriptor(fontStretchRegex, block)
+		ff.UnicodeRange = descriptor(unicodeRangeRegex, block)
+
+		if sm := fontSrcRegex.FindStringSubmatch(block); sm != nil {
+			for _, entry := range strings.Split(sm[1], ",") {
+				u := URLValue(entry)
+				if u == "" {
+					continue
+				}
+				fs := FontSrc{URL: u}
+				if fm := formatRegex.FindStringSubmatch(entry); fm != nil {
+					fs.Format = strings.ToLower(fm[1])
+				}
+				ff.Srcs = append(ff.Srcs, fs)
+			}
+		}
+
+		faces = append(faces, ff)
+	}
+	return faces
+}
+
+func descriptor(re *regexp.Regexp, block string) string {
+	m := re.FindStringSubmatch(block)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
+
+// SetFontDisplay sets the font-display descriptor of every @font-face rule
+func SetFontDisplay(contents, display string) string {
+	return fontFaceRegex.ReplaceAllStringFunc(contents, func(rule string) string {
+		descriptor := fmt.Sprintf("font-display:%v", display)
+		if fontDisplayRegex.MatchString(rule) {
+			return fontDisplayRegex.ReplaceAllString(rule, descriptor)
+		}
+		i := strings.Index(rule, "{")
+		return rule[:i+1] + descriptor + ";" + rule[i+1:]
+	})
+}
+
+// URLValue returns the URL of the first url() function in a CSS value
+func URLValue(value string) string {
+	return firstGroup(urlRegex.FindStringSubmatch(value))
+}
+
+// ResolveURL resolves a URL referenced from a stylesheet against the URL of
+// the stylesheet itself.
+func ResolveURL(stylesheetURL, ref string) string {
+	r, err := url.Parse(ref)
+	if err != nil || r.IsAbs() || r.Host != "" || strings.HasPrefix(ref, "/") {
+		return ref
+	}
+	resolved := path.Join(path.Dir(stylesheetURL), r.Path)
+	if r.RawQuery != "" {
+		resolved += "?" + r.RawQuery
+	}
+	if r.Fragment != "" {
+		resolved += "#" + r.Fragment
+	}
+	return resolved
+}
@@ -0,0 +1,163 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_FontFaces(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        []FontFace
+	}{
+		{
+			description: "return nothing for css without font faces",
+			contents:    `body { font-family: sans-serif; }`,
+			want:        []FontFace{},
+		},
+		{
+			description: "return font faces with sources and formats",
+			contents: `
+@font-face {
+	font-family: "Example Sans";
+	src: url("../fonts/example.woff2") format("woff2"),
+		url('../fonts/example.woff') format('woff');
+}
+@font-face{font-family:Mono;src:url(/fonts/mono.woff2)}
+@font-face{font-family:Mono;font-weight:700;font-style:italic;font-stretch:condensed;unicode-range:U+0000-00FF;src:url(/fonts/mono.woff2)}
+body { font-family: "Example Sans"; background: url(/bg.png); }`,
+			want: []FontFace{
+				{
+					Family: "Example Sans",
+					Srcs: []FontSrc{
+						{URL: "../fonts/example.woff2", Format: "woff2"},
+						{URL: "../fonts/example.woff", Format: "woff"},
+					},
+				},
+				{
+					Family: "Mono",
+					Srcs: []FontSrc{
+						{URL: "/fonts/mono.woff2"},
+					},
+				},
+				{
+					Family:       "Mono",
+					Weight:       "700",
+					Style:        "italic",
+					Stretch:      "condensed",
+					UnicodeRange: "U+0000-00FF",
+					Srcs: []FontSrc{
+						{URL: "/fonts/mono.woff2"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := FontFaces(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_SetFontDisplay(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		display     string
+		want        string
+	}{
+		{
+			description: "do nothing without font faces",
+			contents:    `body{font-display:block}`,
+			display:     "swap",
+			want:        `body{font-display:block}`,
+		},
+		{
+			description: "add font-display to font faces",
+			contents:    `@font-face{font-family:A;src:url(a.woff2)}`,
+			display:     "swap",
+			want:        `@font-face{font-display:swap;font-family:A;src:url(a.woff2)}`,
+		},
+		{
+			description: "replace existing font-display",
+			contents:    `@font-face { font-family: A; font-display: block; }`,
+			display:     "optional",
+			want:        `@font-face { font-family: A; font-display:optional; }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := SetFontDisplay(tt.contents, tt.display)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_ResolveURL(t *testing.T) {
+	tests := []struct {
+		description   string
+		stylesheetURL string
+		ref           string
+		want          string
+	}{
+		{
+			description:   "return root relative URL",
+			stylesheetURL: "/css/main.css",
+			ref:           "/fonts/a.woff2",
+			want:          "/fonts/a.woff2",
+		},
+		{
+			description:   "return absolute URL",
+			stylesheetURL: "/css/main.css",
+			ref:           "https://example.com/a.woff2",
+			want:          "https://example.com/a.woff2",
+		},
+		{
+			description:   "return data URL",
+			stylesheetURL: "/css/main.css",
+			ref:           "data:font/woff2;base64,AAAA",
+			want:          "data:font/woff2;base64,AAAA",
+		},
+		{
+			description:   "resolve relative URL",
+			stylesheetURL: "/css/main.css",
+			ref:           "../fonts/a.woff2?v=1#iefix",
+			want:          "/fonts/a.woff2?v=1#iefix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := ResolveURL(tt.stylesheetURL, tt.ref)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	commentRegex   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	styleRuleRegex = regexp.MustCompile(`([^{}]+)\{([^{}]*)\}`)
+	fontSizeRegex  = regexp.MustCompile(`(?i)^(?:[0-9.]+[a-z%]*|xx-small|x-small|small|medium|large|x-large|xx-large|xxx-large|smaller|larger)(?:/.*)?$`)
+
+	fontWeightKeywords = map[string]bool{
+		"normal":  true,
+		"bold":    true,
+		"bolder":  true,
+		"lighter": true,
+	}
+)
+
+// FontRule is a style rule that sets any of the font-family, font-weight or
+// font-style properties, directly or with the font shorthand
+type FontRule struct {
+	Selectors []string
+	Families  []string
+	Weight    string
+	Style     string
+}
+
+// FontRules returns the style rules of a stylesheet that set font
+// properties, in order. Rules in @media and @supports blocks are included.
+func FontRules(contents string) []FontRule {
+	contents = commentRegex.ReplaceAllString(contents, "")
+	contents = fontFaceRegex.ReplaceAllString(contents, "")
+
+	rules := []FontRule{}
+	for _, m := range styleRuleRegex.FindAllStringSubmatch(contents, -1) {
+		selector := strings.TrimSpace(m[1])
+		// Drop the prelude of an enclosing block, i.e. "@media print {"
+		if i := strings.LastIndexAny(selector, ";"); i != -1 {
+			selector = strings.TrimSpace(selector[i+1:])
+		}
+		if selector == "" || strings.HasPrefix(selector, "@") {
+			continue
+		}
+
+		r := FontRule{}
+		for _, d := range strings.Split(m[2], ";") {
+			parts := strings.SplitN(d, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(parts[1]), "!important"))
+			switch strings.ToLower(strings.TrimSpace(parts[0])) {
+			case "font-family":
+				r.Families = fontFamilies(value)
+			case "font-weight":
+				r.Weight = strings.ToLower(value)
+			case "font-style":
+				r.Style = strings.ToLower(strings.Fields(value + " normal")[0])
+			case "font":
+				r.Families, r.Weight, r.Style = fontShorthand(value)
+			}
+		}
+		if r.Families == nil && r.Weight == "" && r.Style == "" {
+			continue
+		}
+
+		for _, s := range strings.Split(selector, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				r.Selectors = append(r.Selectors, s)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// fontShorthand returns the families, weight and style of a font shorthand
+// value, which resets weight and style to normal when they are left out
+func fontShorthand(value string) ([]string, string, string) {
+	weight, style := "normal", "normal"
+	fields := strings.Fields(value)
+	for i, f := range fields {
+		lf := strings.ToLower(f)
+		switch {
+		case lf == "italic" || lf == "oblique":
+			style = lf
+		case fontWeightKeywords[lf] && lf != "normal":
+			weight = lf
+		case isNumber(lf):
+			weight = lf
+		case fontSizeRegex.MatchString(lf):
+			rest := fields[i+1:]
+			// The line height may be separated from the size by spaces
+			if len(rest) > 1 && rest[0] == "/" {
+				rest = rest[2:]
+			} else if len(rest) > 0 && strings.HasPrefix(rest[0], "/") {
+				rest = rest[1:]
+			}
+			return fontFamilies(strings.Join(rest, " ")), weight, style
+		}
+	}
+	// System fonts such as "caption" don't set a family
+	return nil, weight, style
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func fontFamilies(value string) []string {
+	families := []string{}
+	for _, f := range strings.Split(value, ",") {
+		f = strings.Trim(strings.TrimSpace(f), `"'`)
+		if f != "" {
+			families = append(families, f)
+		}
+	}
+	return families
+}
+
+// UnicodeRanges returns the ranges of a unicode-range descriptor. Invalid
+// ranges are skipped.
+func UnicodeRanges(value string) [][2]rune {
+	ranges := [][2]rune{}
+	for _, r := range strings.Split(value, ",") {
+		r = strings.ToUpper(strings.TrimSpace(r))
+		if !strings.HasPrefix(r, "U+") {
+			continue
+		}
+		r = r[2:]
+
+		start, end := r, r
+		if i := strings.Index(r, "-"); i != -1 {
+			start, end = r[:i], r[i+1:]
+		} else if strings.Contains(r, "?") {
+			start = strings.ReplaceAll(r, "?", "0")
+			end = strings.ReplaceAll(r, "?", "F")
+		}
+
+		s, err := strconv.ParseInt(start, 16, 32)
+		if err != nil {
+			continue
+		}
+		e, err := strconv.ParseInt(end, 16, 32)
+		if err != nil {
+			continue
+		}
+		ranges = append(ranges, [2]rune{rune(s), rune(e)})
+	}
+	return ranges
+}
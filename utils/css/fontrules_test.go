@@ -0,0 +1,75 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_FontRules(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        []FontRule
+	}{
+		{
+			description: "return nothing without font properties",
+			contents:    `body{color:red}@font-face{font-family:Sans;font-weight:700;src:url(a.woff2)}`,
+			want:        []FontRule{},
+		},
+		{
+			description: "return font properties of style rules",
+			contents: `/* h1{font-weight:900} */
+@import url(base.css);
+body, .page { font-family: "Example Sans", sans-serif; color: red }
+h1{font-weight:700!important}
+@media (min-width: 40em) {
+	em { font-style: italic }
+}
+.quote{font:italic 600 1.2rem/1.5 Serif}
+.small{font: 12px / 1.2 'Mono'}
+.caption{font:caption}`,
+			want: []FontRule{
+				{Selectors: []string{"body", ".page"}, Families: []string{"Example Sans", "sans-serif"}},
+				{Selectors: []string{"h1"}, Weight: "700"},
+				{Selectors: []string{"em"}, Style: "italic"},
+				{Selectors: []string{".quote"}, Families: []string{"Serif"}, Weight: "600", Style: "italic"},
+				{Selectors: []string{".small"}, Families: []string{"Mono"}, Weight: "normal", Style: "normal"},
+				{Selectors: []string{".caption"}, Weight: "normal", Style: "normal"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := FontRules(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_UnicodeRanges(t *testing.T) {
+	got := UnicodeRanges("U+0000-00FF, U+0131, u+4??, invalid, U+XYZ")
+	want := [][2]rune{{0x0, 0xFF}, {0x131, 0x131}, {0x400, 0x4FF}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Unexpected result; diff %v", diff)
+	}
+}
@@ -0,0 +1,74 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	importStringRegex = regexp.MustCompile(`(?i)@import\s+(?:"([^"]*)"|'([^']*)')`)
+)
+
+// References returns the URLs referenced by url() functions and @import rules
+// in a stylesheet, in order and without duplicates
+func References(contents string) []string {
+	refs := []string{}
+	seen := map[string]bool{}
+	ReplaceReferences(contents, func(ref string) string {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+		return ref
+	})
+	return refs
+}
+
+// ReplaceReferences replaces every URL referenced by url() functions and
+// @import rules in a stylesheet with the result of fn
+func ReplaceReferences(contents string, fn func(ref string) string) string {
+	replace := func(re *regexp.Regexp) func(string) string {
+		return func(match string) string {
+			ref := firstGroup(re.FindStringSubmatch(match))
+			if ref == "" {
+				return match
+			}
+			updated := fn(ref)
+			if updated == ref {
+				return match
+			}
+			return strings.Replace(match, ref, updated, 1)
+		}
+	}
+
+	contents = urlRegex.ReplaceAllStringFunc(contents, replace(urlRegex))
+	return importStringRegex.ReplaceAllStringFunc(contents, replace(importStringRegex))
+}
+
+func firstGroup(m []string) string {
+	if m == nil {
+		return ""
+	}
+	for _, g := range m[1:] {
+		if g != "" {
+			return strings.TrimSpace(g)
+		}
+	}
+	return ""
+}
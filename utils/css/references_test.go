@@ -0,0 +1,85 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package css
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_References(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        []string
+	}{
+		{
+			description: "return nothing without references",
+			contents:    `body{color:red}`,
+			want:        []string{},
+		},
+		{
+			description: "return url and import references",
+			contents:    `@import "base-sync.css";@import url('print.css') print;body{background:url(../img/bg.png)}.a{background:url("../img/bg.png")}`,
+			want:        []string{"print.css", "../img/bg.png", "base-sync.css"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := References(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_ReplaceReferences(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        string
+	}{
+		{
+			description: "leave unmatched references",
+			contents:    `body{background:url(/other.png)}`,
+			want:        `body{background:url(/other.png)}`,
+		},
+		{
+			description: "replace url and import references keeping quotes",
+			contents:    `@import "base.css";@import url('base.css');body{background:url(../img/base.png)}`,
+			want:        `@import "base.1234.css";@import url('base.1234.css');body{background:url(../img/base.1234.png)}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := ReplaceReferences(tt.contents, func(ref string) string {
+				if !strings.Contains(ref, "base.") {
+					return ref
+				}
+				return strings.Replace(ref, "base.", "base.1234.", 1)
+			})
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -74,3 +75,36 @@ func Hash(filepath string) (string, error) {
 
 	return hex.EncodeToString(h.Sum(nil))[0:7], nil
 }
+
+// MatchPath reports whether a slash separated name matches the pattern, where
+// a `**` segment matches zero or more path segments and other segments are
+// matched with path.Match
+func MatchPath(pattern, name string) (bool, error) {
+	patterns := strings.Split(pattern, "/")
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return false, err
+		}
+	}
+	return matchSegments(patterns, strings.Split(name, "/")), nil
+}
+
+func matchSegments(patterns, names []string) bool {
+	if len(patterns) == 0 {
+		return len(names) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(names); i++ {
+			if matchSegments(patterns[1:], names[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(names) == 0 {
+		return false
+	}
+	// The pattern has been validated so errors can't happen
+	m, _ := path.Match(patterns[0], names[0])
+	return m && matchSegments(patterns[1:], names[1:])
+}
@@ -175,6 +175,35 @@ func Test_Hash(t *testing.T) {
 	}
 }
 
+func Test_MatchPath(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		name      string
+		want      bool
+		wantError bool
+	}{
+		{pattern: "blog/*.html", name: "blog/index.html", want: true},
+		{pattern: "blog/*.html", name: "blog/2020/index.html", want: false},
+		{pattern: "blog/**", name: "blog/2020/index.html", want: true},
+		{pattern: "**/index.html", name: "index.html", want: true},
+		{pattern: "/**/index.html", name: "/blog/2020/index.html", want: true},
+		{pattern: "/css/**/*.css", name: "/js/main.js", want: false},
+		{pattern: "**/[", name: "index.html", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := MatchPath(tt.pattern, tt.name)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Unexpected error; got %v, want error %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("Unexpected result; got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 type fileInfoStub struct {
 	os.FileInfo
 
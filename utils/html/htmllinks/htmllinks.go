@@ -0,0 +1,182 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package htmllinks checks that the links between a set of HTML files and the
+// files they reference resolve, without any network access
+package htmllinks
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"github.com/gauntface/go-html-asset-manager/v5/utils/sets"
+	"golang.org/x/net/html"
+)
+
+// The reasons a link is broken
+const (
+	MissingFile     = "missing-file"
+	MissingFragment = "missing-fragment"
+)
+
+// Page is a parsed HTML file
+type Page struct {
+	// The URL path of the page, i.e. /blog/index.html
+	URL string
+	Doc *html.Node
+}
+
+// Broken is a link that doesn't resolve
+type Broken struct {
+	// The URL path of the page the link is on
+	Page string
+	// The link as written in the page
+	URL string
+	// The URL path the link resolves to
+	Path   string
+	Reason string
+	// The first element on the page with the link
+	Node *html.Node
+}
+
+// Check returns the broken href, src and srcset links in the pages. Links to
+// other origins are skipped unless they start with the base URL. exists
+// returns true if a URL path is a file, directories resolve to their
+// index.html.
+func Check(pages []Page, baseURL string, exists func(urlPath string) bool) []Broken {
+	ids := map[string]sets.StringSet{}
+	for _, p := range pages {
+		ids[p.URL] = pageIDs(p.Doc)
+	}
+
+	broken := []Broken{}
+	for _, p := range pages {
+		seen := sets.NewStringSet()
+		for _, lk := range links(p.Doc) {
+			l := lk.url
+			if seen.Contains(l) {
+				continue
+			}
+			seen.Add(l)
+
+			target, fragment, ok := resolve(p.URL, baseURL, l)
+			if !ok {
+				continue
+			}
+
+			file := target
+			if !exists(file) {
+				file = path.Join(target, "index.html")
+				if !exists(file) {
+					broken = append(broken, Broken{Page: p.URL, URL: l, Path: target, Reason: MissingFile, Node: lk.node})
+					continue
+				}
+			}
+
+			if fragment == "" || fragment == "top" {
+				continue
+			}
+			targetIDs, ok := ids[file]
+			if !ok {
+				// Fragments are only checked for HTML files
+				continue
+			}
+			if !targetIDs.Contains(fragment) {
+				broken = append(broken, Broken{Page: p.URL, URL: l, Path: file, Reason: MissingFragment, Node: lk.node})
+			}
+		}
+	}
+	return broken
+}
+
+type link struct {
+	url  string
+	node *html.Node
+}
+
+// links returns the href, src and srcset URLs in a document in order
+func links(doc *html.Node) []link {
+	ls := []link{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, a := range n.Attr {
+				switch a.Key {
+				case "href", "src":
+					if strings.TrimSpace(a.Val) != "" {
+						ls = append(ls, link{url: strings.TrimSpace(a.Val), node: n})
+					}
+				case "srcset":
+					for _, u := range htmlparsing.SrcsetURLs(a.Val) {
+						ls = append(ls, link{url: u, node: n})
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return ls
+}
+
+// pageIDs returns the ids and anchor names a fragment can point at
+func pageIDs(doc *html.Node) sets.StringSet {
+	ids := sets.NewStringSet()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attrs := htmlparsing.Attributes(n)
+			if id, ok := attrs["id"]; ok {
+				ids.Add(id.Val)
+			}
+			if name, ok := attrs["name"]; ok && n.Data == "a" {
+				ids.Add(name.Val)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return ids
+}
+
+// resolve returns the URL path and fragment a link points at, ok is false
+// for links that can't be checked locally
+func resolve(pageURL, baseURL, link string) (string, string, bool) {
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", "", false
+	}
+
+	if ref.Scheme != "" || ref.Host != "" {
+		base, err := url.Parse(baseURL)
+		if baseURL == "" || err != nil || ref.Host != base.Host || (ref.Scheme != "" && ref.Scheme != base.Scheme) {
+			return "", "", false
+		}
+		ref = &url.URL{Path: ref.Path, Fragment: ref.Fragment}
+		if ref.Path == "" {
+			ref.Path = "/"
+		}
+	}
+
+	resolved := (&url.URL{Path: pageURL}).ResolveReference(ref)
+	return resolved.Path, resolved.Fragment, true
+}
@@ -0,0 +1,124 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package htmllinks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/net/html"
+)
+
+func Test_Check(t *testing.T) {
+	files := map[string]bool{
+		"/index.html":      true,
+		"/blog/index.html": true,
+		"/img/a.png":       true,
+		"/img/b.png":       true,
+		"/doc.pdf":         true,
+	}
+	exists := func(p string) bool {
+		return files[p]
+	}
+
+	tests := []struct {
+		description string
+		pages       []Page
+		baseURL     string
+		want        []Broken
+	}{
+		{
+			description: "return nothing for valid links",
+			pages: []Page{
+				{
+					URL: "/index.html",
+					Doc: MustGetNode(t, `<h1 id="top-heading">A</h1>
+<a href="#top-heading">A</a>
+<a href="#top">Top</a>
+<a href="#">Empty</a>
+<a href="blog/#post">Blog</a>
+<a href="/blog">Blog</a>
+<a href="https://www.example.com/blog/index.html">Blog</a>
+<a href="/doc.pdf#page=2">Doc</a>
+<a href="https://other.com/missing">Other</a>
+<a href="mailto:a@example.com">Mail</a>
+<img src="img/a.png?v=1" srcset="/img/a.png 1x, img/b.png 2x">`),
+				},
+				{
+					URL: "/blog/index.html",
+					Doc: MustGetNode(t, `<a name="post"></a><a href="../">Home</a><img src="../img/a.png">`),
+				},
+			},
+			baseURL: "https://www.example.com/",
+			want:    []Broken{},
+		},
+		{
+			description: "return missing files and fragments once per page",
+			pages: []Page{
+				{
+					URL: "/index.html",
+					Doc: MustGetNode(t, `<a href="#missing">A</a>
+<a href="/blog/#missing">Blog</a>
+<a href="/missing/">Missing</a>
+<a href="https://www.example.com/missing.html">Missing</a>
+<img src="/img/c.png" srcset="/img/a.png 1x, /img/d.png 2x">
+<img src="/img/c.png">`),
+				},
+				{
+					URL: "/blog/index.html",
+					Doc: MustGetNode(t, `<img src="img/a.png">`),
+				},
+			},
+			baseURL: "https://www.example.com",
+			want: []Broken{
+				{Page: "/index.html", URL: "#missing", Path: "/index.html", Reason: MissingFragment},
+				{Page: "/index.html", URL: "/blog/#missing", Path: "/blog/index.html", Reason: MissingFragment},
+				{Page: "/index.html", URL: "/missing/", Path: "/missing/", Reason: MissingFile},
+				{Page: "/index.html", URL: "https://www.example.com/missing.html", Path: "/missing.html", Reason: MissingFile},
+				{Page: "/index.html", URL: "/img/c.png", Path: "/img/c.png", Reason: MissingFile},
+				{Page: "/index.html", URL: "/img/d.png", Path: "/img/d.png", Reason: MissingFile},
+				{Page: "/blog/index.html", URL: "img/a.png", Path: "/blog/img/a.png", Reason: MissingFile},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := Check(tt.pages, tt.baseURL, exists)
+			if diff := cmp.Diff(got, tt.want, cmpopts.IgnoreFields(Broken{}, "Node")); diff != "" {
+				t.Fatalf("Unexpected broken links; diff %v", diff)
+			}
+			for _, b := range got {
+				if b.Node == nil {
+					t.Fatalf("No element for broken link %q", b.URL)
+				}
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
@@ -0,0 +1,270 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package htmllint audits HTML for common accessibility and performance
+// issues without modifying it
+package htmllint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlparsing"
+	"golang.org/x/net/html"
+)
+
+// The levels of issues, matching the levels used by SARIF
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Rule is a single check run against every page
+type Rule struct {
+	ID          string
+	Level       string
+	Description string
+
+	check func(doc *html.Node) []finding
+}
+
+// Issue is a problem found by a rule, with the position of the element it is
+// about when it is known
+type Issue struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// finding is a message about an element found by a rule's check
+type finding struct {
+	node    *html.Node
+	message string
+}
+
+// Rules are the checks run by Lint in order
+var Rules = []Rule{
+	{
+		ID:          "img-alt",
+		Level:       LevelError,
+		Description: "Images must have an alt attribute, use an empty alt for decorative images",
+		check:       imgAlt,
+	},
+	{
+		ID:          "iframe-title",
+		Level:       LevelError,
+		Description: "Iframes must have a title describing their content",
+		check:       iframeTitle,
+	},
+	{
+		ID:          "html-lang",
+		Level:       LevelError,
+		Description: "The html element must have a lang attribute",
+		check:       htmlLang,
+	},
+	{
+		ID:          "duplicate-id",
+		Level:       LevelError,
+		Description: "Element IDs must be unique within a page",
+		check:       duplicateID,
+	},
+	{
+		ID:          "heading-order",
+		Level:       LevelWarning,
+		Description: "Heading levels should only increase by one",
+		check:       headingOrder,
+	},
+	{
+		ID:          "head-blocking-script",
+		Level:       LevelWarning,
+		Description: "Scripts in the head should be async, deferred or modules so they don't block rendering",
+		check:       headBlockingScript,
+	},
+	{
+		ID:          "lazy-first-image",
+		Level:       LevelWarning,
+		Description: "The first image on a page is likely above the fold and shouldn't be lazy loaded",
+		check:       lazyFirstImage,
+	},
+	{
+		ID:          "img-size",
+		Level:       LevelWarning,
+		Description: "Images should have width and height attributes to avoid layout shifts",
+		check:       imgSize,
+	},
+}
+
+// Lint returns the issues found in a document by every rule. Issues are
+// given the position of their element from positions, which may be nil.
+func Lint(doc *html.Node, positions map[*html.Node]Position) []Issue {
+	issues := []Issue{}
+	for _, r := range Rules {
+		for _, f := range r.check(doc) {
+			p := positions[f.node]
+			issues = append(issues, Issue{
+				Rule:    r.ID,
+				Level:   r.Level,
+				Message: f.message,
+				Line:    p.Line,
+				Column:  p.Column,
+			})
+		}
+	}
+	return issues
+}
+
+func imgAlt(doc *html.Node) []finding {
+	fs := []finding{}
+	for _, n := range htmlparsing.FindNodesByTag("img", doc) {
+		if _, ok := htmlparsing.Attributes(n)["alt"]; !ok {
+			fs = append(fs, finding{n, fmt.Sprintf("%v has no alt attribute", describe(n))})
+		}
+	}
+	return fs
+}
+
+func iframeTitle(doc *html.Node) []finding {
+	fs := []finding{}
+	for _, n := range htmlparsing.FindNodesByTag("iframe", doc) {
+		if strings.TrimSpace(htmlparsing.Attributes(n)["title"].Val) == "" {
+			fs = append(fs, finding{n, fmt.Sprintf("%v has no title", describe(n))})
+		}
+	}
+	return fs
+}
+
+func htmlLang(doc *html.Node) []finding {
+	n := htmlparsing.FindNodeByTag("html", doc)
+	if n == nil || strings.TrimSpace(htmlparsing.Attributes(n)["lang"].Val) == "" {
+		return []finding{{n, "<html> has no lang attribute"}}
+	}
+	return nil
+}
+
+func duplicateID(doc *html.Node) []finding {
+	counts := map[string]int{}
+	// The first duplicate of each id is the element reported
+	duplicates := map[string]*html.Node{}
+	order := []string{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if id, ok := htmlparsing.Attributes(n)["id"]; ok && id.Val != "" {
+				if counts[id.Val] == 0 {
+					order = append(order, id.Val)
+				}
+				if counts[id.Val] == 1 {
+					duplicates[id.Val] = n
+				}
+				counts[id.Val]++
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	fs := []finding{}
+	for _, id := range order {
+		if counts[id] > 1 {
+			fs = append(fs, finding{duplicates[id], fmt.Sprintf("id %q is used by %v elements", id, counts[id])})
+		}
+	}
+	return fs
+}
+
+func headingOrder(doc *html.Node) []finding {
+	fs := []finding{}
+	prev := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6' {
+			level := int(n.Data[1] - '0')
+			if prev > 0 && level > prev+1 {
+				fs = append(fs, finding{n, fmt.Sprintf("<h%v> follows <h%v>, skipping a level", level, prev)})
+			}
+			prev = level
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return fs
+}
+
+func headBlockingScript(doc *html.Node) []finding {
+	head := htmlparsing.FindNodeByTag("head", doc)
+	if head == nil {
+		return nil
+	}
+
+	fs := []finding{}
+	for _, n := range htmlparsing.FindNodesByTag("script", head) {
+		attrs := htmlparsing.Attributes(n)
+		if _, ok := attrs["src"]; !ok {
+			continue
+		}
+		_, async := attrs["async"]
+		_, deferred := attrs["defer"]
+		if async || deferred || attrs["type"].Val == "module" {
+			continue
+		}
+		fs = append(fs, finding{n, fmt.Sprintf("%v blocks rendering", describe(n))})
+	}
+	return fs
+}
+
+func lazyFirstImage(doc *html.Node) []finding {
+	body := htmlparsing.FindNodeByTag("body", doc)
+	if body == nil {
+		return nil
+	}
+	n := htmlparsing.FindNodeByTag("img", body)
+	if n == nil || htmlparsing.Attributes(n)["loading"].Val != "lazy" {
+		return nil
+	}
+	return []finding{{n, fmt.Sprintf("%v is the first image and is lazy loaded", describe(n))}}
+}
+
+func imgSize(doc *html.Node) []finding {
+	fs := []finding{}
+	for _, n := range htmlparsing.FindNodesByTag("img", doc) {
+		attrs := htmlparsing.Attributes(n)
+		_, w := attrs["width"]
+		_, h := attrs["height"]
+		if w && h {
+			continue
+		}
+		fs = append(fs, finding{n, fmt.Sprintf("%v has no width or height", describe(n))})
+	}
+	return fs
+}
+
+// describe returns a short description of an element to identify it in a
+// message
+func describe(n *html.Node) string {
+	attrs := htmlparsing.Attributes(n)
+	for _, k := range []string{"src", "id", "class"} {
+		if a, ok := attrs[k]; ok && a.Val != "" {
+			return fmt.Sprintf("<%v %v=%q>", n.Data, k, a.Val)
+		}
+	}
+	return fmt.Sprintf("<%v>", n.Data)
+}
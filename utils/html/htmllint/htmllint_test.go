@@ -0,0 +1,83 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package htmllint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_Lint(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        []Issue
+	}{
+		{
+			description: "return nothing for a page without issues",
+			contents: `<html lang="en"><head><script src="/a.js" defer></script><script type="module" src="/b.js"></script><script>a();</script></head><body>
+<h1 id="a">A</h1><h2 id="b">B</h2><h3>C</h3><h2>D</h2>
+<img src="/a.png" alt="" width="1" height="1">
+<img src="/b.png" alt="B" width="1" height="1" loading="lazy">
+<iframe src="https://example.com" title="Example"></iframe>
+</body></html>`,
+			want: []Issue{},
+		},
+		{
+			description: "return issues for every rule",
+			contents: `<html><head><script src="/a.js"></script></head><body>
+<h1 id="a">A</h1><h3 id="a">C</h3>
+<img src="/a.png" loading="lazy">
+<iframe src="https://example.com"></iframe>
+<p class="a" id="a"></p>
+</body></html>`,
+			want: []Issue{
+				{Rule: "img-alt", Level: LevelError, Message: `<img src="/a.png"> has no alt attribute`, Line: 3, Column: 1},
+				{Rule: "iframe-title", Level: LevelError, Message: `<iframe src="https://example.com"> has no title`, Line: 4, Column: 1},
+				{Rule: "html-lang", Level: LevelError, Message: `<html> has no lang attribute`, Line: 1, Column: 1},
+				{Rule: "duplicate-id", Level: LevelError, Message: `id "a" is used by 3 elements`, Line: 2, Column: 18},
+				{Rule: "heading-order", Level: LevelWarning, Message: `<h3> follows <h1>, skipping a level`, Line: 2, Column: 18},
+				{Rule: "head-blocking-script", Level: LevelWarning, Message: `<script src="/a.js"> blocks rendering`, Line: 1, Column: 13},
+				{Rule: "lazy-first-image", Level: LevelWarning, Message: `<img src="/a.png"> is the first image and is lazy loaded`, Line: 3, Column: 1},
+				{Rule: "img-size", Level: LevelWarning, Message: `<img src="/a.png"> has no width or height`, Line: 3, Column: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			doc := MustGetNode(t, tt.contents)
+			got := Lint(doc, Positions(tt.contents, doc))
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected issues; diff %v", diff)
+			}
+		})
+	}
+}
+
+func MustGetNode(t *testing.T, input string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
@@ -0,0 +1,107 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package htmllint
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// Elements the parser adds when their start tag is left out
+	impliedTags = map[string]bool{
+		"html":     true,
+		"head":     true,
+		"body":     true,
+		"tbody":    true,
+		"colgroup": true,
+		"tr":       true,
+	}
+)
+
+// Position is the line and column of an element's start tag, counted from 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+type startTag struct {
+	name     string
+	position Position
+}
+
+// Positions returns the position in contents of the start tag of each
+// element in doc, the document parsed from contents. Elements added by the
+// parser have no position.
+func Positions(contents string, doc *html.Node) map[*html.Node]Position {
+	tags := startTags(contents)
+	positions := map[*html.Node]Position{}
+
+	i := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			name := strings.ToLower(n.Data)
+
+			// Skip over tags the parser ignored, unless the element may
+			// have been added by the parser instead
+			j := i
+			if !impliedTags[name] {
+				for j < len(tags) && tags[j].name != name {
+					j++
+				}
+			}
+			if j < len(tags) && tags[j].name == name {
+				positions[n] = tags[j].position
+				i = j + 1
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return positions
+}
+
+// startTags returns the start tags in contents in order
+func startTags(contents string) []startTag {
+	tags := []startTag{}
+	z := html.NewTokenizer(strings.NewReader(contents))
+	p := Position{Line: 1, Column: 1}
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return tags
+		}
+
+		raw := string(z.Raw())
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			name, _ := z.TagName()
+			tags = append(tags, startTag{name: strings.ToLower(string(name)), position: p})
+		}
+
+		if n := strings.Count(raw, "\n"); n > 0 {
+			p.Line += n
+			p.Column = 1
+			raw = raw[strings.LastIndex(raw, "\n")+1:]
+		}
+		p.Column += utf8.RuneCountInString(raw)
+	}
+}
@@ -0,0 +1,81 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package htmllint
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_Positions(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        map[string]Position
+	}{
+		{
+			description: "return the position of each start tag",
+			contents:    "<!DOCTYPE html>\n<html lang=\"en\">\n<head><title>é <b></title></head>\n<body>\n  <p id=\"a\">Ünïcode <img id=\"b\" src=\"/a.png\"/></p>\n</body></html>",
+			want: map[string]Position{
+				"html":  {Line: 2, Column: 1},
+				"head":  {Line: 3, Column: 1},
+				"title": {Line: 3, Column: 7},
+				"body":  {Line: 4, Column: 1},
+				"p#a":   {Line: 5, Column: 3},
+				"img#b": {Line: 5, Column: 21},
+			},
+		},
+		{
+			description: "skip elements added by the parser and tags it ignores",
+			contents:    "<title>A</title>\n<body><table><tr><td id=\"a\">A</td></tr></table><body class=\"x\"><svg><linearGradient id=\"b\"/></svg>",
+			want: map[string]Position{
+				"title":            {Line: 1, Column: 1},
+				"body":             {Line: 2, Column: 1},
+				"table":            {Line: 2, Column: 7},
+				"tr":               {Line: 2, Column: 14},
+				"td#a":             {Line: 2, Column: 18},
+				"svg":              {Line: 2, Column: 64},
+				"linearGradient#b": {Line: 2, Column: 69},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			doc := MustGetNode(t, tt.contents)
+
+			got := map[string]Position{}
+			for n, p := range Positions(tt.contents, doc) {
+				got[name(n)] = p
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected positions; diff %v", diff)
+			}
+		})
+	}
+}
+
+func name(n *html.Node) string {
+	for _, a := range n.Attr {
+		if a.Key == "id" {
+			return n.Data + "#" + a.Val
+		}
+	}
+	return n.Data
+}
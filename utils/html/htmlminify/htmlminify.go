@@ -0,0 +1,316 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package htmlminify renders HTML without unneeded whitespace, comments,
+// closing tags and attribute quotes
+package htmlminify
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/sets"
+	"golang.org/x/net/html"
+)
+
+var (
+	whitespaceRegex = regexp.MustCompile(`[ \t\n\r\f]+`)
+
+	voidElements = sets.NewStringSet(
+		"area", "base", "br", "col", "embed", "hr", "img", "input", "link",
+		"meta", "source", "track", "wbr",
+	)
+
+	// Elements whose children are rendered as is
+	rawTextElements = sets.NewStringSet(
+		"iframe", "noembed", "noframes", "noscript", "plaintext", "script",
+		"style", "xmp",
+	)
+
+	// Elements where whitespace is significant
+	preformattedElements = sets.NewStringSet("pre", "textarea", "code", "listing")
+
+	// Elements where whitespace between children is never rendered
+	whitespaceInsensitiveElements = sets.NewStringSet(
+		"html", "head", "table", "thead", "tbody", "tfoot", "tr", "colgroup",
+		"ul", "ol", "dl", "select", "optgroup", "datalist",
+	)
+
+	blockElements = sets.NewStringSet(
+		"address", "article", "aside", "blockquote", "body", "details",
+		"dialog", "dd", "div", "dl", "dt", "fieldset", "figcaption", "figure",
+		"footer", "form", "h1", "h2", "h3", "h4", "h5", "h6", "head",
+		"header", "hgroup", "hr", "html", "li", "link", "main", "meta", "nav",
+		"ol", "p", "pre", "script", "section", "style", "table", "tbody",
+		"td", "tfoot", "th", "thead", "title", "tr", "ul",
+	)
+
+	// Elements after which a p element is closed
+	pClosers = sets.NewStringSet(
+		"address", "article", "aside", "blockquote", "details", "div", "dl",
+		"fieldset", "figcaption", "figure", "footer", "form", "h1", "h2",
+		"h3", "h4", "h5", "h6", "header", "hgroup", "hr", "main", "menu",
+		"nav", "ol", "p", "pre", "section", "table", "ul",
+	)
+
+	// Parents in which a p element's end tag is needed when it's the last
+	// child, along with autonomous custom elements
+	pKeepParents = sets.NewStringSet(
+		"a", "audio", "del", "ins", "map", "noscript", "video",
+	)
+)
+
+// Render writes the minified HTML of a node and its children. Text is
+// expected to be escaped already, as done by htmlencoding.EncodeNodes, or be
+// a text node that is escaped when rendered.
+func Render(w io.Writer, n *html.Node) error {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	if err := render(bw, n); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func render(w *bufio.Writer, n *html.Node) error {
+	switch n.Type {
+	case html.DocumentNode:
+		return renderChildren(w, n)
+	case html.TextNode, html.RawNode:
+		if rawMarkup(n) {
+			_, err := w.WriteString(n.Data)
+			return err
+		}
+		return renderText(w, n)
+	case html.CommentNode:
+		if !keepComment(n) {
+			return nil
+		}
+		_, err := w.WriteString("<!--" + n.Data + "-->")
+		return err
+	case html.ElementNode:
+		if n.Namespace != "" {
+			// SVG and MathML have their own rules for self closing and
+			// end tags
+			return html.Render(w, n)
+		}
+		return renderElement(w, n)
+	}
+	return html.Render(w, n)
+}
+
+func renderChildren(w *bufio.Writer, n *html.Node) error {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := render(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderElement(w *bufio.Writer, n *html.Node) error {
+	w.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		w.WriteByte(' ')
+		if a.Namespace != "" {
+			w.WriteString(a.Namespace + ":")
+		}
+		w.WriteString(a.Key)
+		if a.Val == "" {
+			continue
+		}
+		w.WriteByte('=')
+		w.WriteString(attributeValue(a.Val))
+	}
+	w.WriteByte('>')
+
+	if voidElements.Contains(n.Data) {
+		return nil
+	}
+
+	if rawTextElements.Contains(n.Data) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode || c.Type == html.RawNode {
+				w.WriteString(c.Data)
+				continue
+			}
+			if err := render(w, c); err != nil {
+				return err
+			}
+		}
+	} else {
+		// The parser drops a newline directly after these start tags
+		if (n.Data == "pre" || n.Data == "listing" || n.Data == "textarea") &&
+			n.FirstChild != nil && (n.FirstChild.Type == html.TextNode || n.FirstChild.Type == html.RawNode) &&
+			strings.HasPrefix(n.FirstChild.Data, "\n") {
+			w.WriteByte('\n')
+		}
+		if err := renderChildren(w, n); err != nil {
+			return err
+		}
+	}
+
+	if omitEndTag(n) {
+		return nil
+	}
+	_, err := w.WriteString("</" + n.Data + ">")
+	return err
+}
+
+func renderText(w *bufio.Writer, n *html.Node) error {
+	data := n.Data
+	if n.Type == html.TextNode {
+		data = html.EscapeString(data)
+	}
+
+	if !preformatted(n) {
+		if droppedText(n) {
+			return nil
+		}
+		data = whitespaceRegex.ReplaceAllString(data, " ")
+	}
+	_, err := w.WriteString(data)
+	return err
+}
+
+// attributeValue returns the value quoted if the unquoted form would be
+// parsed differently
+func attributeValue(v string) string {
+	if !strings.ContainsAny(v, " \t\n\r\f\"'=<>`&") {
+		return v
+	}
+	v = strings.ReplaceAll(v, "&", "&amp;")
+	return `"` + strings.ReplaceAll(v, `"`, "&quot;") + `"`
+}
+
+// keepComment returns true for conditional comments and comments starting
+// with ! that hold licences
+func keepComment(n *html.Node) bool {
+	d := strings.TrimSpace(n.Data)
+	return strings.HasPrefix(d, "[if") || strings.HasSuffix(d, "<![endif]") || strings.HasPrefix(d, "!")
+}
+
+// rawMarkup returns true for raw nodes holding markup rather than escaped
+// text, such as the meta elements written by htmlencoding.EncodeNodes
+func rawMarkup(n *html.Node) bool {
+	return n.Type == html.RawNode && strings.HasPrefix(n.Data, "<")
+}
+
+func preformatted(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && (preformattedElements.Contains(p.Data) || rawTextElements.Contains(p.Data)) {
+			return true
+		}
+	}
+	return false
+}
+
+// droppedText returns true for whitespace only text that doesn't affect
+// rendering
+func droppedText(n *html.Node) bool {
+	if (n.Type != html.TextNode && n.Type != html.RawNode) || rawMarkup(n) || strings.TrimSpace(n.Data) != "" || preformatted(n) {
+		return false
+	}
+	if n.Parent != nil && n.Parent.Type == html.ElementNode && whitespaceInsensitiveElements.Contains(n.Parent.Data) {
+		return true
+	}
+	if n.Parent != nil && n.Parent.Type == html.DocumentNode {
+		return true
+	}
+	return isBlockOrNil(n.PrevSibling) && isBlockOrNil(n.NextSibling)
+}
+
+func isBlockOrNil(n *html.Node) bool {
+	if n == nil {
+		return true
+	}
+	if n.Type == html.CommentNode && !keepComment(n) {
+		return true
+	}
+	if rawMarkup(n) {
+		return true
+	}
+	return n.Type == html.ElementNode && blockElements.Contains(n.Data)
+}
+
+// nextSibling returns the next sibling that is rendered
+func nextSibling(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.CommentNode && !keepComment(s) {
+			continue
+		}
+		if droppedText(s) {
+			continue
+		}
+		return s
+	}
+	return nil
+}
+
+// omitEndTag returns true if the end tag of an element is optional where it
+// appears, as defined by the HTML spec
+func omitEndTag(n *html.Node) bool {
+	next := nextSibling(n)
+	nextIs := func(tags ...string) bool {
+		if next == nil || next.Type != html.ElementNode || next.Namespace != "" {
+			return false
+		}
+		for _, t := range tags {
+			if next.Data == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch n.Data {
+	case "html", "body":
+		return next == nil || next.Type != html.CommentNode
+	case "head":
+		return next == nil || next.Type == html.ElementNode
+	case "li":
+		return next == nil || nextIs("li")
+	case "dt":
+		return nextIs("dt", "dd")
+	case "dd":
+		return next == nil || nextIs("dt", "dd")
+	case "p":
+		if next == nil {
+			return n.Parent == nil || n.Parent.Type != html.ElementNode ||
+				!(pKeepParents.Contains(n.Parent.Data) || strings.Contains(n.Parent.Data, "-"))
+		}
+		return next.Type == html.ElementNode && next.Namespace == "" && pClosers.Contains(next.Data)
+	case "option":
+		return next == nil || nextIs("option", "optgroup")
+	case "optgroup":
+		return next == nil || nextIs("optgroup")
+	case "thead":
+		return nextIs("tbody", "tfoot")
+	case "tbody":
+		return next == nil || nextIs("tbody", "tfoot")
+	case "tfoot":
+		return next == nil
+	case "tr":
+		return next == nil || nextIs("tr")
+	case "td", "th":
+		return next == nil || nextIs("td", "th")
+	}
+	return false
+}
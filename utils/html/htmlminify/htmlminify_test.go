@@ -0,0 +1,162 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package htmlminify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/html/htmlencoding"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		description string
+		html        string
+		want        string
+	}{
+		{
+			description: "collapse whitespace and drop optional end tags",
+			html: `<!DOCTYPE html>
+<html>
+  <head>
+    <title>Example</title>
+  </head>
+  <body>
+    <h1>Hello   <em>World</em></h1>
+    <p>First  paragraph</p>
+    <p>Second
+      paragraph</p>
+  </body>
+</html>`,
+			want: `<!DOCTYPE html><html><head><title>Example</title><body><h1>Hello <em>World</em></h1><p>First paragraph<p>Second paragraph`,
+		},
+		{
+			description: "keep whitespace in preformatted elements",
+			html:        "<body><pre>\n\n  a\n    b</pre><textarea>  x  </textarea><code>a  b</code><script>if (a  &&  b) {}</script></body>",
+			want:        "<html><head><body><pre>\n\n  a\n    b</pre><textarea>  x  </textarea><code>a  b</code><script>if (a  &&  b) {}</script>",
+		},
+		{
+			description: "remove comments except conditional and licence comments",
+			html:        "<body><!-- remove --><!--[if IE]><p>IE</p><![endif]--><!--! License --><div>a</div></body>",
+			want:        "<html><head><body><!--[if IE]><p>IE</p><![endif]--><!--! License --><div>a</div>",
+		},
+		{
+			description: "drop attribute quotes where safe",
+			html:        `<body><a href="/about/" class="a b" title="" data-x="a=b" data-y='say "hi"' data-z="&amp;">About</a></body>`,
+			want:        `<html><head><body><a class="a b" data-x="a=b" data-y="say &quot;hi&quot;" data-z="&amp;" href=/about/ title>About</a>`,
+		},
+		{
+			description: "drop optional end tags of lists, tables and options",
+			html: `<body><ul>
+  <li>One</li>
+  <li>Two</li>
+</ul><dl><dt>A</dt><dd>B</dd></dl><table>
+  <tr><td>1</td><td>2</td></tr>
+  <tr><th>3</th></tr>
+</table><select><option>a</option><option>b</option></select></body>`,
+			want: `<html><head><body><ul><li>One<li>Two</ul><dl><dt>A<dd>B</dl><table><tbody><tr><td>1<td>2<tr><th>3</table><select><option>a<option>b</select>`,
+		},
+		{
+			description: "keep end tags that are needed",
+			html:        `<body><a href="/"><p>Link</p></a><p>Text</p> more<div><p>Last</p></div><span>a</span> <span>b</span></body>`,
+			want:        `<html><head><body><a href=/><p>Link</p></a><p>Text</p> more<div><p>Last</div><span>a</span> <span>b</span>`,
+		},
+		{
+			description: "keep end tags of p elements last in custom elements",
+			html:        `<body><my-el><p>b</p></my-el><table><tr><td>1</td></tr></table></body>`,
+			want:        `<html><head><body><my-el><p>b</p></my-el><table><tbody><tr><td>1</table>`,
+		},
+		{
+			description: "keep whitespace in encoded meta elements",
+			html:        "<head><meta name=\"description\" content=\"x\n  y\">\n  <title>A</title></head>",
+			want:        "<html><head><meta name=\"description\" content=\"x\n  y\"><title>A</title><body>",
+		},
+		{
+			description: "render svg as is",
+			html:        `<body><svg viewBox="0 0 10 10"><path d="M0 0"/></svg></body>`,
+			want:        `<html><head><body><svg viewBox="0 0 10 10"><path d="M0 0"></path></svg>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			htmlencoding.EncodeNodes(doc)
+
+			var buf bytes.Buffer
+			if err := Render(&buf, doc); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), tt.want); diff != "" {
+				t.Fatalf("Unexpected HTML; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestRender_Reparse(t *testing.T) {
+	tests := []string{
+		`<body><my-el><p>b</p></my-el><table><tr><td>1</td></tr></table></body>`,
+		`<body><a href="/"><p>Link</p></a><p>Text</p> more<div><p>Last</p></div></body>`,
+		`<body><video><p>Fallback</p></video><p>After</p><x-a><x-b><p>Nested</p></x-b></x-a><div>End</div></body>`,
+		`<body><ul><li>One<li>Two</ul><dl><dt>A<dd>B</dl><select><option>a<option>b</select></body>`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			want := tree(doc)
+			htmlencoding.EncodeNodes(doc)
+
+			var buf bytes.Buffer
+			if err := Render(&buf, doc); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			reparsed, err := html.Parse(&buf)
+			if err != nil {
+				t.Fatalf("Failed to parse minified HTML: %v", err)
+			}
+			if diff := cmp.Diff(tree(reparsed), want); diff != "" {
+				t.Fatalf("Minified HTML parses to a different tree; diff %v", diff)
+			}
+		})
+	}
+}
+
+// tree returns the element structure of a node
+func tree(n *html.Node) string {
+	children := []string{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			children = append(children, tree(c))
+		}
+	}
+	if len(children) == 0 {
+		return n.Data
+	}
+	return n.Data + "(" + strings.Join(children, ",") + ")"
+}
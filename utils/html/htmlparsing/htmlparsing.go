@@ -141,6 +141,44 @@ func AsyncJSTag(jm JSTagData) *html.Node {
 	}
 }
 
+func ModuleJSTag(jm JSTagData) *html.Node {
+	attr := jm.Attributes
+	attr = append(attr, []html.Attribute{
+		{Key: "type", Val: "module"},
+		{Key: "src", Val: jm.URL},
+	}...)
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "script",
+		Attr: attr,
+	}
+}
+
+func ModulePreloadTag(url string) *html.Node {
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "link",
+		Attr: []html.Attribute{
+			{Key: "rel", Val: "modulepreload"},
+			{Key: "href", Val: url},
+		},
+	}
+}
+
+func ImportMapTag(contents string) *html.Node {
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "script",
+		Attr: []html.Attribute{
+			{Key: "type", Val: "importmap"},
+		},
+		FirstChild: &html.Node{
+			Type: html.TextNode,
+			Data: contents,
+		},
+	}
+}
+
 func PreloadTag(as, url string) *html.Node {
 	return &html.Node{
 		Type: html.ElementNode,
@@ -153,6 +191,51 @@ func PreloadTag(as, url string) *html.Node {
 	}
 }
 
+func PreloadImageTag(im ImagePreloadData) *html.Node {
+	attr := []html.Attribute{
+		{Key: "rel", Val: "preload"},
+		{Key: "as", Val: "image"},
+	}
+	if im.URL != "" {
+		attr = append(attr, html.Attribute{Key: "href", Val: im.URL})
+	}
+	if im.Srcset != "" {
+		attr = append(attr, html.Attribute{Key: "imagesrcset", Val: im.Srcset})
+	}
+	if im.Sizes != "" {
+		attr = append(attr, html.Attribute{Key: "imagesizes", Val: im.Sizes})
+	}
+	if im.Type != "" {
+		attr = append(attr, html.Attribute{Key: "type", Val: im.Type})
+	}
+	attr = append(attr, html.Attribute{Key: "fetchpriority", Val: "high"})
+
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "link",
+		Attr: attr,
+	}
+}
+
+func PreloadFontTag(url, mimeType string) *html.Node {
+	attr := []html.Attribute{
+		{Key: "rel", Val: "preload"},
+		{Key: "as", Val: "font"},
+		{Key: "href", Val: url},
+	}
+	if mimeType != "" {
+		attr = append(attr, html.Attribute{Key: "type", Val: mimeType})
+	}
+	// Fonts are always fetched in CORS mode, without this the preload is wasted
+	attr = append(attr, html.Attribute{Key: "crossorigin"})
+
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "link",
+		Attr: attr,
+	}
+}
+
 func FindNodeByTag(tag string, node *html.Node) *html.Node {
 	if node.Type == html.ElementNode {
 		if node.Data == tag {
@@ -203,6 +286,22 @@ func FindNodesByClassname(classname string, node *html.Node) []*html.Node {
 	return elements
 }
 
+// FirstResourceNode returns the first link, script or style element in the
+// head, which is where hints that must be seen before any resource is fetched
+// are inserted. Nil is returned when there is none.
+func FirstResourceNode(headNode *html.Node) *html.Node {
+	for c := headNode.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "link", "script", "style":
+			return c
+		}
+	}
+	return nil
+}
+
 func SwapNodes(original, new *html.Node) {
 	p := original.Parent
 	s := original.NextSibling
@@ -211,6 +310,19 @@ func SwapNodes(original, new *html.Node) {
 	p.InsertBefore(new, s)
 }
 
+// SrcsetURLs returns the URL of each candidate in a srcset attribute value
+func SrcsetURLs(srcset string) []string {
+	urls := []string{}
+	for _, c := range strings.Split(srcset, ",") {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		urls = append(urls, fields[0])
+	}
+	return urls
+}
+
 func Attributes(e *html.Node) map[string]html.Attribute {
 	attributes := map[string]html.Attribute{}
 	for _, a := range e.Attr {
@@ -236,6 +348,13 @@ type CSSTagData struct {
 	Media      string
 }
 
+type ImagePreloadData struct {
+	URL    string
+	Srcset string
+	Sizes  string
+	Type   string
+}
+
 type JSTagData struct {
 	URL        string
 	Attributes []html.Attribute
@@ -273,6 +273,60 @@ func Test_AsyncJSTag(t *testing.T) {
 	}
 }
 
+func Test_ModuleJSTag(t *testing.T) {
+	tests := []struct {
+		description string
+		jsData      JSTagData
+		want        string
+	}{
+		{
+			description: "return module script tag",
+			jsData: JSTagData{
+				URL: "/example.js",
+			},
+			want: `<script type="module" src="/example.js"></script>`,
+		},
+		{
+			description: "return module script tag with attributes",
+			jsData: JSTagData{
+				URL: "/example.js",
+				Attributes: []html.Attribute{
+					{
+						Key: "example",
+						Val: "test",
+					},
+				},
+			},
+			want: `<script example="test" type="module" src="/example.js"></script>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := ModuleJSTag(tt.jsData)
+			if diff := cmp.Diff(MustRenderNode(t, got), tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_ModulePreloadTag(t *testing.T) {
+	got := ModulePreloadTag("/example.js")
+	want := `<link rel="modulepreload" href="/example.js"/>`
+	if diff := cmp.Diff(MustRenderNode(t, got), want); diff != "" {
+		t.Fatalf("Unexpected result; diff %v", diff)
+	}
+}
+
+func Test_ImportMapTag(t *testing.T) {
+	got := ImportMapTag(`{"imports":{"/a.js":"/a.1234.js"}}`)
+	want := `<script type="importmap">{"imports":{"/a.js":"/a.1234.js"}}</script>`
+	if diff := cmp.Diff(MustRenderNode(t, got), want); diff != "" {
+		t.Fatalf("Unexpected result; diff %v", diff)
+	}
+}
+
 func Test_PreloadTag(t *testing.T) {
 	tests := []struct {
 		description string
@@ -298,6 +352,103 @@ func Test_PreloadTag(t *testing.T) {
 	}
 }
 
+func Test_PreloadImageTag(t *testing.T) {
+	tests := []struct {
+		description string
+		data        ImagePreloadData
+		want        string
+	}{
+		{
+			description: "return link tag for src only",
+			data: ImagePreloadData{
+				URL: "/example.jpg",
+			},
+			want: `<link rel="preload" as="image" href="/example.jpg" fetchpriority="high"/>`,
+		},
+		{
+			description: "return link tag with srcset, sizes and type",
+			data: ImagePreloadData{
+				Srcset: "/example-1.avif 100w,/example-2.avif 200w",
+				Sizes:  "100vw",
+				Type:   "image/avif",
+			},
+			want: `<link rel="preload" as="image" imagesrcset="/example-1.avif 100w,/example-2.avif 200w" imagesizes="100vw" type="image/avif" fetchpriority="high"/>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := PreloadImageTag(tt.data)
+			if diff := cmp.Diff(MustRenderNode(t, got), tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_PreloadFontTag(t *testing.T) {
+	tests := []struct {
+		description string
+		url         string
+		mimeType    string
+		want        string
+	}{
+		{
+			description: "return link tag without type",
+			url:         "/example.woff2",
+			want:        `<link rel="preload" as="font" href="/example.woff2" crossorigin=""/>`,
+		},
+		{
+			description: "return link tag with type",
+			url:         "/example.woff2",
+			mimeType:    "font/woff2",
+			want:        `<link rel="preload" as="font" href="/example.woff2" type="font/woff2" crossorigin=""/>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := PreloadFontTag(tt.url, tt.mimeType)
+			if diff := cmp.Diff(MustRenderNode(t, got), tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_SrcsetURLs(t *testing.T) {
+	tests := []struct {
+		description string
+		srcset      string
+		want        []string
+	}{
+		{
+			description: "return empty slice for empty srcset",
+			srcset:      "",
+			want:        []string{},
+		},
+		{
+			description: "return single URL without descriptor",
+			srcset:      "/example.jpg",
+			want:        []string{"/example.jpg"},
+		},
+		{
+			description: "return URLs with width and density descriptors",
+			srcset:      "/example-1.jpg 100w, /example-2.jpg 200w,\n\t/example-3.jpg 2x",
+			want:        []string{"/example-1.jpg", "/example-2.jpg", "/example-3.jpg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := SrcsetURLs(tt.srcset)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
 func Test_FindNodeByTag(t *testing.T) {
 	tests := []struct {
 		description string
@@ -364,6 +515,35 @@ func Test_FindNodesByTag(t *testing.T) {
 	}
 }
 
+func Test_FirstResourceNode(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        string
+	}{
+		{
+			description: "return nil without resources",
+			input:       `<head><title>Example</title><meta charset="utf-8"/></head>`,
+			want:        ``,
+		},
+		{
+			description: "return the first link, script or style element",
+			input:       `<head><title>Example</title><style>a{}</style><link rel="stylesheet" href="/main.css"/></head>`,
+			want:        `<style>a{}</style>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			headNode := FindNodeByTag("head", MustGetNode(t, tt.input))
+			got := MustRenderNode(t, FirstResourceNode(headNode))
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
 func Test_SwapNodes(t *testing.T) {
 	doc := MustGetNode(t, `<div></div>`)
 
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package imgcrop crops images to an exact size, keeping either a given focal
+// point or the most detailed part of the image in frame.
+package imgcrop
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Focus is a point in an image as fractions of its width and height, where
+// 0,0 is the top left and 1,1 is the bottom right
+type Focus struct {
+	X float64
+	Y float64
+}
+
+// Fill scales an image to cover width x height and crops the overflow. The
+// crop is centered on the focus when given, otherwise it is placed where the
+// image has the most detail.
+func Fill(img image.Image, width, height int, focus *Focus) *image.NRGBA {
+	b := img.Bounds()
+	scale := math.Max(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy()))
+	rw := int(math.Max(float64(width), math.Round(float64(b.Dx())*scale)))
+	rh := int(math.Max(float64(height), math.Round(float64(b.Dy())*scale)))
+	resized := imaging.Resize(img, rw, rh, imaging.Lanczos)
+
+	var x, y int
+	if focus != nil {
+		x = offset(focus.X, rw, width)
+		y = offset(focus.Y, rh, height)
+	} else if rw > width {
+		x = detailedOffset(columnEnergy(resized), width)
+	} else if rh > height {
+		y = detailedOffset(rowEnergy(resized), height)
+	}
+
+	return imaging.Crop(resized, image.Rect(x, y, x+width, y+height))
+}
+
+// offset returns the start of a window of size centered on a fraction of
+// total, kept within total
+func offset(fraction float64, total, size int) int {
+	o := int(math.Round(fraction*float64(total) - float64(size)/2))
+	if o < 0 {
+		return 0
+	}
+	if o > total-size {
+		return total - size
+	}
+	return o
+}
+
+// detailedOffset returns the start of the window of size with the most
+// energy, preferring the center when windows are equal
+func detailedOffset(energy []float64, size int) int {
+	if len(energy) <= size {
+		return 0
+	}
+
+	sums := make([]float64, len(energy)-size+1)
+	for i := 0; i < size; i++ {
+		sums[0] += energy[i]
+	}
+	for i := 1; i < len(sums); i++ {
+		sums[i] = sums[i-1] - energy[i-1] + energy[i+size-1]
+	}
+
+	best := (len(energy) - size) / 2
+	for i, s := range sums {
+		if s > sums[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// columnEnergy returns the summed luminance gradient of every column
+func columnEnergy(img *image.NRGBA) []float64 {
+	b := img.Bounds()
+	energy := make([]float64, b.Dx())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			energy[x] += gradient(img, x, y)
+		}
+	}
+	return energy
+}
+
+// rowEnergy returns the summed luminance gradient of every row
+func rowEnergy(img *image.NRGBA) []float64 {
+	b := img.Bounds()
+	energy := make([]float64, b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			energy[y] += gradient(img, x, y)
+		}
+	}
+	return energy
+}
+
+// gradient returns how much the luminance changes from a pixel to its right
+// and bottom neighbours
+func gradient(img *image.NRGBA, x, y int) float64 {
+	b := img.Bounds()
+	l := luminance(img, x, y)
+	g := 0.0
+	if x+1 < b.Dx() {
+		g += math.Abs(luminance(img, x+1, y) - l)
+	}
+	if y+1 < b.Dy() {
+		g += math.Abs(luminance(img, x, y+1) - l)
+	}
+	return g
+}
+
+func luminance(img *image.NRGBA, x, y int) float64 {
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+3]
+	return 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+}
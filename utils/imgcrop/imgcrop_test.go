@@ -0,0 +1,150 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package imgcrop
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		description string
+		img         image.Image
+		width       int
+		height      int
+		focus       *Focus
+		wantSize    image.Point
+		wantDark    bool
+	}{
+		{
+			description: "crop a portrait image to the detailed area",
+			img:         bandedImage(100, 400, 300),
+			width:       120,
+			height:      63,
+			wantSize:    image.Pt(120, 63),
+			wantDark:    true,
+		},
+		{
+			description: "crop a portrait image around the focus",
+			img:         bandedImage(100, 400, 300),
+			width:       120,
+			height:      63,
+			focus:       &Focus{X: 0.5, Y: 0},
+			wantSize:    image.Pt(120, 63),
+		},
+		{
+			description: "crop a landscape image around the focus",
+			img:         bandedImage(800, 100, 0),
+			width:       120,
+			height:      63,
+			focus:       &Focus{X: 1, Y: 0.5},
+			wantSize:    image.Pt(120, 63),
+			wantDark:    true,
+		},
+		{
+			description: "scale up a small image",
+			img:         bandedImage(10, 10, 0),
+			width:       120,
+			height:      63,
+			wantSize:    image.Pt(120, 63),
+			wantDark:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := Fill(tt.img, tt.width, tt.height, tt.focus)
+
+			if diff := cmp.Diff(got.Bounds().Size(), tt.wantSize); diff != "" {
+				t.Fatalf("Unexpected size; diff %v", diff)
+			}
+
+			total := 0.0
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					total += luminance(got, x, y)
+				}
+			}
+			dark := total/float64(tt.width*tt.height) < 128
+			if dark != tt.wantDark {
+				t.Fatalf("Unexpected crop; got dark %v, want %v", dark, tt.wantDark)
+			}
+		})
+	}
+}
+
+func TestDetailedOffset(t *testing.T) {
+	tests := []struct {
+		description string
+		energy      []float64
+		size        int
+		want        int
+	}{
+		{
+			description: "return 0 when the window covers everything",
+			energy:      []float64{1, 2},
+			size:        2,
+			want:        0,
+		},
+		{
+			description: "return the center when there is no detail",
+			energy:      []float64{0, 0, 0, 0, 0},
+			size:        1,
+			want:        2,
+		},
+		{
+			description: "return the window with the most energy",
+			energy:      []float64{0, 1, 0, 0, 5, 5, 0},
+			size:        2,
+			want:        4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := detailedOffset(tt.energy, tt.size)
+			if got != tt.want {
+				t.Fatalf("Unexpected offset; got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// bandedImage returns a white image that is mostly black from the start row
+// or column onwards along its longest side, with a white line every 4 pixels
+// to give that area detail
+func bandedImage(width, height, start int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := y
+			if width > height {
+				p = x
+			}
+			c := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			if p >= start && p%4 != 0 {
+				c = color.NRGBA{A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
@@ -0,0 +1,102 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package jsmodules finds the dependencies between ES modules
+package jsmodules
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Matches `import x from "y"`, `import "y"` and `export x from "y"` but not
+	// dynamic `import("y")` calls
+	staticImportRegex = regexp.MustCompile(`(?:^|[;}\s])(?:import|export)\s*(?:[\w$*{}\s,]+?\s*from\s*)?["']([^"'\n]+)["']`)
+
+	// Matches dynamic `import("y")` calls with a string literal specifier
+	dynamicImportRegex = regexp.MustCompile(`\bimport\s*\(\s*["']([^"'\n]+)["']\s*\)`)
+)
+
+// StaticImports returns the specifiers of the static imports and re-exports in
+// a module, in order and without duplicates
+func StaticImports(contents string) []string {
+	return specifiers(contents, staticImportRegex)
+}
+
+// Imports returns the specifiers of the static and dynamic imports in a
+// module, in order and without duplicates
+func Imports(contents string) []string {
+	return specifiers(contents, staticImportRegex, dynamicImportRegex)
+}
+
+// ReplaceImports replaces the specifier of every static and dynamic import in
+// a module with the result of fn
+func ReplaceImports(contents string, fn func(specifier string) string) string {
+	for _, re := range []*regexp.Regexp{staticImportRegex, dynamicImportRegex} {
+		contents = re.ReplaceAllStringFunc(contents, func(match string) string {
+			s := re.FindStringSubmatch(match)[1]
+			updated := fn(s)
+			if updated == s {
+				return match
+			}
+			// The specifier is the last quoted string in the match
+			i := strings.LastIndex(match, s)
+			return match[:i] + updated + match[i+len(s):]
+		})
+	}
+	return contents
+}
+
+func specifiers(contents string, res ...*regexp.Regexp) []string {
+	specifiers := []string{}
+	seen := map[string]bool{}
+	for _, re := range res {
+		for _, m := range re.FindAllStringSubmatch(contents, -1) {
+			s := strings.TrimSpace(m[1])
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			specifiers = append(specifiers, s)
+		}
+	}
+	return specifiers
+}
+
+// Resolve returns the URL a specifier refers to when imported from the module
+// at moduleURL. Bare specifiers such as "lodash" can only be resolved with an
+// import map so false is returned for them.
+func Resolve(moduleURL, specifier string) (string, bool) {
+	switch {
+	case strings.HasPrefix(specifier, "/"):
+		return specifier, true
+	case strings.HasPrefix(specifier, "./"), strings.HasPrefix(specifier, "../"):
+		u, err := url.Parse(specifier)
+		if err != nil {
+			return "", false
+		}
+		return path.Join(path.Dir(moduleURL), u.Path), true
+	}
+
+	u, err := url.Parse(specifier)
+	if err != nil || !u.IsAbs() {
+		return "", false
+	}
+	return specifier, true
+}
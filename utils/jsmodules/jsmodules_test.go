@@ -0,0 +1,156 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package jsmodules
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_StaticImports(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        []string
+	}{
+		{
+			description: "return nothing for a script without imports",
+			contents:    `console.log("import './a.js'")`,
+			want:        []string{},
+		},
+		{
+			description: "return static imports and re-exports",
+			contents: `import a from "./a.js";
+import {b, c as d} from './b.js';
+import * as e from "../e.js";
+import "/side-effect.js";
+import {
+	f,
+	g,
+} from "./f.js";
+export * from "./a.js";
+export { h } from "lodash";
+const lazy = import("./lazy.js");`,
+			want: []string{"./a.js", "./b.js", "../e.js", "/side-effect.js", "./f.js", "lodash"},
+		},
+		{
+			description: "return imports from minified modules",
+			contents:    `import{a}from"./a.js";import"./b.js";export{c}from"./c.js";`,
+			want:        []string{"./a.js", "./b.js", "./c.js"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := StaticImports(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_Imports(t *testing.T) {
+	got := Imports(`import a from "./a.js";const b = import("./b.js");const c = import( './a.js' );const d = import(name);`)
+	want := []string{"./a.js", "./b.js"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Unexpected result; diff %v", diff)
+	}
+}
+
+func Test_ReplaceImports(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        string
+	}{
+		{
+			description: "leave unmatched imports",
+			contents:    `import "./other.js";`,
+			want:        `import "./other.js";`,
+		},
+		{
+			description: "replace static and dynamic imports",
+			contents:    `import {a} from "./a.js";export * from './a.js';const b = import("./a.js");console.log("./a.js");`,
+			want:        `import {a} from "./a.1234.js";export * from './a.1234.js';const b = import("./a.1234.js");console.log("./a.js");`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := ReplaceImports(tt.contents, func(s string) string {
+				if s != "./a.js" {
+					return s
+				}
+				return "./a.1234.js"
+			})
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_Resolve(t *testing.T) {
+	tests := []struct {
+		description string
+		moduleURL   string
+		specifier   string
+		want        string
+		wantOK      bool
+	}{
+		{
+			description: "return false for bare specifiers",
+			moduleURL:   "/js/main.js",
+			specifier:   "lodash",
+		},
+		{
+			description: "return root relative specifiers",
+			moduleURL:   "/js/main.js",
+			specifier:   "/lib/a.js",
+			want:        "/lib/a.js",
+			wantOK:      true,
+		},
+		{
+			description: "resolve relative specifiers",
+			moduleURL:   "/js/main.js",
+			specifier:   "../lib/a.js",
+			want:        "/lib/a.js",
+			wantOK:      true,
+		},
+		{
+			description: "return absolute URLs",
+			moduleURL:   "/js/main.js",
+			specifier:   "https://cdn.example.com/a.js",
+			want:        "https://cdn.example.com/a.js",
+			wantOK:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, ok := Resolve(tt.moduleURL, tt.specifier)
+			if ok != tt.wantOK {
+				t.Fatalf("Unexpected ok; got %v, want %v", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
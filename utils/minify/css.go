@@ -0,0 +1,278 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package minify removes whitespace and comments from CSS and JS without
+// changing their meaning
+package minify
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	whitespaceRegex  = regexp.MustCompile(`\s+`)
+	aroundRegex      = regexp.MustCompile(` ?([,>~]) ?`)
+	afterRegex       = regexp.MustCompile(`([:(]) `)
+	beforeRegex      = regexp.MustCompile(` ([)!])`)
+	zeroUnitRegex    = regexp.MustCompile(`(^|[^\w.#-])0(?:px|em|rem|ex|ch|vw|vh|vmin|vmax|cm|mm|in|pt|pc)\b`)
+	leadingZeroRegex = regexp.MustCompile(`(^|[^\w.])0+\.(\d)`)
+	hexColorRegex    = regexp.MustCompile(`#[0-9a-fA-F]{6}\b`)
+)
+
+const urlFunctionPrefix = "url("
+
+// CSS minifies a stylesheet by removing comments and unneeded whitespace and
+// shortening zero lengths, leading zeros and hex colors in declarations.
+// Comments starting with /*! are kept. The returned mappings map the start of
+// every rule, selector and declaration back to the source.
+func CSS(contents string) (string, []Mapping) {
+	m := &cssMinifier{
+		src: contents,
+		out: newOutput(contents),
+	}
+	m.minify()
+	return m.out.String(), m.out.mappings
+}
+
+type cssPart struct {
+	text    string
+	literal bool
+}
+
+type cssMinifier struct {
+	src string
+	out *output
+
+	// The selector, at-rule prelude or declaration being read
+	parts []cssPart
+	start int
+	text  strings.Builder
+
+	// A semicolon is only written if more follows in the same block
+	pendingSemicolon bool
+}
+
+func (m *cssMinifier) minify() {
+	m.start = -1
+	for i := 0; i < len(m.src); {
+		c := m.src[i]
+		switch {
+		case c == '/' && strings.HasPrefix(m.src[i:], "/*"):
+			end := strings.Index(m.src[i+2:], "*/")
+			if end == -1 {
+				end = len(m.src)
+			} else {
+				end += i + 4
+			}
+			if strings.HasPrefix(m.src[i:], "/*!") {
+				m.addLiteral(i, m.src[i:end])
+			} else {
+				m.text.WriteByte(' ')
+			}
+			i = end
+		case c == '"' || c == '\'':
+			end := stringEnd(m.src, i)
+			m.addLiteral(i, m.src[i:end])
+			i = end
+		case (c == 'u' || c == 'U') && m.isURLFunction(i):
+			m.addText(i, m.src[i:i+len(urlFunctionPrefix)])
+			i += len(urlFunctionPrefix)
+			for i < len(m.src) && isSpace(m.src[i]) {
+				i++
+			}
+			if i < len(m.src) && m.src[i] != '"' && m.src[i] != '\'' {
+				// Unquoted URLs can contain characters like ; and , so
+				// are kept as is
+				end := strings.IndexByte(m.src[i:], ')')
+				if end == -1 {
+					end = len(m.src) - i
+				}
+				m.addLiteral(i, strings.TrimRight(m.src[i:i+end], " \t\r\n"))
+				i += end
+			}
+		case c == '{' || c == '}' || c == ';':
+			m.flush(c)
+			i++
+		default:
+			m.addText(i, string(c))
+			i++
+		}
+	}
+	m.flush(0)
+	if m.pendingSemicolon {
+		m.out.write(";")
+	}
+}
+
+func (m *cssMinifier) isURLFunction(i int) bool {
+	if !strings.EqualFold(m.src[i:min(i+len(urlFunctionPrefix), len(m.src))], urlFunctionPrefix) {
+		return false
+	}
+	return i == 0 || !(isIdent(m.src[i-1]) || m.src[i-1] == '-')
+}
+
+func (m *cssMinifier) addText(i int, s string) {
+	if m.start == -1 && strings.TrimSpace(s) != "" {
+		m.start = i
+	}
+	m.text.WriteString(s)
+}
+
+func (m *cssMinifier) addLiteral(i int, s string) {
+	if m.start == -1 {
+		m.start = i
+	}
+	m.endText()
+	m.parts = append(m.parts, cssPart{text: s, literal: true})
+}
+
+func (m *cssMinifier) endText() {
+	if m.text.Len() == 0 {
+		return
+	}
+	m.parts = append(m.parts, cssPart{text: m.text.String()})
+	m.text.Reset()
+}
+
+func (m *cssMinifier) flush(terminator byte) {
+	m.endText()
+	parts := m.parts
+	start := m.start
+	m.parts = nil
+	m.start = -1
+
+	for i, p := range parts {
+		if p.literal {
+			continue
+		}
+		t := whitespaceRegex.ReplaceAllString(p.text, " ")
+		t = aroundRegex.ReplaceAllString(t, "$1")
+		t = afterRegex.ReplaceAllString(t, "$1")
+		t = beforeRegex.ReplaceAllString(t, "$1")
+		if i == 0 {
+			t = strings.TrimLeft(t, " ")
+		}
+		if i == len(parts)-1 {
+			t = strings.TrimRight(t, " ")
+		}
+		parts[i].text = t
+	}
+
+	if terminator != '{' {
+		shortenDeclaration(parts)
+	}
+
+	empty := true
+	for _, p := range parts {
+		if p.text != "" {
+			empty = false
+		}
+	}
+
+	if !empty {
+		if m.pendingSemicolon {
+			m.out.write(";")
+			m.pendingSemicolon = false
+		}
+		m.out.mark(start)
+		for _, p := range parts {
+			m.out.write(p.text)
+		}
+	}
+
+	switch terminator {
+	case ';':
+		m.pendingSemicolon = m.pendingSemicolon || !empty
+	case '{':
+		if m.pendingSemicolon {
+			m.out.write(";")
+			m.pendingSemicolon = false
+		}
+		m.out.write("{")
+	case '}':
+		m.pendingSemicolon = false
+		m.out.write("}")
+	}
+}
+
+// shortenDeclaration applies safe shorthands to the value of a declaration
+func shortenDeclaration(parts []cssPart) {
+	if len(parts) == 0 || parts[0].literal || strings.HasPrefix(parts[0].text, "@") {
+		return
+	}
+	colon := strings.IndexByte(parts[0].text, ':')
+	if colon == -1 {
+		return
+	}
+	property := parts[0].text[:colon]
+
+	// Unitless zeros aren't valid in calc() and custom properties may be
+	// used in calc()
+	stripUnits := !strings.HasPrefix(property, "--")
+	for _, p := range parts {
+		if !p.literal && strings.Contains(p.text, "(") {
+			stripUnits = false
+		}
+	}
+
+	for i, p := range parts {
+		if p.literal {
+			continue
+		}
+		prefix, value := "", p.text
+		if i == 0 {
+			prefix, value = strings.TrimRight(property, " ")+":", p.text[colon+1:]
+		}
+		if stripUnits {
+			value = zeroUnitRegex.ReplaceAllString(value, "${1}0")
+		}
+		value = leadingZeroRegex.ReplaceAllString(value, "${1}.${2}")
+		value = hexColorRegex.ReplaceAllStringFunc(value, shortenHex)
+		parts[i].text = prefix + value
+	}
+}
+
+func shortenHex(hex string) string {
+	l := strings.ToLower(hex)
+	if l[1] != l[2] || l[3] != l[4] || l[5] != l[6] {
+		return hex
+	}
+	return string([]byte{'#', hex[1], hex[3], hex[5]})
+}
+
+// stringEnd returns the offset after the string starting at i
+func stringEnd(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
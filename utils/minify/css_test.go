@@ -0,0 +1,104 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minify
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_CSS(t *testing.T) {
+	tests := []struct {
+		description  string
+		contents     string
+		want         string
+		wantMappings []Mapping
+	}{
+		{
+			description: "return empty string for empty contents",
+			contents:    "",
+			want:        "",
+		},
+		{
+			description: "remove comments and whitespace",
+			contents:    "/* Header */\nbody {\n  color : red ;\n  margin: 1px  2px;\n}\n",
+			want:        "body{color:red;margin:1px 2px}",
+			wantMappings: []Mapping{
+				{GeneratedLine: 0, GeneratedColumn: 0, SourceLine: 1, SourceColumn: 0},
+				{GeneratedLine: 0, GeneratedColumn: 5, SourceLine: 2, SourceColumn: 2},
+				{GeneratedLine: 0, GeneratedColumn: 15, SourceLine: 3, SourceColumn: 2},
+			},
+		},
+		{
+			description: "keep license comments",
+			contents:    "/*! License */\na{}",
+			want:        "/*! License */ a{}",
+			wantMappings: []Mapping{
+				{GeneratedLine: 0, GeneratedColumn: 0, SourceLine: 0, SourceColumn: 0},
+			},
+		},
+		{
+			description: "minify selectors and at-rules without breaking them",
+			contents:    "@media screen and (max-width: 100px) {\n  a:hover , ul > li, a :first-child { color: red }\n}",
+			want:        "@media screen and (max-width:100px){a:hover,ul>li,a :first-child{color:red}}",
+			wantMappings: []Mapping{
+				{GeneratedLine: 0, GeneratedColumn: 0, SourceLine: 0, SourceColumn: 0},
+				{GeneratedLine: 0, GeneratedColumn: 36, SourceLine: 1, SourceColumn: 2},
+				{GeneratedLine: 0, GeneratedColumn: 65, SourceLine: 1, SourceColumn: 38},
+			},
+		},
+		{
+			description: "shorten values in declarations",
+			contents:    "#aabbcc{color:#AABBCC;border-color:#aabbcd;margin:0px 0.5em -0.25rem 10px;opacity:0.50;width:calc(0px + 1em);--gap:0px;color:red !important}",
+			want:        "#aabbcc{color:#ABC;border-color:#aabbcd;margin:0 .5em -.25rem 10px;opacity:.50;width:calc(0px + 1em);--gap:0px;color:red!important}",
+			wantMappings: []Mapping{
+				{GeneratedLine: 0, GeneratedColumn: 0, SourceLine: 0, SourceColumn: 0},
+				{GeneratedLine: 0, GeneratedColumn: 8, SourceLine: 0, SourceColumn: 8},
+				{GeneratedLine: 0, GeneratedColumn: 19, SourceLine: 0, SourceColumn: 22},
+				{GeneratedLine: 0, GeneratedColumn: 40, SourceLine: 0, SourceColumn: 43},
+				{GeneratedLine: 0, GeneratedColumn: 67, SourceLine: 0, SourceColumn: 74},
+				{GeneratedLine: 0, GeneratedColumn: 79, SourceLine: 0, SourceColumn: 87},
+				{GeneratedLine: 0, GeneratedColumn: 101, SourceLine: 0, SourceColumn: 109},
+				{GeneratedLine: 0, GeneratedColumn: 111, SourceLine: 0, SourceColumn: 119},
+			},
+		},
+		{
+			description: "keep strings and unquoted URLs as is",
+			contents:    `a::before { content: "  a ; b  " ; background: url( data:image/svg+xml;utf8,<svg a="1 2"></svg> ) } @import url( "x.css" ) print;`,
+			want:        `a::before{content:"  a ; b  ";background:url(data:image/svg+xml;utf8,<svg a="1 2"></svg>)}@import url("x.css") print;`,
+			wantMappings: []Mapping{
+				{GeneratedLine: 0, GeneratedColumn: 0, SourceLine: 0, SourceColumn: 0},
+				{GeneratedLine: 0, GeneratedColumn: 10, SourceLine: 0, SourceColumn: 12},
+				{GeneratedLine: 0, GeneratedColumn: 30, SourceLine: 0, SourceColumn: 35},
+				{GeneratedLine: 0, GeneratedColumn: 90, SourceLine: 0, SourceColumn: 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, gotMappings := CSS(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+			if diff := cmp.Diff(gotMappings, tt.wantMappings); diff != "" {
+				t.Fatalf("Unexpected mappings; diff %v", diff)
+			}
+		})
+	}
+}
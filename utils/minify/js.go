@@ -0,0 +1,275 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minify
+
+import (
+	"strings"
+)
+
+var (
+	// Keywords after which a / starts a regular expression
+	regexKeywords = map[string]bool{
+		"return":     true,
+		"typeof":     true,
+		"case":       true,
+		"do":         true,
+		"else":       true,
+		"in":         true,
+		"of":         true,
+		"new":        true,
+		"delete":     true,
+		"void":       true,
+		"throw":      true,
+		"instanceof": true,
+		"yield":      true,
+		"await":      true,
+	}
+)
+
+const (
+	// Characters after which a / starts a regular expression
+	regexAfter = "(,=:[!&|?{};+-*%<>~^"
+
+	// Characters after which a line break can't end a statement
+	noASIAfter = "{([,;:=&|?<>!~^%*.+-"
+
+	// Characters before which a line break can't end a statement
+	noASIBefore = ")]},;:?=*%&|^<>"
+)
+
+// JS minifies a script by removing comments and unneeded whitespace. Line
+// breaks are kept where automatic semicolon insertion may depend on them and
+// comments starting with /*! are kept. The returned mappings map the start of
+// every token back to the source.
+func JS(contents string) (string, []Mapping) {
+	m := &jsMinifier{
+		src: contents,
+		out: newOutput(contents),
+	}
+	m.minify()
+	return m.out.String(), m.out.mappings
+}
+
+type jsMinifier struct {
+	src string
+	out *output
+
+	// Whitespace or comments have been skipped since the last token
+	space   bool
+	newline bool
+
+	// The last token written
+	lastWord    string
+	lastNumeric bool
+}
+
+func (m *jsMinifier) minify() {
+	for i := 0; i < len(m.src); {
+		c := m.src[i]
+		switch {
+		case isSpace(c):
+			if c == '\n' {
+				m.newline = true
+			}
+			m.space = true
+			i++
+			continue
+		case strings.HasPrefix(m.src[i:], "//"):
+			end := strings.IndexByte(m.src[i:], '\n')
+			if end == -1 {
+				end = len(m.src) - i
+			}
+			m.space = true
+			i += end
+			continue
+		case strings.HasPrefix(m.src[i:], "/*"):
+			end := strings.Index(m.src[i+2:], "*/")
+			if end == -1 {
+				end = len(m.src)
+			} else {
+				end += i + 4
+			}
+			if !strings.HasPrefix(m.src[i:], "/*!") {
+				if strings.Contains(m.src[i:end], "\n") {
+					m.newline = true
+				}
+				m.space = true
+				i = end
+				continue
+			}
+			m.token(i, m.src[i:end])
+			i = end
+		case c == '"' || c == '\'':
+			end := stringEnd(m.src, i)
+			m.token(i, m.src[i:end])
+			i = end
+		case c == '`':
+			end := templateEnd(m.src, i)
+			m.token(i, m.src[i:end])
+			i = end
+		case c == '/' && m.regexAllowed():
+			end := regexEnd(m.src, i)
+			if end == -1 {
+				end = i + 1
+			}
+			m.token(i, m.src[i:end])
+			i = end
+		case isIdent(c):
+			end := i
+			for end < len(m.src) && isIdent(m.src[end]) {
+				end++
+			}
+			m.token(i, m.src[i:end])
+			m.lastWord = m.src[i:end]
+			m.lastNumeric = '0' <= c && c <= '9'
+			i = end
+			continue
+		default:
+			m.token(i, string(c))
+			i++
+		}
+		m.lastWord = ""
+		m.lastNumeric = false
+	}
+}
+
+// token writes a token with any separator needed between it and the last
+func (m *jsMinifier) token(offset int, t string) {
+	if m.space {
+		a := m.out.last(1)
+		b := t[0]
+		switch {
+		case a == "":
+		case m.newline && !m.safeToJoin(a[0], b):
+			m.out.write("\n")
+		case m.needsSpace(a[0], b):
+			m.out.write(" ")
+		}
+	}
+	m.space = false
+	m.newline = false
+
+	m.out.mark(offset)
+	m.out.write(t)
+}
+
+// needsSpace returns true if joining the characters would change the tokens
+func (m *jsMinifier) needsSpace(a, b byte) bool {
+	switch {
+	case isIdent(a) && isIdent(b):
+		return true
+	case (a == '+' || a == '-') && a == b:
+		return true
+	case a == '/' && (b == '/' || b == '*'):
+		return true
+	case m.lastNumeric && b == '.':
+		return true
+	}
+	return false
+}
+
+// safeToJoin returns true if removing a line break between the characters
+// can't change where statements end
+func (m *jsMinifier) safeToJoin(a, b byte) bool {
+	if strings.IndexByte(noASIBefore, b) != -1 {
+		return true
+	}
+	if strings.IndexByte(noASIAfter, a) == -1 {
+		return false
+	}
+	// A line break after ++ or -- ends the statement
+	if (a == '+' || a == '-') && m.out.last(2) == string([]byte{a, a}) {
+		return false
+	}
+	return true
+}
+
+func (m *jsMinifier) regexAllowed() bool {
+	if m.lastWord != "" {
+		return regexKeywords[m.lastWord]
+	}
+	a := m.out.last(1)
+	return a == "" || strings.IndexByte(regexAfter, a[0]) != -1
+}
+
+// regexEnd returns the offset after the regular expression starting at i or
+// -1 if it isn't a regular expression
+func regexEnd(src string, i int) int {
+	inClass := false
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '\n':
+			return -1
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			// Include any flags
+			j++
+			for j < len(src) && isIdent(src[j]) {
+				j++
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+// templateEnd returns the offset after the template literal starting at i,
+// skipping over any nested expressions
+func templateEnd(src string, i int) int {
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '`':
+			return j + 1
+		case '$':
+			if j+1 < len(src) && src[j+1] == '{' {
+				j = expressionEnd(src, j+2) - 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// expressionEnd returns the offset after the } closing a template expression
+func expressionEnd(src string, i int) int {
+	depth := 0
+	for j := i; j < len(src); j++ {
+		switch src[j] {
+		case '"', '\'':
+			j = stringEnd(src, j) - 1
+		case '`':
+			j = templateEnd(src, j) - 1
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return j + 1
+			}
+			depth--
+		}
+	}
+	return len(src)
+}
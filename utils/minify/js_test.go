@@ -0,0 +1,76 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minify
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_JS(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        string
+	}{
+		{
+			description: "return empty string for empty contents",
+			contents:    "",
+			want:        "",
+		},
+		{
+			description: "remove comments and whitespace",
+			contents:    "// Comment\nfunction add(a, b) {\n  /* Sum */\n  return a + b;\n}\n",
+			want:        "function add(a,b){return a+b;}",
+		},
+		{
+			description: "keep license comments",
+			contents:    "/*! License */\nconst a = 1;",
+			want:        "/*! License */\nconst a=1;",
+		},
+		{
+			description: "keep line breaks that may end statements",
+			contents:    "let a = b\nc()\nlet d = e++\nf\nreturn\nx\nconst g = {}\nh()",
+			want:        "let a=b\nc()\nlet d=e++\nf\nreturn\nx\nconst g={}\nh()",
+		},
+		{
+			description: "remove line breaks that can't end statements",
+			contents:    "const a = [\n  1,\n  2\n]\nfoo(a,\n  b)\nx = a +\n  b",
+			want:        "const a=[1,2]\nfoo(a,b)\nx=a+b",
+		},
+		{
+			description: "keep space between tokens that would merge",
+			contents:    "a = b + +c - -d; e = 1 .toString(); f = a / /x/.source.length",
+			want:        "a=b+ +c- -d;e=1 .toString();f=a/ /x/.source.length",
+		},
+		{
+			description: "keep strings, template literals and regular expressions as is",
+			contents:    "const s = 'a  // b' + \"c /* d */\";\nconst t = `x  ${ y + `z ${ w }` }  /* v */`;\nconst r = /[/]  \\/ *x/g.test(s) / 2;",
+			want:        "const s='a  // b'+\"c /* d */\";const t=`x  ${ y + `z ${ w }` }  /* v */`;const r=/[/]  \\/ *x/g.test(s)/2;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, _ := JS(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
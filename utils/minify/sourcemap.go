@@ -0,0 +1,190 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minify
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+var (
+	jsMapCommentRegex  = regexp.MustCompile(`(?m)(?:\r?\n)?^[ \t]*//[#@][ \t]*sourceMappingURL=\S*[ \t]*$`)
+	cssMapCommentRegex = regexp.MustCompile(`(?:\r?\n)?/\*[#@][ \t]*sourceMappingURL=[^*]*\*/`)
+)
+
+// Mapping maps a position in minified output to a position in the source.
+// Lines and columns are zero based.
+type Mapping struct {
+	GeneratedLine   int
+	GeneratedColumn int
+	SourceLine      int
+	SourceColumn    int
+}
+
+// SourceMap returns a version 3 source map for a minified file. The source is
+// embedded in the map as the original file is replaced by the minified one.
+func SourceMap(file, sourceName, source string, mappings []Mapping) ([]byte, error) {
+	return json.Marshal(sourceMap{
+		Version:        3,
+		File:           file,
+		Sources:        []string{sourceName},
+		SourcesContent: []string{source},
+		Names:          []string{},
+		Mappings:       encodeMappings(mappings),
+	})
+}
+
+// StripSourceMapComments removes sourceMappingURL comments from CSS or JS.
+// The comments are relative to the original file so they point at the wrong
+// place once the contents are bundled or inlined elsewhere.
+func StripSourceMapComments(contents string) string {
+	contents = jsMapCommentRegex.ReplaceAllString(contents, "")
+	return cssMapCommentRegex.ReplaceAllString(contents, "")
+}
+
+func encodeMappings(mappings []Mapping) string {
+	sorted := make([]Mapping, len(mappings))
+	copy(sorted, mappings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].GeneratedLine != sorted[j].GeneratedLine {
+			return sorted[i].GeneratedLine < sorted[j].GeneratedLine
+		}
+		return sorted[i].GeneratedColumn < sorted[j].GeneratedColumn
+	})
+
+	var sb strings.Builder
+	line := 0
+	prevCol, prevSrcLine, prevSrcCol := 0, 0, 0
+	first := true
+	for _, m := range sorted {
+		for line < m.GeneratedLine {
+			sb.WriteByte(';')
+			line++
+			prevCol = 0
+			first = true
+		}
+		if !first {
+			sb.WriteByte(',')
+		}
+		first = false
+
+		// Every mapping points at the only source, index 0
+		sb.WriteString(vlq(m.GeneratedColumn - prevCol))
+		sb.WriteString(vlq(0))
+		sb.WriteString(vlq(m.SourceLine - prevSrcLine))
+		sb.WriteString(vlq(m.SourceColumn - prevSrcCol))
+
+		prevCol, prevSrcLine, prevSrcCol = m.GeneratedColumn, m.SourceLine, m.SourceColumn
+	}
+	return sb.String()
+}
+
+func vlq(n int) string {
+	v := n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+
+	var sb strings.Builder
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		sb.WriteByte(base64Chars[digit])
+		if v == 0 {
+			return sb.String()
+		}
+	}
+}
+
+type sourceMap struct {
+	Version        int      `json:"version"`
+	File           string   `json:"file"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+	Names          []string `json:"names"`
+	Mappings       string   `json:"mappings"`
+}
+
+// output builds minified contents while tracking the generated position so
+// mappings can be recorded.
+type output struct {
+	sb       strings.Builder
+	line     int
+	col      int
+	mappings []Mapping
+
+	// lineStarts holds the offset of every line in the source
+	lineStarts []int
+}
+
+func newOutput(source string) *output {
+	starts := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &output{lineStarts: starts}
+}
+
+// mark maps the current output position to an offset in the source
+func (o *output) mark(offset int) {
+	l := sort.Search(len(o.lineStarts), func(i int) bool {
+		return o.lineStarts[i] > offset
+	}) - 1
+	m := Mapping{
+		GeneratedLine:   o.line,
+		GeneratedColumn: o.col,
+		SourceLine:      l,
+		SourceColumn:    offset - o.lineStarts[l],
+	}
+	if n := len(o.mappings); n > 0 && o.mappings[n-1].GeneratedLine == m.GeneratedLine && o.mappings[n-1].GeneratedColumn == m.GeneratedColumn {
+		o.mappings[n-1] = m
+		return
+	}
+	o.mappings = append(o.mappings, m)
+}
+
+func (o *output) write(s string) {
+	o.sb.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i != -1 {
+		o.line += strings.Count(s, "\n")
+		o.col = len(s) - i - 1
+		return
+	}
+	o.col += len(s)
+}
+
+// last returns the last n bytes written
+func (o *output) last(n int) string {
+	s := o.sb.String()
+	if len(s) < n {
+		return s
+	}
+	return s[len(s)-n:]
+}
+
+func (o *output) String() string {
+	return o.sb.String()
+}
@@ -0,0 +1,100 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package minify
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_SourceMap(t *testing.T) {
+	tests := []struct {
+		description string
+		mappings    []Mapping
+		want        string
+	}{
+		{
+			description: "return empty mappings",
+			want:        `{"version":3,"file":"a.js","sources":["a.src.js"],"sourcesContent":["a = 1"],"names":[],"mappings":""}`,
+		},
+		{
+			description: "encode mappings across lines",
+			mappings: []Mapping{
+				{GeneratedLine: 0, GeneratedColumn: 0, SourceLine: 0, SourceColumn: 0},
+				{GeneratedLine: 0, GeneratedColumn: 4, SourceLine: 1, SourceColumn: 2},
+				{GeneratedLine: 2, GeneratedColumn: 1, SourceLine: 0, SourceColumn: 16},
+			},
+			want: `{"version":3,"file":"a.js","sources":["a.src.js"],"sourcesContent":["a = 1"],"names":[],"mappings":"AAAA,IACE;;CADc"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got, err := SourceMap("a.js", "a.src.js", "a = 1", tt.mappings)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(string(got), tt.want); diff != "" {
+				t.Fatalf("Unexpected source map; diff %v", diff)
+			}
+		})
+	}
+}
+
+func Test_StripSourceMapComments(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        string
+	}{
+		{
+			description: "leave contents without comments",
+			contents:    "a();\nb()",
+			want:        "a();\nb()",
+		},
+		{
+			description: "remove JS comment",
+			contents:    "a()\n//# sourceMappingURL=a.js.map",
+			want:        "a()",
+		},
+		{
+			description: "remove legacy JS comment followed by code",
+			contents:    "a()\n//@ sourceMappingURL=a.js.map\nb()",
+			want:        "a()\nb()",
+		},
+		{
+			description: "remove CSS comment",
+			contents:    "a{color:red}\n/*# sourceMappingURL=a.css.map */",
+			want:        "a{color:red}",
+		},
+		{
+			description: "keep comments that mention source maps in code",
+			contents:    `a = "//# sourceMappingURL=a.js.map"`,
+			want:        `a = "//# sourceMappingURL=a.js.map"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := StripSourceMapComments(tt.contents)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected contents; diff %v", diff)
+			}
+		})
+	}
+}
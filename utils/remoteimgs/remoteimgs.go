@@ -0,0 +1,236 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package remoteimgs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gauntface/go-html-asset-manager/v5/utils/svg"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Most image formats declare their dimensions in the first few bytes, but
+	// JPEGs can have large EXIF blocks before the frame header.
+	maxHeaderBytes = 256 * 1024
+
+	requestTimeout = 10 * time.Second
+)
+
+var (
+	errNotCached    = errors.New("remote image size is not cached")
+	errFetchFailed  = errors.New("unable to fetch remote image")
+	errDecodeFailed = errors.New("unable to decode remote image size")
+	errJSONParse    = errors.New("unable to parse cache file")
+)
+
+// Client looks up the intrinsic size of remote images, storing results in an
+// on-disk cache keyed by URL. Failed lookups are only remembered for the
+// lifetime of the client so they are retried on the next run.
+type Client struct {
+	cacheFile string
+	offline   bool
+
+	mu     sync.Mutex
+	cache  map[string]Size
+	failed map[string]error
+	dirty  bool
+	group  singleflight.Group
+
+	httpClient      httpClient
+	ioutilReadFile  func(filename string) ([]byte, error)
+	ioutilWriteFile func(filename string, data []byte, perm os.FileMode) error
+}
+
+// Size is the width and height of an image in pixels
+type Size struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// New creates a client and loads any existing cache file. When offline is
+// true, only sizes in the cache are returned.
+func New(cacheFile string, offline bool) (*Client, error) {
+	c := &Client{
+		cacheFile: cacheFile,
+		offline:   offline,
+		cache:     map[string]Size{},
+		failed:    map[string]error{},
+
+		httpClient:      &http.Client{Timeout: requestTimeout},
+		ioutilReadFile:  ioutil.ReadFile,
+		ioutilWriteFile: ioutil.WriteFile,
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) load() error {
+	if c.cacheFile == "" {
+		return nil
+	}
+
+	b, err := c.ioutilReadFile(c.cacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, &c.cache); err != nil {
+		return fmt.Errorf("%w %q: %v", errJSONParse, c.cacheFile, err)
+	}
+	return nil
+}
+
+// Size returns the width and height of the image at the URL
+func (c *Client) Size(u string) (int, int, error) {
+	u = normalizeURL(u)
+
+	c.mu.Lock()
+	s, ok := c.cache[u]
+	failedErr := c.failed[u]
+	c.mu.Unlock()
+	if ok {
+		return s.Width, s.Height, nil
+	}
+	if failedErr != nil {
+		return 0, 0, failedErr
+	}
+
+	if c.offline {
+		return 0, 0, fmt.Errorf("%w: %q", errNotCached, u)
+	}
+
+	res, err, _ := c.group.Do(u, func() (interface{}, error) {
+		return c.fetch(u)
+	})
+	if err != nil {
+		c.mu.Lock()
+		c.failed[u] = err
+		c.mu.Unlock()
+		return 0, 0, err
+	}
+
+	s = res.(Size)
+	c.mu.Lock()
+	c.cache[u] = s
+	c.dirty = true
+	c.mu.Unlock()
+
+	return s.Width, s.Height, nil
+}
+
+func (c *Client) fetch(u string) (Size, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return Size{}, fmt.Errorf("%w %q: %v", errFetchFailed, u, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%v", maxHeaderBytes-1))
+	req.Header.Set("User-Agent", "go-html-asset-manager")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return Size{}, fmt.Errorf("%w %q: %v", errFetchFailed, u, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return Size{}, fmt.Errorf("%w %q: status %v", errFetchFailed, u, res.StatusCode)
+	}
+
+	// Servers may ignore the range header so never read more than needed
+	header, err := ioutil.ReadAll(io.LimitReader(res.Body, maxHeaderBytes))
+	if err != nil {
+		return Size{}, fmt.Errorf("%w %q: %v", errFetchFailed, u, err)
+	}
+
+	if isSVG(u, res.Header.Get("Content-Type")) {
+		w, h, err := svg.Size(bytes.NewReader(header))
+		if err != nil {
+			return Size{}, fmt.Errorf("%w %q: %v", errDecodeFailed, u, err)
+		}
+		return Size{Width: w, Height: h}, nil
+	}
+
+	conf, _, err := image.DecodeConfig(bytes.NewReader(header))
+	if err != nil {
+		return Size{}, fmt.Errorf("%w %q: %v", errDecodeFailed, u, err)
+	}
+	return Size{Width: conf.Width, Height: conf.Height}, nil
+}
+
+// Save writes the cache file if any new sizes were fetched
+func (c *Client) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cacheFile == "" || !c.dirty {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(c.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := c.ioutilWriteFile(c.cacheFile, b, 0644); err != nil {
+		return fmt.Errorf("failed to write cache file %q: %w", c.cacheFile, err)
+	}
+	c.dirty = false
+	return nil
+}
+
+func normalizeURL(u string) string {
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
+	return u
+}
+
+func isSVG(u, contentType string) bool {
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	return strings.HasSuffix(strings.ToLower(u), ".svg")
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
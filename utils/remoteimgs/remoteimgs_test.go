@@ -0,0 +1,297 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package remoteimgs
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var errInjected = errors.New("injected error")
+
+func newTestServer(t *testing.T, requests *int32) *httptest.Server {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20))); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/photo.png", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("Range") == "" {
+			t.Errorf("Expected range header to be set")
+		}
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/icon.svg", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte(`<svg viewBox="0 0 16 8"></svg>`))
+	})
+	mux.HandleFunc("/text.txt", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Write([]byte(`not an image`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		description string
+		readFile    func(filename string) ([]byte, error)
+		wantCache   map[string]Size
+		wantError   error
+	}{
+		{
+			description: "return client with empty cache if file does not exist",
+			readFile: func(filename string) ([]byte, error) {
+				return nil, os.ErrNotExist
+			},
+			wantCache: map[string]Size{},
+		},
+		{
+			description: "return error if reading cache fails",
+			readFile: func(filename string) ([]byte, error) {
+				return nil, errInjected
+			},
+			wantError: errInjected,
+		},
+		{
+			description: "return error if cache is invalid JSON",
+			readFile: func(filename string) ([]byte, error) {
+				return []byte(`{`), nil
+			},
+			wantError: errJSONParse,
+		},
+		{
+			description: "return client with cached sizes",
+			readFile: func(filename string) ([]byte, error) {
+				return []byte(`{"https://example.com/a.jpg": {"width": 1, "height": 2}}`), nil
+			},
+			wantCache: map[string]Size{
+				"https://example.com/a.jpg": {Width: 1, Height: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Client{
+				cacheFile:      "/cache.json",
+				cache:          map[string]Size{},
+				ioutilReadFile: tt.readFile,
+			}
+			err := c.load()
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(c.cache, tt.wantCache); diff != "" {
+				t.Fatalf("Unexpected cache; diff %v", diff)
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	var requests int32
+	server := newTestServer(t, &requests)
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "sizes.json")
+	c, err := New(cacheFile, false)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		url         string
+		wantWidth   int
+		wantHeight  int
+		wantError   error
+	}{
+		{
+			description: "return size of remote png",
+			url:         server.URL + "/photo.png",
+			wantWidth:   30,
+			wantHeight:  20,
+		},
+		{
+			description: "return size of remote svg",
+			url:         server.URL + "/icon.svg",
+			wantWidth:   16,
+			wantHeight:  8,
+		},
+		{
+			description: "return error for missing image",
+			url:         server.URL + "/missing.png",
+			wantError:   errFetchFailed,
+		},
+		{
+			description: "return error for non-image",
+			url:         server.URL + "/text.txt",
+			wantError:   errDecodeFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			w, h, err := c.Size(tt.url)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("Unexpected size; got %vx%v, want %vx%v", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+
+	// Cached sizes should not trigger a new request
+	before := atomic.LoadInt32(&requests)
+	if _, _, err := c.Size(server.URL + "/photo.png"); err != nil {
+		t.Fatalf("Unexpected error for cached size: %v", err)
+	}
+	if after := atomic.LoadInt32(&requests); after != before {
+		t.Errorf("Expected cached size to skip fetch; got %v requests, want %v", after, before)
+	}
+
+	// Failed fetches should not be retried in the same run
+	if _, _, err := c.Size(server.URL + "/text.txt"); !errors.Is(err, errDecodeFailed) {
+		t.Fatalf("Unexpected error for failed fetch; got %v, want %v", err, errDecodeFailed)
+	}
+	if after := atomic.LoadInt32(&requests); after != before {
+		t.Errorf("Expected failed fetch to be remembered; got %v requests, want %v", after, before)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	// Offline mode should only use the saved cache
+	offline, err := New(cacheFile, true)
+	if err != nil {
+		t.Fatalf("Failed to create offline client: %v", err)
+	}
+
+	w, h, err := offline.Size(server.URL + "/photo.png")
+	if err != nil {
+		t.Fatalf("Unexpected error for offline cached size: %v", err)
+	}
+	if w != 30 || h != 20 {
+		t.Errorf("Unexpected offline size; got %vx%v, want 30x20", w, h)
+	}
+
+	_, _, err = offline.Size(server.URL + "/other.png")
+	if !errors.Is(err, errNotCached) {
+		t.Errorf("Unexpected offline error; got %v, want %v", err, errNotCached)
+	}
+
+	// Failed fetches should not be saved, so the next run retries them
+	next, err := New(cacheFile, false)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	before = atomic.LoadInt32(&requests)
+	if _, _, err := next.Size(server.URL + "/text.txt"); !errors.Is(err, errDecodeFailed) {
+		t.Fatalf("Unexpected error for failed fetch; got %v, want %v", err, errDecodeFailed)
+	}
+	if after := atomic.LoadInt32(&requests); after != before+1 {
+		t.Errorf("Expected failed fetch to be retried; got %v requests, want %v", after, before+1)
+	}
+}
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		description string
+		cacheFile   string
+		dirty       bool
+		writeFile   func(filename string, data []byte, perm os.FileMode) error
+		wantError   error
+	}{
+		{
+			description: "do nothing without a cache file",
+			dirty:       true,
+		},
+		{
+			description: "do nothing if cache is unchanged",
+			cacheFile:   "/cache.json",
+		},
+		{
+			description: "return error if write fails",
+			cacheFile:   "/cache.json",
+			dirty:       true,
+			writeFile: func(filename string, data []byte, perm os.FileMode) error {
+				return errInjected
+			},
+			wantError: errInjected,
+		},
+		{
+			description: "write cache file",
+			cacheFile:   "/cache.json",
+			dirty:       true,
+			writeFile: func(filename string, data []byte, perm os.FileMode) error {
+				want := "{\n  \"https://example.com/a.jpg\": {\n    \"width\": 1,\n    \"height\": 2\n  }\n}"
+				if diff := cmp.Diff(string(data), want); diff != "" {
+					t.Errorf("Unexpected cache file; diff %v", diff)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Client{
+				cacheFile: tt.cacheFile,
+				dirty:     tt.dirty,
+				cache: map[string]Size{
+					"https://example.com/a.jpg": {Width: 1, Height: 2},
+				},
+				ioutilWriteFile: tt.writeFile,
+			}
+			err := c.Save()
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	if got := normalizeURL("//example.com/a.png"); got != "https://example.com/a.png" {
+		t.Errorf("Unexpected URL; got %v", got)
+	}
+	if got := normalizeURL("http://example.com/a.png"); got != "http://example.com/a.png" {
+		t.Errorf("Unexpected URL; got %v", got)
+	}
+}
@@ -0,0 +1,158 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package svg
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	errNoSVGElement = errors.New("no svg element found")
+	errNoDimensions = errors.New("unable to determine svg dimensions")
+
+	commentRegex     = regexp.MustCompile(`(?s)<!--.*?-->`)
+	prologRegex      = regexp.MustCompile(`(?s)<\?xml.*?\?>`)
+	doctypeRegex     = regexp.MustCompile(`(?is)<!DOCTYPE[^>]*>`)
+	metadataRegex    = regexp.MustCompile(`(?s)<metadata[\s>].*?</metadata>|<metadata\s*/>`)
+	betweenTagsRegex = regexp.MustCompile(`>\s+<`)
+	textRegex        = regexp.MustCompile(`(?s)<text[\s>].*?</text>`)
+)
+
+// Size returns the intrinsic width and height of an SVG document. The width
+// and height attributes are preferred, falling back to the viewBox when they
+// are missing or use relative units.
+func Size(r io.Reader) (int, int, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return 0, 0, errNoSVGElement
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse svg: %w", err)
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local != "svg" {
+			return 0, 0, errNoSVGElement
+		}
+		return sizeFromAttributes(se.Attr)
+	}
+}
+
+func sizeFromAttributes(attrs []xml.Attr) (int, int, error) {
+	var width, height, vbWidth, vbHeight float64
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "width":
+			width = parseLength(a.Value)
+		case "height":
+			height = parseLength(a.Value)
+		case "viewBox":
+			vbWidth, vbHeight = parseViewBox(a.Value)
+		}
+	}
+
+	hasViewBox := vbWidth > 0 && vbHeight > 0
+	switch {
+	case width > 0 && height > 0:
+		// Use the explicit width and height
+	case width > 0 && hasViewBox:
+		height = width * vbHeight / vbWidth
+	case height > 0 && hasViewBox:
+		width = height * vbWidth / vbHeight
+	case hasViewBox:
+		width, height = vbWidth, vbHeight
+	default:
+		return 0, 0, errNoDimensions
+	}
+
+	return int(math.Round(width)), int(math.Round(height)), nil
+}
+
+func parseLength(v string) float64 {
+	v = strings.TrimSpace(v)
+	v = strings.TrimSuffix(v, "px")
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		// Relative units like % or em can't be resolved to an intrinsic size
+		return 0
+	}
+	return f
+}
+
+func parseViewBox(v string) (float64, float64) {
+	parts := strings.FieldsFunc(v, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(parts) != 4 {
+		return 0, 0
+	}
+	w, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, 0
+	}
+	h, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return 0, 0
+	}
+	return w, h
+}
+
+// Optimize strips comments, the XML prolog, doctype and metadata from an SVG
+// document along with any whitespace between tags. Whitespace in text
+// elements is rendered so it is kept.
+func Optimize(s string) string {
+	s = commentRegex.ReplaceAllString(s, "")
+	s = prologRegex.ReplaceAllString(s, "")
+	s = doctypeRegex.ReplaceAllString(s, "")
+	s = metadataRegex.ReplaceAllString(s, "")
+
+	texts := textRegex.FindAllStringIndex(s, -1)
+	var b strings.Builder
+	last := 0
+	for _, m := range betweenTagsRegex.FindAllStringIndex(s, -1) {
+		if inRanges(texts, m) {
+			continue
+		}
+		b.WriteString(s[last : m[0]+1])
+		b.WriteString("<")
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return strings.TrimSpace(b.String())
+}
+
+func inRanges(ranges [][]int, r []int) bool {
+	for _, rr := range ranges {
+		if r[0] >= rr[0] && r[1] <= rr[1] {
+			return true
+		}
+	}
+	return false
+}
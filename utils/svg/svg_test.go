@@ -0,0 +1,153 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package svg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		wantWidth   int
+		wantHeight  int
+		wantError   error
+	}{
+		{
+			description: "return error for empty input",
+			input:       "",
+			wantError:   errNoSVGElement,
+		},
+		{
+			description: "return error if root element is not svg",
+			input:       `<html></html>`,
+			wantError:   errNoSVGElement,
+		},
+		{
+			description: "return error if no dimensions are defined",
+			input:       `<svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+			wantError:   errNoDimensions,
+		},
+		{
+			description: "return width and height attributes",
+			input:       `<svg xmlns="http://www.w3.org/2000/svg" width="24" height="12"></svg>`,
+			wantWidth:   24,
+			wantHeight:  12,
+		},
+		{
+			description: "return width and height attributes with px units",
+			input:       `<svg width="24px" height="12.4px"></svg>`,
+			wantWidth:   24,
+			wantHeight:  12,
+		},
+		{
+			description: "return viewBox dimensions",
+			input:       `<?xml version="1.0"?><!-- Example --><svg viewBox="0 0 100 50"></svg>`,
+			wantWidth:   100,
+			wantHeight:  50,
+		},
+		{
+			description: "return viewBox dimensions with comma separators",
+			input:       `<svg viewBox="0,0,100,50"></svg>`,
+			wantWidth:   100,
+			wantHeight:  50,
+		},
+		{
+			description: "return viewBox dimensions for relative width and height",
+			input:       `<svg width="100%" height="100%" viewBox="0 0 100 50"></svg>`,
+			wantWidth:   100,
+			wantHeight:  50,
+		},
+		{
+			description: "return height from width and viewBox ratio",
+			input:       `<svg width="200" viewBox="0 0 100 50"></svg>`,
+			wantWidth:   200,
+			wantHeight:  100,
+		},
+		{
+			description: "return width from height and viewBox ratio",
+			input:       `<svg height="25" viewBox="0 0 100 50"></svg>`,
+			wantWidth:   50,
+			wantHeight:  25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			w, h, err := Size(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Different error returned; got %v, want %v", err, tt.wantError)
+			}
+
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("Unexpected size; got %vx%v, want %vx%v", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        string
+	}{
+		{
+			description: "return empty string for empty input",
+			input:       "",
+			want:        "",
+		},
+		{
+			description: "strip prolog, doctype, comments and metadata",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<!-- Generator: Example -->
+<svg viewBox="0 0 10 10">
+	<metadata>
+		<rdf:RDF></rdf:RDF>
+	</metadata>
+	<title>Example</title>
+	<path d="M0 0h10v10H0z"/>
+</svg>
+`,
+			want: `<svg viewBox="0 0 10 10"><title>Example</title><path d="M0 0h10v10H0z"/></svg>`,
+		},
+		{
+			description: "keep whitespace in text elements",
+			input: `<svg viewBox="0 0 10 10">
+	<text x="0" y="5"><tspan>Hello</tspan> <tspan>world</tspan></text>
+	<text>A <textPath href="#p">curve</textPath></text>
+	<path id="p" d="M0 0h10"/>
+</svg>`,
+			want: `<svg viewBox="0 0 10 10"><text x="0" y="5"><tspan>Hello</tspan> <tspan>world</tspan></text><text>A <textPath href="#p">curve</textPath></text><path id="p" d="M0 0h10"/></svg>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := Optimize(tt.input)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Unexpected result; diff %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+// Package video reads the metadata of video files and extracts frames from
+// them with ffprobe and ffmpeg
+package video
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	posterSuffix = ".poster.jpg"
+)
+
+var (
+	// Extensions are the video file extensions handled
+	Extensions = []string{".mp4", ".webm", ".m4v", ".mov", ".ogv"}
+
+	errProbeFailed   = errors.New("ffprobe failed")
+	errNoVideo       = errors.New("no video stream")
+	errExtractFailed = errors.New("ffmpeg failed")
+
+	execCommand = exec.Command
+)
+
+// Info is the metadata of a video's first video stream
+type Info struct {
+	Width  int
+	Height int
+	// The duration in seconds, 0 when unknown
+	Duration float64
+}
+
+// IsVideo returns true if the path has a video extension
+func IsVideo(p string) bool {
+	ext := filepath.Ext(p)
+	for _, e := range Extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// PosterPath returns the path of the poster frame for a video, i.e.
+// "/videos/intro.poster.jpg" for "/videos/intro.mp4"
+func PosterPath(videoPath string) string {
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + posterSuffix
+}
+
+type probeOutput struct {
+	Streams []struct {
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// Probe returns the display size and duration of a video
+func Probe(p string) (Info, error) {
+	cmd := execCommand(
+		"ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:stream_tags=rotate:stream_side_data=rotation:format=duration",
+		"-of", "json",
+		p,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return Info{}, fmt.Errorf("%w for %q: %v", errProbeFailed, p, err)
+	}
+
+	var o probeOutput
+	if err := json.Unmarshal(output, &o); err != nil {
+		return Info{}, fmt.Errorf("%w for %q: %v", errProbeFailed, p, err)
+	}
+	if len(o.Streams) == 0 || o.Streams[0].Width == 0 || o.Streams[0].Height == 0 {
+		return Info{}, fmt.Errorf("%w in %q", errNoVideo, p)
+	}
+
+	s := o.Streams[0]
+	info := Info{Width: s.Width, Height: s.Height}
+
+	// Videos recorded in portrait are often stored in landscape and rotated
+	// when played
+	rotation, _ := strconv.ParseFloat(s.Tags["rotate"], 64)
+	for _, sd := range s.SideDataList {
+		if sd.Rotation != 0 {
+			rotation = sd.Rotation
+		}
+	}
+	if math.Mod(math.Abs(rotation), 180) == 90 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+
+	info.Duration, _ = strconv.ParseFloat(o.Format.Duration, 64)
+	return info, nil
+}
+
+// ExtractFrame writes the frame of a video at a time in seconds to a JPEG
+func ExtractFrame(p, output string, at float64) error {
+	cmd := execCommand(
+		"ffmpeg",
+		"-v", "error",
+		"-y",
+		"-ss", strconv.FormatFloat(at, 'f', 3, 64),
+		"-i", p,
+		"-frames:v", "1",
+		"-q:v", "2",
+		output,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w extracting a frame of %q: %v: %v", errExtractFailed, p, err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/**
+ * Copyright 2020 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ **/
+
+package video
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeCommand returns a command that prints the output and exits with the
+// code, recording the arguments it was called with
+func fakeCommand(output string, code int, gotArgs *[]string) func(string, ...string) *exec.Cmd {
+	return func(name string, args ...string) *exec.Cmd {
+		*gotArgs = append([]string{name}, args...)
+		return exec.Command("sh", "-c", `printf '%s' "$0"; exit $1`, output, strconv.Itoa(code))
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		description string
+		output      string
+		code        int
+		want        Info
+		wantError   error
+	}{
+		{
+			description: "return error if ffprobe fails",
+			code:        1,
+			wantError:   errProbeFailed,
+		},
+		{
+			description: "return error for invalid output",
+			output:      "nope",
+			wantError:   errProbeFailed,
+		},
+		{
+			description: "return error without a video stream",
+			output:      `{"streams":[],"format":{"duration":"1.5"}}`,
+			wantError:   errNoVideo,
+		},
+		{
+			description: "return the size and duration",
+			output:      `{"streams":[{"width":1920,"height":1080}],"format":{"duration":"12.500000"}}`,
+			want:        Info{Width: 1920, Height: 1080, Duration: 12.5},
+		},
+		{
+			description: "swap the size of rotated videos",
+			output:      `{"streams":[{"width":1920,"height":1080,"side_data_list":[{"rotation":-90}]}],"format":{}}`,
+			want:        Info{Width: 1080, Height: 1920},
+		},
+		{
+			description: "swap the size of videos with a rotate tag",
+			output:      `{"streams":[{"width":1920,"height":1080,"tags":{"rotate":"270"}}],"format":{}}`,
+			want:        Info{Width: 1080, Height: 1920},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			var args []string
+			execCommand = fakeCommand(tt.output, tt.code, &args)
+			t.Cleanup(func() {
+				execCommand = exec.Command
+			})
+
+			got, err := Probe("/static/intro.mp4")
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("Unexpected error; got %v, want %v", err, tt.wantError)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Unexpected info; diff %v", diff)
+			}
+			if args[0] != "ffprobe" || args[len(args)-1] != "/static/intro.mp4" {
+				t.Fatalf("Unexpected command; got %v", args)
+			}
+		})
+	}
+}
+
+func TestExtractFrame(t *testing.T) {
+	var args []string
+	execCommand = fakeCommand("", 0, &args)
+	t.Cleanup(func() {
+		execCommand = exec.Command
+	})
+
+	err := ExtractFrame("/static/intro.mp4", "/static/intro.poster.jpg", 1.5)
+	if err != nil {
+		t.Fatalf("ExtractFrame failed: %v", err)
+	}
+	want := []string{"ffmpeg", "-v", "error", "-y", "-ss", "1.500", "-i", "/static/intro.mp4", "-frames:v", "1", "-q:v", "2", "/static/intro.poster.jpg"}
+	if diff := cmp.Diff(args, want); diff != "" {
+		t.Fatalf("Unexpected command; diff %v", diff)
+	}
+
+	execCommand = fakeCommand("boom", 1, &args)
+	err = ExtractFrame("/static/intro.mp4", "/static/intro.poster.jpg", 0)
+	if !errors.Is(err, errExtractFailed) {
+		t.Fatalf("Unexpected error; got %v, want %v", err, errExtractFailed)
+	}
+}
+
+func TestPosterPath(t *testing.T) {
+	if got, want := PosterPath("/videos/intro.mp4"), "/videos/intro.poster.jpg"; got != want {
+		t.Fatalf("Unexpected poster path; got %q, want %q", got, want)
+	}
+	if !IsVideo("/videos/intro.MP4") || IsVideo("/videos/intro.poster.jpg") {
+		t.Fatalf("Unexpected IsVideo result")
+	}
+}